@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/backup"
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/mongodb"
+	"github.com/Glorified-Toaster/senior-project/internal/migrations"
+)
+
+// runBackup : `backup [-out backup.tar.gz] [-collections students,exams] [-temp-dir dir]`
+func runBackup(args []string) error {
+	fs := newCommandFlags("backup")
+	out := fs.String("out", "", "archive to write (default backup-<database>-<timestamp>.tar.gz)")
+	collections := fs.String("collections", "", "comma separated collections to back up (default all)")
+	tempDir := fs.String("temp-dir", "", "directory collections are spooled to before archiving (default the system temp dir)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, cleanup, err := bootstrap(fs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+
+	schemaVersion, err := appliedSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		*out = fmt.Sprintf("backup-%s-%s.tar.gz", cfg.MongoDB.Database, time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	// write next to the target and rename once complete, a failed run never looks like a good archive;
+	// the archive holds password hashes, keep it private
+	partial := *out + ".partial"
+	f, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+
+	manifest, err := backup.Backup(ctx, mongodb.Database, f, backup.BackupOptions{
+		Collections:   splitList(*collections),
+		SchemaVersion: schemaVersion,
+		TempDir:       *tempDir,
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(partial, *out); err != nil {
+		return err
+	}
+
+	if err := printManifest(os.Stdout, manifest); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", *out)
+	return nil
+}
+
+// runRestore : `restore -file backup.tar.gz [-db name] [-collections students,exams] [-drop] [-indexes] [-verify-only]`
+func runRestore(args []string) error {
+	fs := newCommandFlags("restore")
+	file := fs.String("file", "", "archive written by the backup command")
+	database := fs.String("db", "", "database to restore into (default the configured database)")
+	collections := fs.String("collections", "", "comma separated collections to restore (default all)")
+	drop := fs.Bool("drop", false, "drop the target collections first instead of requiring them to be empty")
+	indexes := fs.Bool("indexes", true, "recreate the indexes recorded in the archive")
+	batchSize := fs.Int("batch-size", 1000, "number of documents inserted per batch")
+	verifyOnly := fs.Bool("verify-only", false, "only check the archive, do not write anything")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		fs.Usage()
+		return errors.New("-file is required")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// a damaged archive is refused before anything is written
+	selection := splitList(*collections)
+	manifest, err := backup.Verify(f, selection)
+	if err != nil {
+		return err
+	}
+	if err := printManifest(os.Stdout, manifest); err != nil {
+		return err
+	}
+	if *verifyOnly {
+		fmt.Fprintf(os.Stderr, "%s is valid\n", *file)
+		return nil
+	}
+
+	cfg, cleanup, err := bootstrap(fs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if *database == "" {
+		*database = cfg.MongoDB.Database
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err = backup.Restore(context.Background(), mongodb.Database.Client().Database(*database), f, backup.RestoreOptions{
+		Collections: selection,
+		Drop:        *drop,
+		Indexes:     *indexes,
+		BatchSize:   *batchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "restored %s into %s\n", *file, *database)
+	return nil
+}
+
+// appliedSchemaVersion : the latest applied migration, recorded in the manifest of a backup
+func appliedSchemaVersion(ctx context.Context) (int, error) {
+	statuses, err := migrations.NewRunner(mongodb.Database).Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for _, status := range statuses {
+		if status.Applied {
+			version = max(version, status.Version)
+		}
+	}
+	return version, nil
+}
+
+func printManifest(w io.Writer, manifest *backup.Manifest) error {
+	fmt.Fprintf(w, "database %s, schema version %d, taken %s\n",
+		manifest.Database, manifest.SchemaVersion, manifest.CreatedAt.Format("2006-01-02 15:04:05"))
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLLECTION\tDOCUMENTS\tBYTES\tINDEXES")
+	for _, collection := range manifest.Collections {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", collection.Name, collection.Documents, collection.Bytes, len(collection.Indexes))
+	}
+	return tw.Flush()
+}
+
+// splitList : the non empty items of a comma separated flag
+func splitList(value string) []string {
+	items := []string{}
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config"
+	"github.com/Glorified-Toaster/senior-project/internal/config/logger"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+)
+
+// cachePrefix : key prefix of the application cache, the server and the commands that write
+// cached documents must use the same one
+const cachePrefix = "myapp"
+
+// command : a CLI subcommand of the binary
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"backup": {
+		usage: "write the database to a compressed archive",
+		run:   runBackup,
+	},
+	"config": {
+		usage: "check the configuration (validate) without starting the server",
+		run:   runConfig,
+	},
+	"import-students": {
+		usage: "create student accounts from a CSV or XLSX class list",
+		run:   runImportStudents,
+	},
+	"migrate": {
+		usage: "apply (up), revert (down) or list (status) database migrations",
+		run:   runMigrate,
+	},
+	"restore": {
+		usage: "verify an archive and load it into a database",
+		run:   runRestore,
+	},
+}
+
+// runCommand : runs a CLI subcommand and exits with its status.
+func runCommand(name string, args []string) {
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printCommands()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// printCommands : lists the available subcommands.
+func printCommands() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "available commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", name, commands[name].usage)
+	}
+}
+
+// commandFlags : flag set with the config flags every subcommand shares.
+type commandFlags struct {
+	*flag.FlagSet
+	configPath *string
+	configFile *string
+	profile    *string
+}
+
+func newCommandFlags(name string) *commandFlags {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	return &commandFlags{
+		FlagSet:    fs,
+		configPath: fs.String("config-path", "", "path to configuration path (default: search the usual paths)"),
+		configFile: fs.String("config-file", "config", "name of configuration file (without extension)"),
+		profile:    fs.String("profile", "", "config profile merged over the base file (default: $APP_PROFILE)"),
+	}
+}
+
+// source : where the flags say the config is.
+func (fs *commandFlags) source() config.Source {
+	return config.NewSource(*fs.configPath, *fs.configFile, *fs.profile)
+}
+
+// bootstrap : loads the config, the logger and connects to MongoDB for a subcommand,
+// the returned func releases everything.
+func bootstrap(fs *commandFlags) (*config.Config, func(), error) {
+	if err := config.Setup(fs.source()); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", utils.ConfigFailedToLoad.Msg, err)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", utils.ConfigFailedToLoad.Msg, err)
+	}
+
+	if err := logger.InitLogger(*cfg); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", utils.LoggerFailedToInit.Msg, err)
+	}
+	utils.InitUtils()
+
+	if cfg.MongoDB == nil {
+		return nil, nil, errors.New("mongodb is not configured")
+	}
+	if err := connectMongo(cfg); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", utils.MongoFailedToConnect.Msg, err)
+	}
+
+	cleanup := func() {
+		disconnectMongo()
+		_ = logger.Sync()
+	}
+	return cfg, cleanup, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config"
+)
+
+// runConfig : `config validate [-release] [-profile name]`
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return errors.New("missing action, expected validate")
+	}
+	action, args := args[0], args[1:]
+
+	fs := newCommandFlags("config")
+	release := fs.Bool("release", false, "also refuse the development-only secrets, as the server does in release mode")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "validate":
+		// loaded without Setup, nothing else runs in this process
+		src := fs.source()
+		cfg, err := config.Load(src)
+		if err != nil {
+			return err
+		}
+		if *release {
+			if err := cfg.ValidateRelease(); err != nil {
+				return err
+			}
+		}
+		files, _ := src.Files()
+		fmt.Printf("configuration is valid (%s)\n", strings.Join(files, ", "))
+		return nil
+
+	default:
+		return fmt.Errorf("unknown action %q, expected validate", action)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache"
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/mongodb"
+	"github.com/Glorified-Toaster/senior-project/internal/importer"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/go-playground/validator"
+)
+
+// runImportStudents : `import-students -file students.xlsx [-dry-run] [-out report.csv]`
+func runImportStudents(args []string) error {
+	fs := newCommandFlags("import-students")
+	file := fs.String("file", "", "CSV or XLSX file with the students to import")
+	dryRun := fs.Bool("dry-run", false, "only validate the file, do not create any account")
+	batchSize := fs.Int("batch-size", 100, "number of students inserted per batch")
+	out := fs.String("out", "", "write the per-row report (with initial passwords) to this CSV file instead of stdout")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		fs.Usage()
+		return errors.New("-file is required")
+	}
+
+	format, err := importer.FormatFromFilename(*file)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rows, err := importer.Parse(f, format)
+	if err != nil {
+		return err
+	}
+
+	_, cleanup, err := bootstrap(fs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// the server may have cached the imported students as not found
+	appCache := cache.InitCache(cachePrefix)
+	fileRepo, err := repository.NewFileRepo(mongodb.Database)
+	if err != nil {
+		return err
+	}
+	studentRepo := repository.NewStudentRepo(context.Background(), mongodb.Database, appCache, fileRepo)
+	report, importErr := importer.NewImporter(studentRepo, validator.New()).Import(context.Background(), rows, importer.Options{
+		DryRun:    *dryRun,
+		BatchSize: *batchSize,
+	})
+	if report == nil {
+		return importErr
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		// the report holds initial passwords, keep it private
+		outFile, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		defer outFile.Close()
+		w = outFile
+	}
+
+	if err := writeImportReport(w, report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "rows: %d, valid: %d, created: %d, failed: %d, dry run: %t\n",
+		report.Total, report.Valid, report.Created, report.Failed, report.DryRun)
+
+	return importErr
+}
+
+// writeImportReport : writes one CSV line per imported row.
+func writeImportReport(w io.Writer, report *importer.Report) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"line", "student_id", "email", "status", "initial_password", "errors"}); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		err := writer.Write([]string{
+			strconv.Itoa(row.Line),
+			row.StudentID,
+			row.Email,
+			row.Status,
+			row.InitialPassword,
+			strings.Join(row.Errors, "; "),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net"
 	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/Glorified-Toaster/senior-project/internal/config"
 	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache"
@@ -13,17 +15,26 @@ import (
 	"github.com/Glorified-Toaster/senior-project/internal/config/logger"
 	"github.com/Glorified-Toaster/senior-project/internal/controllers"
 	"github.com/Glorified-Toaster/senior-project/internal/helpers"
+	"github.com/Glorified-Toaster/senior-project/internal/jobs"
 	"github.com/Glorified-Toaster/senior-project/internal/middleware"
+	"github.com/Glorified-Toaster/senior-project/internal/migrations"
 	"github.com/Glorified-Toaster/senior-project/internal/repository"
 	"github.com/Glorified-Toaster/senior-project/internal/server"
 	"github.com/Glorified-Toaster/senior-project/internal/utils"
 	"github.com/go-playground/validator"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
 func main() {
+	// run a CLI subcommand if one is given, e.g. `main import-students -file students.csv`
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	// loading the YAML config variables
-	config.Init(getConfigPath(), "config")
+	config.Init("", "config")
 
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -46,15 +57,7 @@ func main() {
 
 	// connect to MongoDB
 	if cfg.MongoDB != nil {
-		uri := mongodb.MakeURI(
-			cfg.MongoDB.Host,
-			cfg.MongoDB.Port,
-			cfg.MongoDB.Username,
-			cfg.MongoDB.Password,
-			cfg.MongoDB.Database,
-		)
-
-		if err := mongodb.MongoConnect(uri, cfg.MongoDB.Database); err != nil {
+		if err := connectMongo(cfg); err != nil {
 			utils.LogErrorWithLevel("fatal",
 				utils.MongoFailedToConnect.Type,
 				utils.MongoFailedToConnect.Code,
@@ -62,33 +65,64 @@ func main() {
 				err,
 			)
 		}
-		defer func() {
-			if err := mongodb.MongoDisconnect(); err != nil {
-				utils.LogErrorWithLevel("warn",
-					utils.MongoFailedToDisconnect.Type,
-					utils.MongoFailedToDisconnect.Code,
-					utils.MongoFailedToDisconnect.Msg,
-					err,
-				)
+		defer disconnectMongo()
+
+		// exam submission and the cache invalidation watcher need transactions and change streams
+		if err := requireReplicaSet(); err != nil {
+			utils.LogErrorWithLevel("fatal",
+				utils.MongoNotReplicaSet.Type,
+				utils.MongoNotReplicaSet.Code,
+				utils.MongoNotReplicaSet.Msg,
+				err,
+			)
+		}
+
+		// bring the schema up to date (indexes, validators, backfills)
+		if cfg.MongoDB.AutoMigrate {
+			if _, err := migrations.NewRunner(mongodb.Database).Up(context.Background(), 0); err != nil {
+				utils.LogErrorWithLevel("fatal", utils.MigrationFailed.Type, utils.MigrationFailed.Code, utils.MigrationFailed.Msg, err)
 			}
-		}()
+		}
 	}
 
-	// init cache
-	cache, err := cache.InitCache("myapp")
+	// init cache, an unreachable dragonfly is bypassed until it is back
+	appCache := cache.InitCache(cachePrefix)
+	if err := cache.RegisterMetrics(appCache, prometheus.DefaultRegisterer); err != nil {
+		utils.LogErrorWithLevel("warn", utils.CacheMetricsFailedToRegister.Type, utils.CacheMetricsFailedToRegister.Code, utils.CacheMetricsFailedToRegister.Msg, err)
+	}
+	// init the exam file store, the purges of the student and exam repos delete the files too
+	fileRepo, err := repository.NewFileRepo(mongodb.Database)
 	if err != nil {
-		utils.LogErrorWithLevel("fatal", utils.DragonflyFailedToInit.Type, utils.DragonflyFailedToInit.Code, utils.DragonflyFailedToInit.Msg, err)
+		utils.LogErrorWithLevel("fatal", utils.FileStoreFailedToInit.Type, utils.FileStoreFailedToInit.Code, utils.FileStoreFailedToInit.Msg, err)
 	}
 	// init the user repo
-	studentRepo := repository.NewStudentRepo(context.Background(), mongodb.Database, cache)
+	studentRepo := repository.NewStudentRepo(context.Background(), mongodb.Database, appCache, fileRepo)
+	// init the login history repo
+	loginHistoryRepo := repository.NewLoginHistoryRepo(mongodb.Database)
+	// init the exam repo
+	examRepo := repository.NewExamRepo(mongodb.Database, appCache, fileRepo)
+	// init the audit log repo
+	auditRepo := repository.NewAuditRepo(mongodb.Database)
+	// background work of the server, stopped when main returns
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	// purge the trash in the background while the server runs
+	go jobs.NewTrashPurger(studentRepo, examRepo, auditRepo, cfg.Trash.Retention, cfg.Trash.PurgeInterval).Run(jobsCtx)
+	// evict the cached students changed by anyone, not only by this instance
+	go mongodb.NewChangeWatcher(mongodb.Database, "students", "student-cache", repository.StudentCacheInvalidator(appCache)).Run(jobsCtx)
+	// apply the reloadable keys of the config (log level, rate limit, cache ttl, feature flags)
+	// when the file changes or on SIGHUP
+	if err := config.Watch(jobsCtx, logConfigReload); err != nil {
+		utils.LogErrorWithLevel("warn", utils.ConfigFailedToWatch.Type, utils.ConfigFailedToWatch.Code, utils.ConfigFailedToWatch.Msg, err)
+	}
 	// init validator
 	validate := validator.New()
 	// init jwt
 	jwt := helpers.NewJWT(cfg)
 	// init auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwt)
-	// pass cache, repo, validator, jwt to controllers
-	ctrl := controllers.NewControllers(validate, *studentRepo, *cache, jwt)
+	authMiddleware := middleware.NewAuthMiddleware(jwt, studentRepo)
+	// pass cache, repos, validator, jwt to controllers
+	ctrl := controllers.NewControllers(validate, studentRepo, loginHistoryRepo, examRepo, auditRepo, fileRepo, appCache, jwt)
 
 	// initialize the server
 	srv := server.NewServer(ctrl, authMiddleware)
@@ -99,16 +133,41 @@ func main() {
 	srv.StartOverTLS(cfg)
 }
 
-// getConfigPath : to get the path to the config file.
-func getConfigPath() string {
-	return filepath.Join(getProgramPath(), "internal", "config")
+// logConfigReload : logs what a config reload changed and what it left for the next restart.
+func logConfigReload(result config.ReloadResult, err error) {
+	if err != nil {
+		utils.LogErrorWithLevel("warn", utils.ConfigFailedToReload.Type, utils.ConfigFailedToReload.Code, utils.ConfigFailedToReload.Msg, err)
+		return
+	}
+	for _, change := range result.Rejected {
+		utils.LogErrorWithLevel("warn", utils.ConfigKeyNotReloadable.Type, utils.ConfigKeyNotReloadable.Code, utils.ConfigKeyNotReloadable.Msg,
+			fmt.Errorf("%s changed from %q to %q", change.Key, change.Old, change.New))
+	}
+	if len(result.Applied) > 0 {
+		utils.LogInfo(utils.ConfigReloaded.Type, utils.ConfigReloaded.Msg, zap.Any("changes", result.Applied))
+	}
 }
 
-// getProgramPath : to get the path where the program is located.
-func getProgramPath() string {
-	exe, err := os.Executable()
-	if err != nil {
-		utils.LogErrorWithLevel("fatal", utils.FailedToGetProgramPath.Type, utils.FailedToGetProgramPath.Code, utils.FailedToGetProgramPath.Msg, err)
+// connectMongo : connects to MongoDB using the loaded configuration.
+func connectMongo(cfg *config.Config) error {
+	return mongodb.MongoConnect(cfg.MongoDB)
+}
+
+// requireReplicaSet : checks that the connected deployment supports transactions
+func requireReplicaSet() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return mongodb.RequireReplicaSet(ctx)
+}
+
+// disconnectMongo : disconnects from MongoDB, logging any failure.
+func disconnectMongo() {
+	if err := mongodb.MongoDisconnect(); err != nil {
+		utils.LogErrorWithLevel("warn",
+			utils.MongoFailedToDisconnect.Type,
+			utils.MongoFailedToDisconnect.Code,
+			utils.MongoFailedToDisconnect.Msg,
+			err,
+		)
 	}
-	return filepath.Dir(exe)
 }
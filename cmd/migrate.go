@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/mongodb"
+	"github.com/Glorified-Toaster/senior-project/internal/migrations"
+)
+
+// runMigrate : `migrate [up|down|status] [-to version] [-steps n]`
+func runMigrate(args []string) error {
+	action := "up"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	fs := newCommandFlags("migrate")
+	to := fs.Int("to", 0, "up: migrate up to this version (0 = latest)")
+	steps := fs.Int("steps", 1, "down: number of migrations to revert")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	_, cleanup, err := bootstrap(fs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	runner := migrations.NewRunner(mongodb.Database)
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		applied, err := runner.Up(ctx, *to)
+		fmt.Printf("applied %d migration(s) %v\n", len(applied), applied)
+		return err
+
+	case "down":
+		if *steps < 1 {
+			return errors.New("-steps must be at least 1")
+		}
+		reverted, err := runner.Down(ctx, *steps)
+		fmt.Printf("reverted %d migration(s) %v\n", len(reverted), reverted)
+		return err
+
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tAPPLIED AT\tDESCRIPTION")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, appliedAt, status.Description)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown action %q, expected up, down or status", action)
+	}
+}
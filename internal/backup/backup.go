@@ -0,0 +1,229 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// skippedCollections : collections that only make sense on the running database
+var skippedCollections = []string{"schema_migrations_lock"}
+
+type BackupOptions struct {
+	Collections   []string // empty means every collection
+	SchemaVersion int
+	TempDir       string // where collections are spooled before archiving, empty means os.TempDir
+}
+
+// Backup : writes an archive of db to w, collections are spooled to disk first because tar
+// needs their size up front, the snapshot is not point in time across collections
+func Backup(ctx context.Context, db *mongo.Database, w io.Writer, opts BackupOptions) (*Manifest, error) {
+	specs, err := db.ListCollectionSpecifications(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	slices.SortFunc(specs, func(a, b *mongo.CollectionSpecification) int { return strings.Compare(a.Name, b.Name) })
+
+	for _, name := range opts.Collections {
+		if !slices.ContainsFunc(specs, func(spec *mongo.CollectionSpecification) bool { return spec.Name == name }) {
+			return nil, fmt.Errorf("collection %s does not exist in %s", name, db.Name())
+		}
+	}
+
+	spool, err := os.MkdirTemp(opts.TempDir, "backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	defer os.RemoveAll(spool)
+
+	manifest := &Manifest{
+		Format:        FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Database:      db.Name(),
+		SchemaVersion: opts.SchemaVersion,
+		Collections:   []CollectionManifest{},
+	}
+	for _, spec := range specs {
+		// views are rebuilt by whoever defined them, system collections belong to the server
+		if spec.Type != "collection" || strings.HasPrefix(spec.Name, "system.") || slices.Contains(skippedCollections, spec.Name) {
+			continue
+		}
+		if !selected(opts.Collections, spec.Name) {
+			continue
+		}
+
+		collection, err := dumpCollection(ctx, db, spec, filepath.Join(spool, spec.Name+".bson"))
+		if err != nil {
+			return nil, err
+		}
+		manifest.Collections = append(manifest.Collections, *collection)
+	}
+
+	if err := writeArchive(w, manifest, spool); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// dumpCollection : writes the raw documents of a collection to file, returns its manifest entry
+func dumpCollection(ctx context.Context, db *mongo.Database, spec *mongo.CollectionSpecification, file string) (*CollectionManifest, error) {
+	collection := &CollectionManifest{
+		Name: spec.Name,
+		File: dataFile(spec.Name),
+	}
+
+	var err error
+	if len(spec.Options) > 0 {
+		if collection.Options, err = extJSON(spec.Options); err != nil {
+			return nil, fmt.Errorf("failed to encode options of %s: %w", spec.Name, err)
+		}
+	}
+	if collection.Indexes, err = dumpIndexes(ctx, db.Collection(spec.Name)); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool %s: %w", spec.Name, err)
+	}
+	defer f.Close()
+
+	cursor, err := db.Collection(spec.Name).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", spec.Name, err)
+	}
+	defer cursor.Close(ctx)
+
+	hash := sha256.New()
+	out := io.MultiWriter(f, hash)
+	for cursor.Next(ctx) {
+		n, err := out.Write(cursor.Current)
+		if err != nil {
+			return nil, fmt.Errorf("failed to spool %s: %w", spec.Name, err)
+		}
+		collection.Documents++
+		collection.Bytes += int64(n)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", spec.Name, err)
+	}
+
+	collection.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return collection, f.Close()
+}
+
+// dumpIndexes : the index specifications of a collection except the implicit _id index
+func dumpIndexes(ctx context.Context, collection *mongo.Collection) ([]json.RawMessage, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes of %s: %w", collection.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	indexes := []json.RawMessage{}
+	for cursor.Next(ctx) {
+		var spec bson.D
+		if err := cursor.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("failed to decode index of %s: %w", collection.Name(), err)
+		}
+		if name, _ := indexName(spec); name == "_id_" {
+			continue
+		}
+		// the namespace is tied to the source database, createIndexes infers it
+		spec = slices.DeleteFunc(spec, func(e bson.E) bool { return e.Key == "ns" })
+
+		index, err := extJSON(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode index of %s: %w", collection.Name(), err)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, cursor.Err()
+}
+
+// writeArchive : the manifest followed by the spooled collections, in manifest order
+func writeArchive(w io.Writer, manifest *Manifest, spool string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeEntry(tw, manifestName, int64(len(data)), manifest.CreatedAt, bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	for _, collection := range manifest.Collections {
+		f, err := os.Open(filepath.Join(spool, collection.Name+".bson"))
+		if err != nil {
+			return fmt.Errorf("failed to open spooled %s: %w", collection.Name, err)
+		}
+		err = writeEntry(tw, collection.File, collection.Bytes, manifest.CreatedAt, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
+
+func writeEntry(tw *tar.Writer, name string, size int64, modTime time.Time, content io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    size,
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// extJSON : canonical extended JSON of a document, nil for an empty one
+func extJSON(doc any) (json.RawMessage, error) {
+	data, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		return nil, err
+	}
+	if string(data) == "{}" {
+		return nil, nil
+	}
+	return data, nil
+}
+
+func indexName(spec bson.D) (string, bool) {
+	for _, e := range spec {
+		if e.Key == "name" {
+			name, ok := e.Value.(string)
+			return name, ok
+		}
+	}
+	return "", false
+}
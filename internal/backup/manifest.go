@@ -0,0 +1,72 @@
+// Package backup writes and reads database snapshots: a gzip compressed tar holding a manifest
+// followed by one file of concatenated BSON documents per collection.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+)
+
+// FormatVersion : version of the archive layout, bumped on incompatible changes
+const FormatVersion = 1
+
+const (
+	manifestName   = "manifest.json"
+	collectionsDir = "collections"
+)
+
+// Manifest : first entry of an archive, describes every collection it holds
+type Manifest struct {
+	Format        int                  `json:"format"`
+	CreatedAt     time.Time            `json:"created_at"`
+	Database      string               `json:"database"`
+	SchemaVersion int                  `json:"schema_version"`
+	Collections   []CollectionManifest `json:"collections"`
+}
+
+// CollectionManifest : one collection of an archive, Options and Indexes are canonical
+// extended JSON so they restore without loss (validators, partial filters, TTLs...)
+type CollectionManifest struct {
+	Name      string            `json:"name"`
+	File      string            `json:"file"`
+	Documents int64             `json:"documents"`
+	Bytes     int64             `json:"bytes"`
+	SHA256    string            `json:"sha256"`
+	Options   json.RawMessage   `json:"options,omitempty"`
+	Indexes   []json.RawMessage `json:"indexes,omitempty"`
+}
+
+// Collection : the entry of a collection, nil when the archive does not hold it
+func (m *Manifest) Collection(name string) *CollectionManifest {
+	for i := range m.Collections {
+		if m.Collections[i].Name == name {
+			return &m.Collections[i]
+		}
+	}
+	return nil
+}
+
+// validate : checks the manifest can be restored by this build
+func (m *Manifest) validate() error {
+	if m.Format != FormatVersion {
+		return fmt.Errorf("unsupported archive format %d, expected %d", m.Format, FormatVersion)
+	}
+	for _, collection := range m.Collections {
+		if collection.File != dataFile(collection.Name) {
+			return fmt.Errorf("collection %s has an unexpected file %q", collection.Name, collection.File)
+		}
+	}
+	return nil
+}
+
+// selected : whether a collection is part of the selection, an empty selection means all
+func selected(selection []string, name string) bool {
+	return len(selection) == 0 || slices.Contains(selection, name)
+}
+
+func dataFile(collection string) string {
+	return path.Join(collectionsDir, collection+".bson")
+}
@@ -0,0 +1,256 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var defaultRestoreBatchSize int = 1000
+
+type RestoreOptions struct {
+	Collections []string // empty means every collection of the archive
+	Drop        bool     // drop the target collections first, otherwise they must be empty
+	Indexes     bool     // recreate the indexes recorded in the archive
+	BatchSize   int
+}
+
+// Restore : loads an archive into db, checksums are checked while documents are inserted so
+// Verify should be run first to refuse a damaged archive before anything is written
+func Restore(ctx context.Context, db *mongo.Database, r io.Reader, opts RestoreOptions) (*Manifest, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultRestoreBatchSize
+	}
+
+	return readArchive(r, opts.Collections, func(collection *CollectionManifest, next func() (bson.Raw, error)) error {
+		return restoreCollection(ctx, db, collection, next, opts)
+	})
+}
+
+// Verify : reads a whole archive and checks the document counts and checksums of the selected collections
+func Verify(r io.Reader, collections []string) (*Manifest, error) {
+	return readArchive(r, collections, func(_ *CollectionManifest, next func() (bson.Raw, error)) error {
+		for {
+			if _, err := next(); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+		}
+	})
+}
+
+// readArchive : decodes the manifest then hands every selected collection to restore, which must
+// read its documents until io.EOF
+func readArchive(r io.Reader, selection []string, restore func(collection *CollectionManifest, next func() (bson.Raw, error)) error) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestName {
+		return nil, fmt.Errorf("not a backup archive: %s must come first", manifestName)
+	}
+	manifest := &Manifest{}
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if err := manifest.validate(); err != nil {
+		return nil, err
+	}
+	for _, name := range selection {
+		if manifest.Collection(name) == nil {
+			return nil, fmt.Errorf("collection %s is not in the archive", name)
+		}
+	}
+
+	done := []string{}
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		collection := collectionOfFile(manifest, header.Name)
+		if collection == nil {
+			return nil, fmt.Errorf("unexpected archive entry %s", header.Name)
+		}
+		if !selected(selection, collection.Name) {
+			continue
+		}
+
+		if err := readCollection(tr, collection, restore); err != nil {
+			return nil, err
+		}
+		done = append(done, collection.Name)
+	}
+
+	for _, collection := range manifest.Collections {
+		if selected(selection, collection.Name) && !slices.Contains(done, collection.Name) {
+			return nil, fmt.Errorf("archive is truncated, %s is missing", collection.Name)
+		}
+	}
+	return manifest, nil
+}
+
+// readCollection : feeds the documents of one entry to restore and checks them against the manifest
+func readCollection(content io.Reader, collection *CollectionManifest, restore func(collection *CollectionManifest, next func() (bson.Raw, error)) error) error {
+	hash := sha256.New()
+	in := io.TeeReader(content, hash)
+
+	var documents, size int64
+	next := func() (bson.Raw, error) {
+		doc, err := bson.NewFromIOReader(in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("corrupt document %d of %s: %w", documents+1, collection.Name, err)
+		}
+		documents++
+		size += int64(len(doc))
+		return doc, nil
+	}
+
+	if err := restore(collection, next); err != nil {
+		return err
+	}
+
+	if documents != collection.Documents || size != collection.Bytes {
+		return fmt.Errorf("%s holds %d documents (%d bytes), the manifest expects %d (%d bytes)",
+			collection.Name, documents, size, collection.Documents, collection.Bytes)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != collection.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: got %s, the manifest expects %s", collection.Name, sum, collection.SHA256)
+	}
+	return nil
+}
+
+func restoreCollection(ctx context.Context, db *mongo.Database, collection *CollectionManifest, next func() (bson.Raw, error), opts RestoreOptions) error {
+	target := db.Collection(collection.Name)
+
+	if opts.Drop {
+		if err := target.Drop(ctx); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", collection.Name, err)
+		}
+	} else {
+		count, err := target.CountDocuments(ctx, bson.D{}, options.Count().SetLimit(1))
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", collection.Name, err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%s is not empty in %s, restore with drop to replace it", collection.Name, db.Name())
+		}
+	}
+
+	if err := createCollection(ctx, db, collection); err != nil {
+		return err
+	}
+
+	batch := make([]any, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := target.InsertMany(ctx, batch); err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", collection.Name, err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		doc, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, doc)
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if opts.Indexes {
+		return createIndexes(ctx, db, collection)
+	}
+	return nil
+}
+
+// createCollection : creates the collection with its recorded options (validator, pre-images...),
+// an existing empty collection keeps its own options
+func createCollection(ctx context.Context, db *mongo.Database, collection *CollectionManifest) error {
+	command := bson.D{{Key: "create", Value: collection.Name}}
+	if len(collection.Options) > 0 {
+		var opts bson.D
+		if err := bson.UnmarshalExtJSON(collection.Options, true, &opts); err != nil {
+			return fmt.Errorf("failed to decode options of %s: %w", collection.Name, err)
+		}
+		command = append(command, opts...)
+	}
+
+	err := db.RunCommand(ctx, command).Err()
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 48 { // NamespaceExists
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", collection.Name, err)
+	}
+	return nil
+}
+
+func createIndexes(ctx context.Context, db *mongo.Database, collection *CollectionManifest) error {
+	if len(collection.Indexes) == 0 {
+		return nil
+	}
+
+	indexes := make(bson.A, len(collection.Indexes))
+	for i, index := range collection.Indexes {
+		var spec bson.D
+		if err := bson.UnmarshalExtJSON(index, true, &spec); err != nil {
+			return fmt.Errorf("failed to decode index of %s: %w", collection.Name, err)
+		}
+		indexes[i] = spec
+	}
+
+	command := bson.D{{Key: "createIndexes", Value: collection.Name}, {Key: "indexes", Value: indexes}}
+	if err := db.RunCommand(ctx, command).Err(); err != nil {
+		return fmt.Errorf("failed to create indexes on %s: %w", collection.Name, err)
+	}
+	return nil
+}
+
+func collectionOfFile(manifest *Manifest, file string) *CollectionManifest {
+	for i := range manifest.Collections {
+		if manifest.Collections[i].File == file {
+			return &manifest.Collections[i]
+		}
+	}
+	return nil
+}
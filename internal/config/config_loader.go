@@ -6,15 +6,19 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 )
 
 var (
-	GlobalConfig *Config
-	once         sync.Once
+	// current : the loaded config, replaced as a whole by every reload
+	current atomic.Pointer[Config]
+	once    sync.Once
 )
 
 type Config struct {
@@ -24,6 +28,10 @@ type Config struct {
 	ZapLogger   *ZapLoggerConf   `yaml:"zap_logger" mapstructure:"zap_logger"`
 	Lumberjack  *LumberjackConf  `yaml:"lumberjack" mapstructure:"lumberjack"`
 	JWTAuth     *JWTAuthConf     `yaml:"jwt_auth" mapstructure:"jwt_auth"`
+	Trash       *TrashConf       `yaml:"trash" mapstructure:"trash"`
+	RateLimit   *RateLimitConf   `yaml:"rate_limit" mapstructure:"rate_limit"`
+	// Features : feature flags by name, viper lowercases the names
+	Features map[string]bool `yaml:"features" mapstructure:"features"`
 }
 
 type HTTPServerConf struct {
@@ -34,19 +42,65 @@ type HTTPServerConf struct {
 	CertDir  string `yaml:"tls_cert_dir" mapstructure:"tls_cert_dir"`
 }
 
+// MongoDBConf : URI replaces host and port when set, the other settings (credentials included)
+// override the matching options of the URI
 type MongoDBConf struct {
-	Database string `yaml:"database" mapstructure:"database"`
-	Username string `yaml:"username" mapstructure:"username"`
-	Password string `yaml:"password" mapstructure:"password"`
-	Host     string `yaml:"host" mapstructure:"host"`
-	Port     string `yaml:"port" mapstructure:"port"`
+	URI                    string        `yaml:"uri" mapstructure:"uri"`
+	Database               string        `yaml:"database" mapstructure:"database"`
+	Username               string        `yaml:"username" mapstructure:"username"`
+	Password               string        `yaml:"password" mapstructure:"password"`
+	Host                   string        `yaml:"host" mapstructure:"host"`
+	Port                   string        `yaml:"port" mapstructure:"port"`
+	AuthSource             string        `yaml:"auth_source" mapstructure:"auth_source"`
+	ReplicaSet             string        `yaml:"replica_set" mapstructure:"replica_set"`
+	DirectConnection       bool          `yaml:"direct_connection" mapstructure:"direct_connection"`
+	AppName                string        `yaml:"app_name" mapstructure:"app_name"`
+	ReadPreference         string        `yaml:"read_preference" mapstructure:"read_preference"`
+	ReadConcern            string        `yaml:"read_concern" mapstructure:"read_concern"`
+	WriteConcern           string        `yaml:"write_concern" mapstructure:"write_concern"` // "majority" or a number of members
+	WriteTimeout           time.Duration `yaml:"write_timeout" mapstructure:"write_timeout"`
+	MaxPoolSize            uint64        `yaml:"max_pool_size" mapstructure:"max_pool_size"`
+	MinPoolSize            uint64        `yaml:"min_pool_size" mapstructure:"min_pool_size"`
+	MaxConnIdleTime        time.Duration `yaml:"max_conn_idle_time" mapstructure:"max_conn_idle_time"`
+	ConnectTimeout         time.Duration `yaml:"connect_timeout" mapstructure:"connect_timeout"`
+	ServerSelectionTimeout time.Duration `yaml:"server_selection_timeout" mapstructure:"server_selection_timeout"`
+	TLS                    MongoTLSConf  `yaml:"tls" mapstructure:"tls"`
+	AutoMigrate            bool          `yaml:"auto_migrate" mapstructure:"auto_migrate"`
+}
+
+type MongoTLSConf struct {
+	Enabled     bool   `yaml:"enabled" mapstructure:"enabled"`
+	CAFile      string `yaml:"ca_file" mapstructure:"ca_file"`
+	CertKeyFile string `yaml:"cert_key_file" mapstructure:"cert_key_file"` // PEM holding the client certificate and its key
+	Insecure    bool   `yaml:"insecure" mapstructure:"insecure"`
 }
 
 type DragonflyDBConf struct {
-	Host     string `yaml:"host" mapstructure:"host"`
-	Port     string `yaml:"port" mapstructure:"port"`
-	Password string `yaml:"password" mapstructure:"password"`
-	DB       int    `yaml:"db" mapstructure:"db"`
+	Host     string           `yaml:"host" mapstructure:"host"`
+	Port     string           `yaml:"port" mapstructure:"port"`
+	Password string           `yaml:"password" mapstructure:"password"`
+	DB       int              `yaml:"db" mapstructure:"db"`
+	Local    LocalCacheConf   `yaml:"local" mapstructure:"local"`
+	Breaker  CacheBreakerConf `yaml:"breaker" mapstructure:"breaker"`
+	// TTL : expiration of the cached entries
+	TTL time.Duration `yaml:"ttl" mapstructure:"ttl"`
+}
+
+// CacheBreakerConf : the cache is bypassed after FailureThreshold consecutive failed commands
+// and used again once a probe reaches dragonfly
+type CacheBreakerConf struct {
+	FailureThreshold int           `yaml:"failure_threshold" mapstructure:"failure_threshold"`
+	ProbeInterval    time.Duration `yaml:"probe_interval" mapstructure:"probe_interval"`
+	ProbeTimeout     time.Duration `yaml:"probe_timeout" mapstructure:"probe_timeout"`
+}
+
+// LocalCacheConf : in-process L1 tier in front of dragonfly, it must be enabled on every
+// instance or none since only instances with the tier announce their writes
+type LocalCacheConf struct {
+	Enabled    bool          `yaml:"enabled" mapstructure:"enabled"`
+	MaxEntries int           `yaml:"max_entries" mapstructure:"max_entries"`
+	MaxBytes   int64         `yaml:"max_bytes" mapstructure:"max_bytes"`
+	TTL        time.Duration `yaml:"ttl" mapstructure:"ttl"`
 }
 
 type ZapLoggerConf struct {
@@ -68,15 +122,28 @@ type JWTAuthConf struct {
 	Secret string `yaml:"secret" mapstructure:"secret"`
 }
 
-// Init : to initialize the configuration loading process.
-func Init(path, file string) {
-	var err error
+// TrashConf : how long soft deleted documents are kept before being purged
+type TrashConf struct {
+	Retention     time.Duration `yaml:"retention" mapstructure:"retention"`
+	PurgeInterval time.Duration `yaml:"purge_interval" mapstructure:"purge_interval"`
+}
+
+// RateLimitConf : token bucket per client IP, Burst requests at once then RequestsPerSecond
+type RateLimitConf struct {
+	Enabled           bool    `yaml:"enabled" mapstructure:"enabled"`
+	RequestsPerSecond float64 `yaml:"requests_per_second" mapstructure:"requests_per_second"`
+	Burst             int     `yaml:"burst" mapstructure:"burst"`
+}
 
+// Init : to initialize the configuration loading process, an empty path searches
+// DefaultSearchPaths.
+func Init(path, file string) {
 	// to ensure that the config is loaded only once
 	once.Do(func() {
 		// flag definitions
-		configPath := flag.String("config-path", path, "path to configuration path")
+		configPath := flag.String("config-path", path, "path to configuration path (default: search the usual paths)")
 		configFile := flag.String("config-file", file, "name of configuration file (without extension)")
+		profile := flag.String("profile", "", "config profile merged over the base file (default: $APP_PROFILE)")
 		isDebugMode := flag.Bool("debug", false, "enable gin debug mode")
 
 		if !flag.Parsed() {
@@ -91,42 +158,101 @@ func Init(path, file string) {
 			log.Println("Server is running in release mode.")
 		}
 
-		GlobalConfig, err = LoadConfig(*configPath, *configFile)
+		src := NewSource(*configPath, *configFile, *profile)
+		config, err := Load(src)
 		if err != nil {
 			log.Fatalf("Error loading config: %v", err)
 		}
-		log.Println("Configuration loaded successfully")
+		if gin.Mode() == gin.ReleaseMode {
+			if err := config.ValidateRelease(); err != nil {
+				log.Fatalf("Refusing to start in release mode: %v", err)
+			}
+		}
+		source = src
+		current.Store(config)
+
+		files, _ := src.Files()
+		log.Printf("Configuration loaded successfully from %s", strings.Join(files, ", "))
+	})
+}
+
+// Setup : to load the configuration without touching the command line flags,
+// used by the CLI subcommands which parse their own flags.
+func Setup(src Source) error {
+	var err error
+
+	once.Do(func() {
+		var config *Config
+		config, err = Load(src)
+		if err == nil {
+			source = src
+			current.Store(config)
+		}
 	})
+	return err
 }
 
-// LoadConfig : to load the YAML configuration file (using viper package).
+// LoadConfig : to load a single YAML configuration file, see Load.
 func LoadConfig(configPath, configFile string) (*Config, error) {
+	return Load(Source{Paths: []string{configPath}, File: configFile})
+}
+
+// Load : to load the layers of the configuration (using viper package) merged in order,
+// the APP_* environment variables and their _FILE variants override their keys,
+// an invalid config is reported as ValidationErrors.
+func Load(src Source) (*Config, error) {
 	var config *Config
 
+	files, err := src.Files()
+	if err != nil {
+		return nil, fmt.Errorf("fatal error config file: %w", err)
+	}
+
 	viperInst := viper.New() // init viper instance
-	viperInst.AddConfigPath(configPath)
-	viperInst.SetConfigName(configFile)
 	viperInst.SetConfigType("yaml")
 
 	setDefaultConfig(viperInst)
 
-	if err := viperInst.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("fatal error config file: %w", err)
+	for i, file := range files {
+		viperInst.SetConfigFile(file)
+		read := viperInst.MergeInConfig
+		if i == 0 {
+			read = viperInst.ReadInConfig
+		}
+		if err := read(); err != nil {
+			return nil, fmt.Errorf("fatal error config file %s: %w", file, err)
+		}
+	}
+
+	if err := bindEnv(viperInst); err != nil {
+		return nil, fmt.Errorf("environment override error : %w", err)
 	}
 
 	if err := viperInst.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("unmarshaling config error : %w", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
-// GetConfig : to get the global configuration instance.
+// GetConfig : to get the global configuration instance, a reload replaces it
+// so long running code should read it again (or Subscribe) instead of keeping it.
 func GetConfig() (*Config, error) {
-	if GlobalConfig == nil {
+	config := current.Load()
+	if config == nil {
 		return nil, fmt.Errorf("configuration not initialized you must call config.Init() first")
 	}
-	return GlobalConfig, nil
+	return config, nil
+}
+
+// FeatureEnabled : whether the feature flag is on, unknown flags are off.
+func FeatureEnabled(name string) bool {
+	config := current.Load()
+	return config != nil && config.Features[strings.ToLower(name)]
 }
 
 // setDefaultConfig : to set important default values.
@@ -140,11 +266,22 @@ func setDefaultConfig(viperInst *viper.Viper) {
 	viperInst.SetDefault("mongodb.host", "localhost")
 	viperInst.SetDefault("mongodb.port", "27017")
 	viperInst.SetDefault("mongodb.database", "senior_project")
+	viperInst.SetDefault("mongodb.auto_migrate", true)
+	viperInst.SetDefault("mongodb.connect_timeout", "10s")
+	viperInst.SetDefault("mongodb.server_selection_timeout", "10s")
 
 	// DragonflyDB default values
 	viperInst.SetDefault("dragonflydb.host", "localhost")
 	viperInst.SetDefault("dragonflydb.port", "6379")
 	viperInst.SetDefault("dragonflydb.db", 0)
+	viperInst.SetDefault("dragonflydb.local.enabled", false)
+	viperInst.SetDefault("dragonflydb.local.max_entries", 10000)
+	viperInst.SetDefault("dragonflydb.local.max_bytes", 64<<20)
+	viperInst.SetDefault("dragonflydb.local.ttl", "30s")
+	viperInst.SetDefault("dragonflydb.breaker.failure_threshold", 5)
+	viperInst.SetDefault("dragonflydb.breaker.probe_interval", "5s")
+	viperInst.SetDefault("dragonflydb.breaker.probe_timeout", "1s")
+	viperInst.SetDefault("dragonflydb.ttl", "5m")
 
 	// Zap default values
 	viperInst.SetDefault("zap_logger.log_dir", "./logs")
@@ -152,4 +289,13 @@ func setDefaultConfig(viperInst *viper.Viper) {
 	viperInst.SetDefault("zap_logger.level", "debug")
 	viperInst.SetDefault("zap_logger.encoding", "json")
 	viperInst.SetDefault("zap_logger.log_file", "app.log")
+
+	// trash default values
+	viperInst.SetDefault("trash.retention", "720h")
+	viperInst.SetDefault("trash.purge_interval", "24h")
+
+	// rate limit default values
+	viperInst.SetDefault("rate_limit.enabled", true)
+	viperInst.SetDefault("rate_limit.requests_per_second", 20)
+	viperInst.SetDefault("rate_limit.burst", 40)
 }
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configDir : a directory holding the committed config.yaml and the given extra layers
+func configDir(t *testing.T, layers map[string]string) string {
+	t.Helper()
+
+	base, err := os.ReadFile("config.yaml")
+	if err != nil {
+		t.Fatalf("failed to read the committed config: %v", err)
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config.yaml"), string(base))
+	for name, content := range layers {
+		writeFile(t, filepath.Join(dir, name), content)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// editFile : replaces each old string of the file by the new one following it
+func editFile(t *testing.T, path string, oldNew ...string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	content := string(data)
+	for i := 0; i < len(oldNew); i += 2 {
+		if !strings.Contains(content, oldNew[i]) {
+			t.Fatalf("%s does not contain %q", path, oldNew[i])
+		}
+		content = strings.Replace(content, oldNew[i], oldNew[i+1], 1)
+	}
+	writeFile(t, path, content)
+}
+
+// fieldKeys : the keys reported by a ValidationErrors
+func fieldKeys(t *testing.T, err error) []string {
+	t.Helper()
+
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("error = %v, want ValidationErrors", err)
+	}
+	keys := make([]string, len(validationErrs))
+	for i, fieldErr := range validationErrs {
+		keys[i] = fieldErr.Key
+	}
+	return keys
+}
+
+func TestSourceFiles(t *testing.T) {
+	dir := configDir(t, map[string]string{
+		"config.production.yaml": "zap_logger:\n  level: info\n",
+		"config.local.yaml":      "http_server:\n  port: \"9443\"\n",
+	})
+
+	t.Run("layers in order", func(t *testing.T) {
+		files, err := Source{Paths: []string{t.TempDir(), dir}, File: "config", Profile: "production"}.Files()
+		want := []string{
+			filepath.Join(dir, "config.yaml"),
+			filepath.Join(dir, "config.production.yaml"),
+			filepath.Join(dir, "config.local.yaml"),
+		}
+		if err != nil || !slices.Equal(files, want) {
+			t.Fatalf("Files = %q, %v, want %q", files, err, want)
+		}
+	})
+
+	t.Run("without a profile", func(t *testing.T) {
+		files, err := Source{Paths: []string{dir}, File: "config"}.Files()
+		if err != nil || len(files) != 2 || !strings.HasSuffix(files[1], "config.local.yaml") {
+			t.Fatalf("Files = %q, %v", files, err)
+		}
+	})
+
+	for name, src := range map[string]Source{
+		"missing file":     {Paths: []string{t.TempDir()}, File: "config"},
+		"missing profile":  {Paths: []string{dir}, File: "config", Profile: "staging"},
+		"invalid profile":  {Paths: []string{dir}, File: "config", Profile: "../production"},
+		"reserved profile": {Paths: []string{dir}, File: "config", Profile: "local"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if files, err := src.Files(); err == nil {
+				t.Fatalf("Files = %q, want an error", files)
+			}
+		})
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir := configDir(t, map[string]string{
+		"config.production.yaml": "http_server:\n  address: \"0.0.0.0\"\nzap_logger:\n  level: info\n",
+		"config.local.yaml":      "zap_logger:\n  level: warn\n",
+	})
+
+	cfg, err := Load(Source{Paths: []string{dir}, File: "config", Profile: "production"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// the profile overrides the base, the local layer overrides both, the rest is merged
+	if cfg.HTTPServer.Addr != "0.0.0.0" || cfg.HTTPServer.Port != "8443" {
+		t.Fatalf("http_server = %+v", cfg.HTTPServer)
+	}
+	if cfg.ZapLogger.Level != "warn" || cfg.ZapLogger.Encoding != "json" {
+		t.Fatalf("zap_logger = %+v", cfg.ZapLogger)
+	}
+	// keys missing from every layer take their default
+	if cfg.DragonflyDB.Host != "localhost" || cfg.DragonflyDB.Port != "6379" {
+		t.Fatalf("dragonflydb = %+v", cfg.DragonflyDB)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	dir := configDir(t, nil)
+
+	t.Run("variables", func(t *testing.T) {
+		t.Setenv("APP_MONGODB_DATABASE", "from_env")
+		t.Setenv("APP_DRAGONFLYDB_LOCAL_TTL", "45s")
+		// not in the config file
+		t.Setenv("APP_MONGODB_USERNAME", "app")
+
+		cfg, err := Load(Source{Paths: []string{dir}, File: "config"})
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if cfg.MongoDB.Database != "from_env" || cfg.MongoDB.Username != "app" || cfg.DragonflyDB.Local.TTL != 45*time.Second {
+			t.Fatalf("overrides not applied: mongodb = %+v, local ttl = %v", cfg.MongoDB, cfg.DragonflyDB.Local.TTL)
+		}
+	})
+
+	t.Run("file variables", func(t *testing.T) {
+		secretFile := filepath.Join(t.TempDir(), "jwt_secret")
+		writeFile(t, secretFile, strings.Repeat("s", 40)+"\n")
+		t.Setenv("APP_JWT_AUTH_SECRET_FILE", secretFile)
+
+		cfg, err := Load(Source{Paths: []string{dir}, File: "config"})
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if cfg.JWTAuth.Secret != strings.Repeat("s", 40) {
+			t.Fatalf("secret = %q, want the content of the file without the newline", cfg.JWTAuth.Secret)
+		}
+
+		t.Setenv("APP_JWT_AUTH_SECRET", "another")
+		if _, err := Load(Source{Paths: []string{dir}, File: "config"}); err == nil || !strings.Contains(err.Error(), "both APP_JWT_AUTH_SECRET and APP_JWT_AUTH_SECRET_FILE") {
+			t.Fatalf("Load with both variables error = %v", err)
+		}
+	})
+
+	t.Run("names", func(t *testing.T) {
+		if name := envName("dragonflydb.breaker.probe_timeout"); name != "APP_DRAGONFLYDB_BREAKER_PROBE_TIMEOUT" {
+			t.Fatalf("envName = %s", name)
+		}
+		keys := configKeys(reflect.TypeOf(Config{}), "")
+		for _, key := range []string{"mongodb.tls.ca_file", "dragonflydb.local.ttl", "features"} {
+			if !slices.Contains(keys, key) {
+				t.Errorf("configKeys misses %s", key)
+			}
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	dir := configDir(t, map[string]string{
+		"config.broken.yaml": `
+http_server:
+  port: "70000"
+  cert_file: "certs/cert.pem"
+  key_file: ""
+mongodb:
+  password: "secret"
+dragonflydb:
+  breaker:
+    probe_interval: "1s"
+    probe_timeout: "2s"
+zap_logger:
+  level: "loud"
+jwt_auth:
+  secret: "short"
+rate_limit:
+  burst: 0
+`,
+	})
+
+	_, err := Load(Source{Paths: []string{dir}, File: "config", Profile: "broken"})
+	want := []string{
+		"http_server.port",
+		"http_server.cert_file",
+		"mongodb.username",
+		"dragonflydb.breaker.probe_timeout",
+		"zap_logger.level",
+		"jwt_auth.secret",
+		"rate_limit.burst",
+	}
+	if keys := fieldKeys(t, err); !slices.Equal(keys, want) {
+		t.Fatalf("invalid keys = %q, want %q", keys, want)
+	}
+	if !strings.HasPrefix(err.Error(), "invalid configuration (7 error(s)):") {
+		t.Fatalf("error = %q", err)
+	}
+
+	// a missing section is reported once instead of once per key
+	if keys := fieldKeys(t, (&Config{}).Validate()); len(keys) != 8 || keys[0] != "http_server" {
+		t.Fatalf("invalid keys of an empty config = %q", keys)
+	}
+}
+
+func TestValidateRelease(t *testing.T) {
+	cfg, err := Load(Source{Paths: []string{configDir(t, nil)}, File: "config"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// the committed development secret is refused
+	if err := cfg.ValidateRelease(); err == nil {
+		t.Fatal("ValidateRelease accepted the committed secret")
+	}
+
+	cfg.JWTAuth.Secret = strings.Repeat("x", 64)
+	if err := cfg.ValidateRelease(); err != nil {
+		t.Fatalf("ValidateRelease: %v", err)
+	}
+}
+
+// loaded : makes the config of dir the current one like Init does, the previous state is
+// restored when the test ends
+func loaded(t *testing.T, dir string) {
+	t.Helper()
+
+	src := Source{Paths: []string{dir}, File: "config"}
+	cfg, err := Load(src)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	prevSource, prevConfig := source, current.Load()
+	subMu.Lock()
+	prevSubscribers := subscribers
+	subMu.Unlock()
+	t.Cleanup(func() {
+		source = prevSource
+		current.Store(prevConfig)
+		subMu.Lock()
+		subscribers = prevSubscribers
+		subMu.Unlock()
+	})
+
+	source = src
+	current.Store(cfg)
+}
+
+func TestReload(t *testing.T) {
+	dir := configDir(t, nil)
+	loaded(t, dir)
+
+	var notified Changes
+	Subscribe(func(cfg *Config, changes Changes) {
+		notified = changes
+	})
+
+	editFile(t, filepath.Join(dir, "config.yaml"),
+		`level: "debug"`, `level: "info"`,
+		"burst: 40", "burst: 80",
+		"features: {}", "features:\n  New_Exam_UI: true",
+		`port: "8443"`, `port: "9443"`,
+		`secret: "1e029cd5`, `secret: "2e029cd5`,
+	)
+
+	result, err := Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	wantApplied := Changes{
+		{Key: "features.new_exam_ui", Old: "<unset>", New: "true"},
+		{Key: "rate_limit.burst", Old: "40", New: "80"},
+		{Key: "zap_logger.level", Old: "debug", New: "info"},
+	}
+	// secrets are redacted
+	wantRejected := Changes{
+		{Key: "http_server.port", Old: "8443", New: "9443"},
+		{Key: "jwt_auth.secret", Old: "<redacted>", New: "<redacted>"},
+	}
+	if !slices.Equal(result.Applied, wantApplied) || !slices.Equal(result.Rejected, wantRejected) {
+		t.Fatalf("Reload applied %+v and rejected %+v", result.Applied, result.Rejected)
+	}
+	if !slices.Equal(notified, wantApplied) {
+		t.Fatalf("subscribers got %+v", notified)
+	}
+
+	cfg, err := GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	if cfg.ZapLogger.Level != "info" || cfg.RateLimit.Burst != 80 || !FeatureEnabled("NEW_EXAM_UI") {
+		t.Fatalf("reloadable keys not applied: level = %s, burst = %d", cfg.ZapLogger.Level, cfg.RateLimit.Burst)
+	}
+	if cfg.HTTPServer.Port != "8443" || cfg.JWTAuth.Secret[0] != '1' {
+		t.Fatal("a key that needs a restart was applied")
+	}
+
+	// an invalid file changes nothing
+	editFile(t, filepath.Join(dir, "config.yaml"), `level: "info"`, `level: "loud"`)
+	if _, err := Reload(); err == nil {
+		t.Fatal("Reload accepted an invalid file")
+	}
+	if current.Load() != cfg {
+		t.Fatal("an invalid file replaced the config")
+	}
+}
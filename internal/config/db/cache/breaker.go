@@ -0,0 +1,311 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrUnavailable : dragonfly is unhealthy, the command was not sent
+var ErrUnavailable = errors.New("cache unavailable")
+
+// BreakerOptions : when the cache is bypassed and how it is probed for recovery
+type BreakerOptions struct {
+	FailureThreshold int           // consecutive failed commands that open the breaker
+	ProbeInterval    time.Duration // how often dragonfly is pinged
+	ProbeTimeout     time.Duration
+}
+
+// maxPendingInvalidations : invalidations kept while the breaker is open, the ones past it are lost
+// and the entries may be served until they expire
+const maxPendingInvalidations = 10000
+
+// breaker states
+const (
+	breakerClosed = "closed" // commands go to dragonfly
+	breakerOpen   = "open"   // commands fail with ErrUnavailable, only the probes go through
+)
+
+// Health : status of the cache as seen by this instance
+type Health struct {
+	Available bool      `json:"available"`
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	LastError string    `json:"last_error,omitempty"`
+	Trips     uint64    `json:"trips"`    // times the breaker opened
+	Bypassed  uint64    `json:"bypassed"` // commands not sent while it was open
+}
+
+// breaker : opens after FailureThreshold consecutive failures, only a successful probe closes it
+type breaker struct {
+	threshold int32
+	open      atomic.Bool
+	failures  atomic.Int32
+	trips     atomic.Uint64
+	bypassed  atomic.Uint64
+
+	mu          sync.Mutex
+	since       time.Time
+	lastError   string
+	pending     map[string]struct{} // invalidations to replay on recovery
+	pendingTags map[string]struct{} // tag invalidations to replay on recovery
+	lost        bool                // pending overflowed
+}
+
+func newBreaker(threshold int) *breaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &breaker{
+		threshold:   int32(threshold),
+		since:       time.Now(),
+		pending:     map[string]struct{}{},
+		pendingTags: map[string]struct{}{},
+	}
+}
+
+// probeKey : marks the context of a probe, probes go through an open breaker
+type probeKey struct{}
+
+func (b *breaker) allow(ctx context.Context) bool {
+	if !b.open.Load() || ctx.Value(probeKey{}) != nil {
+		return true
+	}
+	b.bypassed.Add(1)
+	return false
+}
+
+// failed : reports whether err means dragonfly is unhealthy, misses, server replies and
+// callers giving up are not its fault
+func failed(ctx context.Context, err error) bool {
+	if err == nil || err == redis.Nil || errors.Is(err, ErrUnavailable) || ctx.Err() != nil {
+		return false
+	}
+	var reply redis.Error
+	return !errors.As(err, &reply)
+}
+
+// record : counts the outcome of a command, true when it opened the breaker
+func (b *breaker) record(ctx context.Context, err error) bool {
+	if !failed(ctx, err) {
+		if err == nil || err == redis.Nil {
+			b.failures.Store(0)
+		}
+		return false
+	}
+	if b.failures.Add(1) < b.threshold {
+		return false
+	}
+	return b.trip(err)
+}
+
+// trip : opens the breaker, true when it was closed
+func (b *breaker) trip(err error) bool {
+	if !b.open.CompareAndSwap(false, true) {
+		return false
+	}
+	b.trips.Add(1)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.since = time.Now()
+	b.lastError = err.Error()
+	return true
+}
+
+// reset : closes the breaker, returns the invalidations to replay when it was open
+func (b *breaker) reset() (pending, pendingTags []string, lost bool, closed bool) {
+	b.failures.Store(0)
+	if !b.open.CompareAndSwap(true, false) {
+		return nil, nil, false, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.since = time.Now()
+	for key := range b.pending {
+		pending = append(pending, key)
+	}
+	for tag := range b.pendingTags {
+		pendingTags = append(pendingTags, tag)
+	}
+	lost = b.lost
+	b.pending = map[string]struct{}{}
+	b.pendingTags = map[string]struct{}{}
+	b.lost = false
+	return pending, pendingTags, lost, true
+}
+
+// deferInvalidation : keeps keys to invalidate once dragonfly is back
+func (b *breaker) deferInvalidation(keys ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		if len(b.pending)+len(b.pendingTags) >= maxPendingInvalidations {
+			b.lost = true
+			return
+		}
+		b.pending[key] = struct{}{}
+	}
+}
+
+// deferTagInvalidation : keeps tags to invalidate once dragonfly is back
+func (b *breaker) deferTagInvalidation(tags ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, tag := range tags {
+		if len(b.pending)+len(b.pendingTags) >= maxPendingInvalidations {
+			b.lost = true
+			return
+		}
+		b.pendingTags[tag] = struct{}{}
+	}
+}
+
+func (b *breaker) health() Health {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	open := b.open.Load()
+	state := breakerClosed
+	if open {
+		state = breakerOpen
+	}
+	return Health{
+		Available: !open,
+		State:     state,
+		Since:     b.since,
+		LastError: b.lastError,
+		Trips:     b.trips.Load(),
+		Bypassed:  b.bypassed.Load(),
+	}
+}
+
+// breakerHook : puts the breaker in front of every command the client sends
+type breakerHook struct {
+	cache *Cache
+}
+
+func (h breakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h breakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !h.cache.breaker.allow(ctx) {
+			cmd.SetErr(ErrUnavailable)
+			return ErrUnavailable
+		}
+		err := next(ctx, cmd)
+		if h.cache.breaker.record(ctx, err) {
+			h.cache.onUnavailable(err)
+		}
+		return err
+	}
+}
+
+func (h breakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !h.cache.breaker.allow(ctx) {
+			for _, cmd := range cmds {
+				cmd.SetErr(ErrUnavailable)
+			}
+			return ErrUnavailable
+		}
+		err := next(ctx, cmds)
+		if h.cache.breaker.record(ctx, err) {
+			h.cache.onUnavailable(err)
+		}
+		return err
+	}
+}
+
+// onUnavailable : the breaker just opened, the L1 copies can no longer be kept in sync
+func (c *Cache) onUnavailable(err error) {
+	utils.LogErrorWithLevel("warn", utils.CacheUnavailable.Type, utils.CacheUnavailable.Code, utils.CacheUnavailable.Msg, err)
+	if local := c.local.Load(); local != nil {
+		local.flush()
+	}
+}
+
+// onRecovered : the breaker just closed, replays the invalidations missed meanwhile and brings
+// the L1 tier back
+func (c *Cache) onRecovered(ctx context.Context, pending, pendingTags []string, lost bool) {
+	utils.LogInfo(utils.CacheRecovered.Type, utils.CacheRecovered.Msg,
+		zap.Int("pending_invalidations", len(pending)),
+		zap.Int("pending_tag_invalidations", len(pendingTags)),
+	)
+
+	if local := c.local.Load(); local != nil {
+		// announcements sent during the outage were lost
+		local.flush()
+	}
+	if len(pending) > 0 {
+		if err := c.Invalidate(pending...); err != nil {
+			utils.LogErrorWithLevel("warn", utils.DragonflyFailedToDeleteCache.Type, utils.DragonflyFailedToDeleteCache.Code, utils.DragonflyFailedToDeleteCache.Msg, err)
+		}
+	}
+	if len(pendingTags) > 0 {
+		if err := c.InvalidateTags(ctx, pendingTags...); err != nil {
+			utils.LogErrorWithLevel("warn", utils.DragonflyFailedToDeleteCache.Type, utils.DragonflyFailedToDeleteCache.Code, utils.DragonflyFailedToDeleteCache.Msg, err)
+		}
+	}
+	if lost {
+		utils.LogErrorWithLevel("warn", utils.CacheInvalidationsLost.Type, utils.CacheInvalidationsLost.Code, utils.CacheInvalidationsLost.Msg,
+			errors.New("too many invalidations while the cache was unavailable"))
+	}
+
+	if c.localOpts != nil && c.local.Load() == nil {
+		if err := c.EnableLocalCache(ctx, *c.localOpts); err != nil {
+			utils.LogErrorWithLevel("warn", utils.LocalCacheFailedToInit.Type, utils.LocalCacheFailedToInit.Code, utils.LocalCacheFailedToInit.Msg, err)
+		}
+	}
+}
+
+// probe : pings dragonfly through an open breaker
+func (c *Cache) probe(ctx context.Context, timeout time.Duration) error {
+	probeCtx, cancel := context.WithTimeout(context.WithValue(ctx, probeKey{}, true), timeout)
+	defer cancel()
+	return c.client.Ping(probeCtx).Err()
+}
+
+// monitor : probes dragonfly until ctx is done, opening the breaker when it is down and closing it
+// once it is back, go-redis reconnects on its own
+func (c *Cache) monitor(ctx context.Context, opts BreakerOptions) {
+	ticker := time.NewTicker(opts.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.probe(ctx, opts.ProbeTimeout); err != nil {
+			if ctx.Err() == nil && c.breaker.trip(err) {
+				c.onUnavailable(err)
+			}
+			continue
+		}
+		if pending, pendingTags, lost, closed := c.breaker.reset(); closed {
+			c.onRecovered(ctx, pending, pendingTags, lost)
+		}
+	}
+}
+
+// Health : whether dragonfly is used or bypassed
+func (c *Cache) Health() Health {
+	return c.breaker.health()
+}
+
+// Health : the in-memory cache is always available
+func (m *MemoryCache) Health() Health {
+	return Health{Available: true, State: breakerClosed}
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// replyError : an error answered by dragonfly, like a wrong type or a script error
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+
+func (replyError) RedisError() {}
+
+var errDown = errors.New("dial tcp: connection refused")
+
+func TestBreakerRecord(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("consecutive failures", func(t *testing.T) {
+		b := newBreaker(3)
+		b.record(ctx, errDown)
+		b.record(ctx, errDown)
+		// a success in between starts the count again
+		b.record(ctx, redis.Nil)
+		b.record(ctx, errDown)
+		if b.record(ctx, errDown) || b.open.Load() {
+			t.Fatal("breaker opened before the threshold")
+		}
+		if !b.record(ctx, errDown) || !b.open.Load() {
+			t.Fatal("breaker did not open at the threshold")
+		}
+		if health := b.health(); health.Available || health.State != breakerOpen || health.Trips != 1 || health.LastError != errDown.Error() {
+			t.Fatalf("health = %+v", health)
+		}
+	})
+
+	t.Run("not failures", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		b := newBreaker(1)
+		b.record(ctx, replyError("WRONGTYPE"))
+		b.record(ctx, ErrUnavailable)
+		b.record(cancelled, errDown)
+		if b.open.Load() || b.failures.Load() != 0 {
+			t.Fatalf("breaker counted %d failures", b.failures.Load())
+		}
+	})
+}
+
+func TestBreakerAllow(t *testing.T) {
+	b := newBreaker(1)
+	if !b.allow(context.Background()) {
+		t.Fatal("closed breaker refused a command")
+	}
+
+	b.trip(errDown)
+	if b.allow(context.Background()) || b.allow(context.Background()) {
+		t.Fatal("open breaker let a command through")
+	}
+	if !b.allow(context.WithValue(context.Background(), probeKey{}, true)) {
+		t.Fatal("open breaker refused a probe")
+	}
+	if bypassed := b.health().Bypassed; bypassed != 2 {
+		t.Fatalf("bypassed = %d, want 2", bypassed)
+	}
+}
+
+func TestBreakerReset(t *testing.T) {
+	t.Run("replays the invalidations", func(t *testing.T) {
+		b := newBreaker(1)
+		if _, _, _, closed := b.reset(); closed {
+			t.Fatal("reset of a closed breaker reported it closed")
+		}
+
+		b.trip(errDown)
+		b.deferInvalidation("a", "b", "a")
+		b.deferTagInvalidation("students")
+
+		pending, pendingTags, lost, closed := b.reset()
+		slices.Sort(pending)
+		if !closed || lost || !slices.Equal(pending, []string{"a", "b"}) || !slices.Equal(pendingTags, []string{"students"}) {
+			t.Fatalf("reset = %q, %q, %v, %v", pending, pendingTags, lost, closed)
+		}
+		if !b.health().Available {
+			t.Fatal("breaker still open after the reset")
+		}
+
+		// the next outage starts empty
+		b.trip(errDown)
+		if pending, pendingTags, _, _ := b.reset(); len(pending) != 0 || len(pendingTags) != 0 {
+			t.Fatalf("second reset = %q, %q", pending, pendingTags)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		b := newBreaker(1)
+		b.trip(errDown)
+		for i := range maxPendingInvalidations {
+			b.deferInvalidation(fmt.Sprintf("key:%d", i))
+		}
+		b.deferTagInvalidation("students")
+
+		pending, pendingTags, lost, _ := b.reset()
+		if !lost || len(pending) != maxPendingInvalidations || len(pendingTags) != 0 {
+			t.Fatalf("reset kept %d keys and %d tags, lost = %v", len(pending), len(pendingTags), lost)
+		}
+	})
+}
+
+func TestBreakerHook(t *testing.T) {
+	utils.InitUtils()
+
+	// nothing listens on port 1, every command fails to dial
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	c := newCache(client, "test", BreakerOptions{FailureThreshold: 2})
+
+	for range 2 {
+		if err := c.Set("item", 1, 0); err == nil || errors.Is(err, ErrUnavailable) {
+			t.Fatalf("Set error = %v, want the dial error", err)
+		}
+	}
+	if health := c.Health(); health.Available || health.Trips != 1 {
+		t.Fatalf("health = %+v", health)
+	}
+
+	// commands are no longer sent, invalidations wait for dragonfly
+	var got int
+	if err := c.Get("item", &got); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Get error = %v, want ErrUnavailable", err)
+	}
+	if err := c.Invalidate("item"); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+
+	// reads fall back to the database without storing the value
+	fetches := 0
+	fetch := func(ctx context.Context) (any, error) {
+		fetches++
+		return 42, nil
+	}
+	for range 2 {
+		if err := c.GetFromCacheOrFetchDB(context.Background(), "item", &got, fetch, 0); err != nil || got != 42 {
+			t.Fatalf("GetFromCacheOrFetchDB = %d, %v", got, err)
+		}
+	}
+	if fetches != 2 {
+		t.Fatalf("fetched %d times, want 2", fetches)
+	}
+
+	if pending, _, _, closed := c.breaker.reset(); !closed || !slices.Equal(pending, []string{"item"}) {
+		t.Fatalf("pending invalidations = %q", pending)
+	}
+}
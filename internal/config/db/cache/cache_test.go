@@ -0,0 +1,22 @@
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache"
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache/cachetest"
+)
+
+func TestMemoryCache(t *testing.T) {
+	cachetest.StoreContract(t, func(t *testing.T) cache.Store {
+		return cache.NewMemoryCache(fmt.Sprintf("contract_%d", time.Now().UnixNano()))
+	})
+}
+
+func TestDragonflyCache(t *testing.T) {
+	cachetest.StoreContract(t, func(t *testing.T) cache.Store {
+		return cachetest.Dragonfly(t)
+	})
+}
@@ -0,0 +1,378 @@
+// Package cachetest holds the contract suite every cache.Store implementation must pass.
+package cachetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache"
+)
+
+type item struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// StoreContract : behaviour every cache.Store must have, newStore must return an empty store
+// with its own key prefix
+func StoreContract(t *testing.T, newStore func(t *testing.T) cache.Store) {
+	ctx := context.Background()
+
+	t.Run("set and get", func(t *testing.T) {
+		store := newStore(t)
+
+		if err := store.Set("item", item{Name: "a", Count: 1}, time.Minute); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+
+		var got item
+		if err := store.Get("item", &got); err != nil || got != (item{Name: "a", Count: 1}) {
+			t.Fatalf("Get = %+v, %v", got, err)
+		}
+		if err := store.GetWithContext(ctx, "item", &got); err != nil || got.Name != "a" {
+			t.Fatalf("GetWithContext = %+v, %v", got, err)
+		}
+	})
+
+	t.Run("miss", func(t *testing.T) {
+		store := newStore(t)
+
+		var got item
+		if err := store.Get("missing", &got); !errors.Is(err, cache.ErrMiss) {
+			t.Fatalf("Get of a missing key error = %v, want cache.ErrMiss", err)
+		}
+		if err := store.GetWithContext(ctx, "missing", &got); !errors.Is(err, cache.ErrMiss) {
+			t.Fatalf("GetWithContext of a missing key error = %v, want cache.ErrMiss", err)
+		}
+	})
+
+	t.Run("expiration", func(t *testing.T) {
+		store := newStore(t)
+
+		if err := store.Set("short", item{Name: "a"}, 50*time.Millisecond); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		time.Sleep(150 * time.Millisecond)
+
+		var got item
+		if err := store.Get("short", &got); err == nil {
+			t.Fatalf("Get returned an expired key")
+		}
+	})
+
+	t.Run("delete and invalidate", func(t *testing.T) {
+		store := newStore(t)
+
+		for _, key := range []string{"a", "b", "c"} {
+			if err := store.Set(key, item{Name: key}, time.Minute); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+		}
+		if err := store.Delete("a"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := store.Invalidate("b", "c"); err != nil {
+			t.Fatalf("Invalidate: %v", err)
+		}
+
+		var got item
+		for _, key := range []string{"a", "b", "c"} {
+			if err := store.Get(key, &got); err == nil {
+				t.Fatalf("key %q is still cached", key)
+			}
+		}
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		store := newStore(t)
+
+		for i := range 3 {
+			if err := store.Set(fmt.Sprintf("key:%d", i), item{Count: i}, time.Minute); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+		}
+		if err := store.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+
+		var got item
+		if err := store.Get("key:0", &got); err == nil {
+			t.Fatalf("key is still cached after Flush")
+		}
+	})
+
+	t.Run("flush many keys", func(t *testing.T) {
+		store := newStore(t)
+
+		// more than one SCAN batch
+		const n = 1200
+		for i := range n {
+			if err := store.Set(fmt.Sprintf("key:%d", i), item{Count: i}, time.Minute); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+		}
+		if err := store.Tag(ctx, "key:0", time.Minute, "group"); err != nil {
+			t.Fatalf("Tag: %v", err)
+		}
+		if err := store.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+
+		var got item
+		for _, i := range []int{0, n / 2, n - 1} {
+			if err := store.Get(fmt.Sprintf("key:%d", i), &got); err == nil {
+				t.Fatalf("key:%d is still cached after Flush", i)
+			}
+		}
+
+		// the tag was flushed too, it no longer reaches a key written after the flush
+		if err := store.Set("key:0", item{Count: 1}, time.Minute); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if err := store.InvalidateTags(ctx, "group"); err != nil {
+			t.Fatalf("InvalidateTags: %v", err)
+		}
+		if err := store.Get("key:0", &got); err != nil {
+			t.Fatalf("Get after invalidating a flushed tag: %v", err)
+		}
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		store := newStore(t)
+
+		entries := map[string][]string{
+			"student:1":       {"student:1"},
+			"student:email:1": {"student:1"},
+			"exam:1":          {"exam:1"},
+			"attempt:1:1":     {"student:1", "exam:1"},
+			"untagged":        nil,
+		}
+		for key, tags := range entries {
+			if err := store.Set(key, item{Name: key}, time.Minute); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if err := store.Tag(ctx, key, time.Minute, tags...); err != nil {
+				t.Fatalf("Tag: %v", err)
+			}
+		}
+
+		if err := store.InvalidateTags(ctx, "student:1"); err != nil {
+			t.Fatalf("InvalidateTags: %v", err)
+		}
+		var got item
+		for _, key := range []string{"student:1", "student:email:1", "attempt:1:1"} {
+			if err := store.Get(key, &got); !errors.Is(err, cache.ErrMiss) {
+				t.Fatalf("Get %q after invalidating its tag: %v", key, err)
+			}
+		}
+		for _, key := range []string{"exam:1", "untagged"} {
+			if err := store.Get(key, &got); err != nil {
+				t.Fatalf("Get %q with another tag: %v", key, err)
+			}
+		}
+
+		// the invalidated tag starts over
+		if err := store.Set("student:1", item{Name: "again"}, time.Minute); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if err := store.InvalidateTags(ctx, "exam:1", "unknown"); err != nil {
+			t.Fatalf("InvalidateTags: %v", err)
+		}
+		if err := store.Get("exam:1", &got); !errors.Is(err, cache.ErrMiss) {
+			t.Fatalf("Get exam:1 after invalidating its tag: %v", err)
+		}
+		if err := store.Get("student:1", &got); err != nil {
+			t.Fatalf("Get of a key written after its tag was invalidated: %v", err)
+		}
+	})
+
+	t.Run("cache aside", func(t *testing.T) {
+		store := newStore(t)
+
+		calls := 0
+		fetch := func(context.Context) (any, error) {
+			calls++
+			return &item{Name: "db", Count: calls}, nil
+		}
+
+		for range 3 {
+			var got item
+			if err := store.GetFromCacheOrFetchDB(ctx, "aside", &got, fetch, time.Minute); err != nil {
+				t.Fatalf("GetFromCacheOrFetchDB: %v", err)
+			}
+			if got != (item{Name: "db", Count: 1}) {
+				t.Fatalf("GetFromCacheOrFetchDB = %+v", got)
+			}
+		}
+		if calls != 1 {
+			t.Fatalf("fetch was called %d times, want 1", calls)
+		}
+
+		fetchErr := errors.New("db down")
+		var got item
+		err := store.GetFromCacheOrFetchDB(ctx, "failing", &got, func(context.Context) (any, error) { return nil, fetchErr }, time.Minute)
+		if !errors.Is(err, fetchErr) {
+			t.Fatalf("GetFromCacheOrFetchDB error = %v, want the fetch error", err)
+		}
+	})
+
+	t.Run("negative caching", func(t *testing.T) {
+		store := newStore(t)
+
+		calls := 0
+		notFound := func(context.Context) (any, error) {
+			calls++
+			return nil, fmt.Errorf("%w: student S0001", cache.ErrNotFound)
+		}
+
+		for range 3 {
+			var got item
+			err := store.GetFromCacheOrFetchDB(ctx, "absent", &got, notFound, time.Minute)
+			if !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("GetFromCacheOrFetchDB error = %v, want cache.ErrNotFound", err)
+			}
+		}
+		if calls != 1 {
+			t.Fatalf("fetch was called %d times, want 1", calls)
+		}
+
+		var got item
+		if err := store.Get("absent", &got); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("Get of a cached not found error = %v, want cache.ErrNotFound", err)
+		}
+
+		// a write replaces the cached not found
+		if err := store.Set("absent", item{Name: "created"}, time.Minute); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if err := store.GetFromCacheOrFetchDB(ctx, "absent", &got, notFound, time.Minute); err != nil || got.Name != "created" {
+			t.Fatalf("GetFromCacheOrFetchDB after Set = %+v, %v", got, err)
+		}
+
+		// other failures are not cached
+		failures := 0
+		failing := func(context.Context) (any, error) {
+			failures++
+			return nil, errors.New("db down")
+		}
+		for range 2 {
+			if err := store.GetFromCacheOrFetchDB(ctx, "failing", &got, failing, time.Minute); err == nil {
+				t.Fatalf("GetFromCacheOrFetchDB of a failing fetch returned no error")
+			}
+		}
+		if failures != 2 {
+			t.Fatalf("failing fetch was called %d times, want 2", failures)
+		}
+	})
+
+	t.Run("concurrent misses share one fetch", func(t *testing.T) {
+		store := newStore(t)
+
+		var calls atomic.Int32
+		fetch := func(context.Context) (any, error) {
+			calls.Add(1)
+			time.Sleep(100 * time.Millisecond)
+			return &item{Name: "db"}, nil
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var got item
+				if err := store.GetFromCacheOrFetchDB(ctx, "hot", &got, fetch, time.Minute); err != nil || got.Name != "db" {
+					errs <- fmt.Errorf("GetFromCacheOrFetchDB = %+v, %v", got, err)
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Fatal(err)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Fatalf("fetch was called %d times, want 1", n)
+		}
+	})
+
+	t.Run("typed values", func(t *testing.T) {
+		for _, codec := range []cache.Codec{cache.JSONCodec, cache.MsgpackCodec, cache.BSONCodec} {
+			t.Run(codec.Name(), func(t *testing.T) {
+				store := newStore(t)
+				typed := cache.NewTypedCache[item](store, cache.TypedCacheOptions{Codec: codec, CompressAbove: 64})
+
+				large := item{Name: strings.Repeat("a", 256), Count: 2}
+				if err := typed.MSet(ctx, map[string]item{"small": {Name: "a", Count: 1}, "large": large}, time.Minute); err != nil {
+					t.Fatalf("MSet: %v", err)
+				}
+
+				got, err := typed.MGet(ctx, "small", "missing", "large")
+				if err != nil {
+					t.Fatalf("MGet: %v", err)
+				}
+				if len(got) != 2 || got["small"] != (item{Name: "a", Count: 1}) || got["large"] != large {
+					t.Fatalf("MGet = %+v", got)
+				}
+
+				if _, err := typed.Get(ctx, "missing"); !errors.Is(err, cache.ErrMiss) {
+					t.Fatalf("Get of a missing key error = %v, want cache.ErrMiss", err)
+				}
+
+				calls := 0
+				fetch := func(context.Context) (item, error) {
+					calls++
+					return item{Name: "db", Count: calls}, nil
+				}
+				for range 2 {
+					value, err := typed.GetOrFetch(ctx, "fetched", fetch, time.Minute)
+					if err != nil || value != (item{Name: "db", Count: 1}) {
+						t.Fatalf("GetOrFetch = %+v, %v", value, err)
+					}
+				}
+				if calls != 1 {
+					t.Fatalf("fetch was called %d times, want 1", calls)
+				}
+
+				if err := typed.Delete("small"); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+				if _, err := typed.Get(ctx, "small"); !errors.Is(err, cache.ErrMiss) {
+					t.Fatalf("Get of a deleted key error = %v, want cache.ErrMiss", err)
+				}
+			})
+		}
+	})
+
+	t.Run("invalidation pub/sub", func(t *testing.T) {
+		store := newStore(t)
+
+		subCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		received := make(chan []string, 1)
+		if err := store.SubscribeInvalidations(subCtx, func(keys []string) { received <- keys }); err != nil {
+			t.Fatalf("SubscribeInvalidations: %v", err)
+		}
+		if err := store.PublishInvalidation(ctx, "user:S0001", "user:email:a@b.c"); err != nil {
+			t.Fatalf("PublishInvalidation: %v", err)
+		}
+
+		select {
+		case keys := <-received:
+			if len(keys) != 2 || keys[0] != "user:S0001" || keys[1] != "user:email:a@b.c" {
+				t.Fatalf("received invalidation %v", keys)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no invalidation received")
+		}
+	})
+}
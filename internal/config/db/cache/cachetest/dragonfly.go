@@ -0,0 +1,39 @@
+package cachetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// DragonflyAddrEnv : environment variable with the address of the dragonfly used by the contract suite
+const DragonflyAddrEnv = "DRAGONFLY_TEST_ADDR"
+
+// Dragonfly : returns a cache with a prefix of its own whose keys are flushed when the test ends,
+// the test is skipped if DRAGONFLY_TEST_ADDR is not set
+func Dragonfly(t *testing.T) *cache.Cache {
+	t.Helper()
+
+	addr := os.Getenv(DragonflyAddrEnv)
+	if addr == "" {
+		t.Skipf("%s is not set, skipping the dragonfly backed suite", DragonflyAddrEnv)
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("failed to connect to dragonfly: %v", err)
+	}
+
+	c := cache.NewCache(client, fmt.Sprintf("contract_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		_ = c.Flush()
+		_ = client.Close()
+	})
+
+	return c
+}
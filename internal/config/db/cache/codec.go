@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ugorji/go/codec"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Codec : serialization of the values of a TypedCache
+type Codec interface {
+	Name() string
+	Marshal(value any) ([]byte, error)
+	Unmarshal(data []byte, dest any) error
+}
+
+var (
+	// JSONCodec : same encoding as Set and GetFromCacheOrFetchDB, readable with any redis client
+	JSONCodec Codec = jsonCodec{}
+	// MsgpackCodec : compact and fast, fields are named after their json tags so the
+	// same fields are skipped as in JSON
+	MsgpackCodec Codec = newMsgpackCodec()
+	// BSONCodec : keeps every bson type (ObjectID, dates, decimals) as stored in mongo, fields
+	// are named after their bson tags so fields hidden from JSON are cached too
+	BSONCodec Codec = bsonCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(value any) ([]byte, error) { return json.Marshal(value) }
+
+func (jsonCodec) Unmarshal(data []byte, dest any) error { return json.Unmarshal(data, dest) }
+
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func newMsgpackCodec() msgpackCodec {
+	handle := &codec.MsgpackHandle{}
+	handle.WriteExt = true // time.Time as the msgpack timestamp extension
+	handle.RawToString = true
+	return msgpackCodec{handle: handle}
+}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (m msgpackCodec) Marshal(value any) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, m.handle).Encode(value)
+	return data, err
+}
+
+func (m msgpackCodec) Unmarshal(data []byte, dest any) error {
+	return codec.NewDecoderBytes(data, m.handle).Decode(dest)
+}
+
+// bsonCodec : bson only encodes documents at the top level, so any value is stored as its
+// bson type followed by the encoded value
+type bsonCodec struct{}
+
+func (bsonCodec) Name() string { return "bson" }
+
+func (bsonCodec) Marshal(value any) ([]byte, error) {
+	valueType, data, err := bson.MarshalValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{byte(valueType)}, data...), nil
+}
+
+func (bsonCodec) Unmarshal(data []byte, dest any) error {
+	if len(data) == 0 {
+		return errors.New("empty bson value")
+	}
+	return bson.RawValue{Type: bsontype.Type(data[0]), Value: data[1:]}.Unmarshal(dest)
+}
+
+// framing of the values of a TypedCache, the first byte tells whether the rest is compressed
+const (
+	framePlain byte = 1
+	frameGzip  byte = 2
+)
+
+// frame : prefixes the encoded value, compressing it when it is at least compressAbove bytes
+// (0 disables compression) and compression actually makes it smaller
+func frame(data []byte, compressAbove int) ([]byte, error) {
+	if compressAbove > 0 && len(data) >= compressAbove {
+		var buf bytes.Buffer
+		buf.WriteByte(frameGzip)
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to compress value: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress value: %w", err)
+		}
+		if buf.Len() < len(data)+1 {
+			return buf.Bytes(), nil
+		}
+	}
+
+	framed := make([]byte, 0, len(data)+1)
+	framed = append(framed, framePlain)
+	return append(framed, data...), nil
+}
+
+// unframe : the encoded value of a framed one
+func unframe(framed []byte) ([]byte, error) {
+	if len(framed) == 0 {
+		return nil, errors.New("empty cached value")
+	}
+
+	switch framed[0] {
+	case framePlain:
+		return framed[1:], nil
+	case frameGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(framed[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress value: %w", err)
+		}
+		defer zr.Close()
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress value: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unknown frame %#x of cached value", framed[0])
+	}
+}
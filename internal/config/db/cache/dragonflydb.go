@@ -7,12 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Glorified-Toaster/senior-project/internal/config"
 	"github.com/Glorified-Toaster/senior-project/internal/utils"
 	"github.com/redis/go-redis/v9"
 	"github.com/redis/go-redis/v9/maintnotifications"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
@@ -24,41 +26,130 @@ type Cache struct {
 	client *redis.Client
 	ctx    context.Context
 	prefix string
+	origin string
+
+	// local : optional in-process L1 tier, nil while disabled
+	local    atomic.Pointer[localCache]
+	counters tierCounters
+
+	// flights : concurrent misses of a key share one fetch, refreshes are kept apart since
+	// they give up when another instance is already refreshing
+	flights   singleflight.Group
+	refreshes singleflight.Group
+
+	// breaker : bypasses dragonfly while it is unhealthy
+	breaker *breaker
+	// localOpts : the L1 tier to enable once dragonfly is reachable, nil when it is disabled
+	localOpts *LocalCacheOptions
 }
 
-// InitCache : init dragonflyDB
-func InitCache(prefix string) (*Cache, error) {
-	var initErr error
+// defaultBreakerOptions : used by NewCache and for the unset options of the config
+var defaultBreakerOptions = BreakerOptions{
+	FailureThreshold: 5,
+	ProbeInterval:    5 * time.Second,
+	ProbeTimeout:     time.Second,
+}
+
+// InitCache : init dragonflyDB, it cannot fail, an unreachable dragonfly is bypassed until a
+// background probe reaches it
+func InitCache(prefix string) *Cache {
 	once.Do(func() {
 		// loading dragonfly options
 		drgonOpts := getDragonFlyOptions()
+		breakerOpts := getBreakerOptions()
+
+		// the ttl follows the reloads of the config
+		if cfg, err := config.GetConfig(); err == nil {
+			setDefaultTTL(cfg)
+			config.Subscribe(applyConfig)
+		}
 
 		// new client instance
 		client := redis.NewClient(drgonOpts)
+		instance = newCache(client, prefix, breakerOpts)
+
+		// a cache without its L1 tier is slower, not broken
+		if opts, enabled := getLocalCacheOptions(); enabled {
+			instance.localOpts = &opts
+		}
 
 		// Ping to check connection
 		ctx := context.Background()
 
-		if _, err := client.Ping(ctx).Result(); err != nil {
-			initErr = fmt.Errorf("failed to connect to DragonflyDB: %v", err)
-			return
+		if err := instance.probe(ctx, breakerOpts.ProbeTimeout); err != nil {
+			instance.breaker.trip(fmt.Errorf("failed to connect to DragonflyDB: %w", err))
+			utils.LogErrorWithLevel("warn", utils.CacheUnavailable.Type, utils.CacheUnavailable.Code, utils.CacheUnavailable.Msg, err)
+		} else {
+			utils.LogInfo(utils.DragonflyIsConnected.Type, utils.DragonflyIsConnected.Msg)
+
+			if instance.localOpts != nil {
+				if err := instance.EnableLocalCache(ctx, *instance.localOpts); err != nil {
+					utils.LogErrorWithLevel("warn", utils.LocalCacheFailedToInit.Type, utils.LocalCacheFailedToInit.Code, utils.LocalCacheFailedToInit.Msg, err)
+				}
+			}
 		}
 
-		utils.LogInfo(utils.DragonflyIsConnected.Type, utils.DragonflyIsConnected.Msg)
+		go instance.monitor(ctx, breakerOpts)
+	})
+
+	return instance
+}
+
+// NewCache : wraps an existing client, unlike InitCache it does not register a singleton nor
+// probe dragonfly, the breaker only opens on failed commands and stays open
+func NewCache(client *redis.Client, prefix string) *Cache {
+	return newCache(client, prefix, defaultBreakerOptions)
+}
+
+func newCache(client *redis.Client, prefix string, breakerOpts BreakerOptions) *Cache {
+	c := &Cache{
+		client:  client,
+		ctx:     context.Background(),
+		prefix:  prefix,
+		origin:  newOrigin(),
+		breaker: newBreaker(breakerOpts.FailureThreshold),
+	}
+	client.AddHook(breakerHook{cache: c})
+	return c
+}
+
+// StartMonitor : probes dragonfly until ctx is done so the breaker of a cache made with NewCache
+// closes again once it is reachable
+func (c *Cache) StartMonitor(ctx context.Context, opts BreakerOptions) {
+	go c.monitor(ctx, opts)
+}
 
-		// return client
-		instance = &Cache{
-			client: client,
-			ctx:    context.Background(),
-			prefix: prefix,
+// EnableLocalCache : puts an in-process LRU in front of dragonfly until ctx is done, writes are
+// announced on the invalidation channel so the other instances drop their copies; only instances
+// with the tier enabled announce, so it must be enabled on all of them
+func (c *Cache) EnableLocalCache(ctx context.Context, opts LocalCacheOptions) error {
+	if opts.MaxEntries <= 0 || opts.MaxBytes <= 0 || opts.TTL <= 0 {
+		return fmt.Errorf("invalid local cache limits %+v", opts)
+	}
+
+	local := newLocalCache(opts)
+	err := c.subscribe(ctx, func(msg invalidationMessage) {
+		// our own writes are already applied
+		if msg.Origin == c.origin {
+			return
 		}
+		if msg.Flush {
+			local.flush()
+			return
+		}
+		local.invalidate(msg.Keys...)
 	})
-
-	if initErr != nil {
-		return nil, initErr
+	if err != nil {
+		return err
 	}
+	c.local.Store(local)
 
-	return instance, nil
+	// without the subscription copies could go stale for the whole TTL
+	go func() {
+		<-ctx.Done()
+		c.local.CompareAndSwap(local, nil)
+	}()
+	return nil
 }
 
 // GetInstance returns the singleton cache instance
@@ -91,56 +182,46 @@ func getDragonFlyOptions() *redis.Options {
 	}
 }
 
-// HealthCheck checks if cache is healthy
-func (c *Cache) HealthCheck() error {
-	_, err := c.client.Ping(c.ctx).Result()
-	return err
-}
-
-// GetFromCacheOrFetchDB implements cache-aside pattern using provided ctx
-func (c *Cache) GetFromCacheOrFetchDB(ctx context.Context, key string, dest any, fetchFromDB func() (any, error), expDate time.Duration) error {
-	// try cache with provided ctx
-	if err := c.GetWithContext(ctx, key, dest); err == nil {
-		// cache hit
-		return nil
-	} else {
-		// If GetWithContext returned an error other than redis.Nil, log it and continue to fetch from DB
-		if err != redis.Nil {
-			utils.LogErrorWithLevel("warn",
-				utils.DragonflyFailedToWriteCache.Type,
-				utils.DragonflyFailedToWriteCache.Code,
-				"cache read failed, falling back to DB",
-				err,
-			)
-		}
+// getBreakerOptions : the breaker options of the config, defaults for the unset ones
+func getBreakerOptions() BreakerOptions {
+	opts := defaultBreakerOptions
+	cfg, err := config.GetConfig()
+	if err != nil || cfg.DragonflyDB == nil {
+		return opts
 	}
 
-	// cache miss -> fetch from DB
-	data, err := fetchFromDB()
-	if err != nil {
-		return fmt.Errorf("failed to fetch data from DB: %w", err)
+	breaker := cfg.DragonflyDB.Breaker
+	if breaker.FailureThreshold > 0 {
+		opts.FailureThreshold = breaker.FailureThreshold
 	}
-
-	// attempt to write to cache (best-effort)
-	if err := c.Set(key, data, expDate); err != nil {
-		utils.LogErrorWithLevel("warn",
-			utils.DragonflyFailedToWriteCache.Type,
-			utils.DragonflyFailedToWriteCache.Code,
-			utils.DragonflyFailedToWriteCache.Msg,
-			err,
-		)
+	if breaker.ProbeInterval > 0 {
+		opts.ProbeInterval = breaker.ProbeInterval
 	}
-
-	// marshal/unmarshal into dest
-	mData, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("failed to marshal db result: %w", err)
+	if breaker.ProbeTimeout > 0 {
+		opts.ProbeTimeout = breaker.ProbeTimeout
 	}
-	if err := json.Unmarshal(mData, dest); err != nil {
-		return fmt.Errorf("failed to unmarshal into dest: %w", err)
+	return opts
+}
+
+// getLocalCacheOptions : the L1 limits of the config and whether the tier is enabled
+func getLocalCacheOptions() (LocalCacheOptions, bool) {
+	cfg, err := config.GetConfig()
+	if err != nil || cfg.DragonflyDB == nil {
+		return LocalCacheOptions{}, false
 	}
 
-	return nil
+	local := cfg.DragonflyDB.Local
+	return LocalCacheOptions{
+		MaxEntries: local.MaxEntries,
+		MaxBytes:   local.MaxBytes,
+		TTL:        local.TTL,
+	}, local.Enabled
+}
+
+// HealthCheck checks if cache is healthy
+func (c *Cache) HealthCheck() error {
+	_, err := c.client.Ping(c.ctx).Result()
+	return err
 }
 
 // buildKey adds prefix to the key
@@ -158,64 +239,153 @@ func (c *Cache) Set(key string, value any, expiration time.Duration) error {
 		return fmt.Errorf("failed to marshal value: %v", err)
 	}
 
-	return c.client.Set(c.ctx, c.buildKey(key), data, expiration).Err()
-}
+	local := c.local.Load()
+	if local == nil {
+		err := c.client.Set(c.ctx, c.buildKey(key), data, expiration).Err()
+		if errors.Is(err, ErrUnavailable) {
+			// an older value may still be there once dragonfly is back
+			c.breaker.deferInvalidation(key)
+		}
+		return err
+	}
 
-// Get : retrieves a value
-func (c *Cache) Get(key string, dest any) error {
-	data, err := c.client.Get(c.ctx, c.buildKey(key)).Bytes()
-	if err != nil {
-		if err == redis.Nil {
-			return fmt.Errorf("key not found: %s", key)
+	// read before writing, a newer write announced meanwhile must win over ours
+	epoch := local.currentEpoch(key)
+	if err := c.client.Set(c.ctx, c.buildKey(key), data, expiration).Err(); err != nil {
+		if errors.Is(err, ErrUnavailable) {
+			c.breaker.deferInvalidation(key)
 		}
 		return err
 	}
+	local.set(key, data, expiration, epoch)
+	c.announce(invalidationMessage{Keys: []string{key}})
+	return nil
+}
 
-	return json.Unmarshal(data, dest)
+// Get : retrieves a value
+func (c *Cache) Get(key string, dest any) error {
+	return c.GetWithContext(c.ctx, key, dest)
 }
 
 // GetWithContext : retrieves a value with context
 func (c *Cache) GetWithContext(ctx context.Context, key string, dest interface{}) error {
-	data, err := c.client.Get(ctx, c.buildKey(key)).Bytes()
+	data, err := c.getData(ctx, key)
 	if err != nil {
 		if err == redis.Nil {
-			return fmt.Errorf("key not found: %s", key)
+			return missError(key)
 		}
 		return err
 	}
+	if isNotFoundMarker(data) {
+		return notFoundError(key)
+	}
 
 	return json.Unmarshal(data, dest)
 }
 
+// getData : reads through the L1 tier when it is enabled, redis.Nil on a miss
+func (c *Cache) getData(ctx context.Context, key string) ([]byte, error) {
+	local := c.local.Load()
+	if local == nil {
+		data, err := c.client.Get(ctx, c.buildKey(key)).Bytes()
+		c.countL2(err)
+		return data, err
+	}
+
+	if data, ok := local.get(key); ok {
+		c.counters.l1Hits.Add(1)
+		return data, nil
+	}
+	c.counters.l1Misses.Add(1)
+
+	// the L1 copy must not outlive the L2 one
+	epoch := local.currentEpoch(key)
+	pipe := c.client.Pipeline()
+	get := pipe.Get(ctx, c.buildKey(key))
+	ttl := pipe.PTTL(ctx, c.buildKey(key))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	data, err := get.Bytes()
+	c.countL2(err)
+	if err != nil {
+		return nil, err
+	}
+	local.set(key, data, ttl.Val(), epoch)
+	return data, nil
+}
+
+func (c *Cache) countL2(err error) {
+	switch err {
+	case nil:
+		c.counters.l2Hits.Add(1)
+	case redis.Nil:
+		c.counters.l2Misses.Add(1)
+	}
+}
+
 // Delete removes a single key from cache
 func (c *Cache) Delete(key string) error {
-	return c.client.Del(c.ctx, c.buildKey(key)).Err()
+	return c.Invalidate(key)
 }
 
-// Invalidate removes multiple keys at once
+// Invalidate removes multiple keys at once together with their fetch metadata and fill locks.
+// The L1 tier is evicted first so this instance never serves the old values, while dragonfly is
+// unavailable the keys are removed from it once it is back
 func (c *Cache) Invalidate(keys ...string) error {
 	if len(keys) == 0 {
 		return nil
 	}
-	builtKeys := make([]string, len(keys))
-	for i, key := range keys {
-		builtKeys[i] = c.buildKey(key)
+
+	local := c.local.Load()
+	if local != nil {
+		local.invalidate(keys...)
+	}
+
+	builtKeys := make([]string, 0, 3*len(keys))
+	for _, key := range keys {
+		builtKeys = append(builtKeys, c.buildKey(key), c.buildKey(fetchMetaKey(key)), c.buildKey(fillLockKey(key)))
+	}
+	err := c.client.Del(c.ctx, builtKeys...).Err()
+
+	// the other instances evict their L1 tier whatever happened to the dragonfly copy
+	if local != nil {
+		c.announce(invalidationMessage{Keys: keys})
 	}
-	return c.client.Del(c.ctx, builtKeys...).Err()
+
+	if err != nil {
+		if errors.Is(err, ErrUnavailable) {
+			c.breaker.deferInvalidation(keys...)
+			return nil
+		}
+		return err
+	}
+	return nil
 }
 
-// Flush : clears all keys
+// announce : best-effort publication of a write to the other L1 tiers, a lost message is
+// bounded by the L1 TTL
+func (c *Cache) announce(msg invalidationMessage) {
+	// an outage is logged by the breaker, the L1 tiers are flushed once dragonfly is back
+	if err := c.publish(c.ctx, msg); err != nil && !errors.Is(err, ErrUnavailable) {
+		utils.LogErrorWithLevel("warn", utils.CacheInvalidationFailed.Type, utils.CacheInvalidationFailed.Code, utils.CacheInvalidationFailed.Msg, err)
+	}
+}
+
+// Flush : clears all keys with the cache prefix (tags included), incrementally so dragonfly is
+// never blocked, keys written while it runs may survive
 func (c *Cache) Flush() error {
+	if local := c.local.Load(); local != nil {
+		// dropped once dragonfly is flushed so no fill can bring old data back
+		defer func() {
+			local.flush()
+			c.announce(invalidationMessage{Flush: true})
+		}()
+	}
+
 	if c.prefix == "" {
 		return c.client.FlushDB(c.ctx).Err()
 	}
-	// atomic deletion using lua script
-	script := `
-        local keys = redis.call('KEYS', ARGV[1])
-        if #keys > 0 then
-            return redis.call('DEL', unpack(keys))
-        end
-        return 0
-    `
-	return c.client.Eval(c.ctx, script, []string{}, fmt.Sprintf("%s:*", c.prefix)).Err()
+	return c.scanDelete(c.ctx, escapeGlob(c.prefix)+":*")
 }
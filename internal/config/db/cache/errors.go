@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrMiss : the key is not in the cache
+	ErrMiss = errors.New("cache miss")
+	// ErrNotFound : the key holds a cached "not found", fetchFromDB returns an error wrapping it
+	// to have its result cached for negativeTTL
+	ErrNotFound = errors.New("not found")
+)
+
+// negativeTTL : how long a "not found" is cached, short since the document may be created any time
+var negativeTTL time.Duration = 30 * time.Second
+
+// notFoundMarker : stored in place of the value of a key that was not found, it is not valid JSON
+// so it cannot collide with a value
+var notFoundMarker = []byte("\x00notfound")
+
+func isNotFoundMarker(data []byte) bool {
+	return bytes.Equal(data, notFoundMarker)
+}
+
+func missError(key string) error {
+	return fmt.Errorf("key not found: %s: %w", key, ErrMiss)
+}
+
+func notFoundError(key string) error {
+	return fmt.Errorf("cached result of %s: %w", key, ErrNotFound)
+}
@@ -0,0 +1,420 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// staleRatio : share of the expiration a value is still served, while it is refreshed
+	// in the background, once it has expired
+	staleRatio float64 = 0.2
+	// xfetchBeta : eagerness of the probabilistic early refresh, above 1 refreshes earlier
+	xfetchBeta float64 = 1.0
+
+	fetchTimeout time.Duration = 10 * time.Second
+	lockTTL      time.Duration = 10 * time.Second // longer than any sane fetch, a crashed holder only blocks for this long
+	lockWait     time.Duration = 2 * time.Second  // how long a miss waits for another instance to fill the key
+	lockPoll     time.Duration = 50 * time.Millisecond
+)
+
+// errRefreshInProgress : another instance holds the fill lock of the key
+var errRefreshInProgress = errors.New("refresh already in progress")
+
+// EncodeFunc : turns a fetched value into the bytes that are cached
+type EncodeFunc func(value any) ([]byte, error)
+
+// FetchResult : the cached bytes of a key, Value is also set when they were fetched by this call
+type FetchResult struct {
+	Value any
+	Data  []byte
+}
+
+// fetchMeta : stored next to a value filled by GetFromCacheOrFetchDB, values written by Set have none
+// and simply live until they expire
+type fetchMeta struct {
+	Delta time.Duration `json:"delta"` // how long the fetch took
+	Stale time.Duration `json:"stale"` // how long the value is served after its logical expiration
+}
+
+func fetchMetaKey(key string) string {
+	return fmt.Sprintf("fetchmeta:%s", key)
+}
+
+func fillLockKey(key string) string {
+	return fmt.Sprintf("lock:%s", key)
+}
+
+// unlockScript : releases a fill lock only if it is still ours
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// GetFromCacheOrFetchDB implements cache-aside pattern using provided ctx, concurrent misses of a key
+// share one fetch per instance and one instance fills it at a time; a value close to (or just past)
+// its expiration is refreshed in the background while the cached one is returned. When fetchFromDB
+// fails with an error wrapping ErrNotFound the "not found" is cached for negativeTTL
+func (c *Cache) GetFromCacheOrFetchDB(ctx context.Context, key string, dest any, fetchFromDB func(ctx context.Context) (any, error), expDate time.Duration) error {
+	res, err := c.FetchBytes(ctx, key, fetchFromDB, json.Marshal, expDate)
+	if err != nil {
+		return err
+	}
+	return decodeFetched(key, res.Data, dest)
+}
+
+// FetchBytes : GetFromCacheOrFetchDB without the decoding, fetched values are cached as encoded by encode
+func (c *Cache) FetchBytes(ctx context.Context, key string, fetchFromDB func(ctx context.Context) (any, error), encode EncodeFunc, expDate time.Duration) (FetchResult, error) {
+	// try cache with provided ctx
+	data, err := c.cachedForFetch(ctx, key, fetchFromDB, encode, expDate)
+	if err == nil {
+		// cache hit
+		return FetchResult{Data: data}, nil
+	}
+	// dragonfly is bypassed while it is unavailable, the fetched value is not stored
+	bypass := errors.Is(err, ErrUnavailable)
+	// If the read failed with an error other than a miss, log it and continue to fetch from DB
+	if !bypass && !errors.Is(err, ErrMiss) {
+		utils.LogErrorWithLevel("warn",
+			utils.DragonflyFailedToWriteCache.Type,
+			utils.DragonflyFailedToWriteCache.Code,
+			"cache read failed, falling back to DB",
+			err,
+		)
+	}
+
+	// cache miss -> fetch from DB, once for all the concurrent callers
+	result := c.flights.DoChan(key, func() (any, error) {
+		// detached so the caller leading the flight cannot fail it for the others
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+		if bypass {
+			return fetchUncached(fetchCtx, fetchFromDB, encode)
+		}
+		return c.fetchLocked(fetchCtx, key, fetchFromDB, encode, expDate, true)
+	})
+
+	select {
+	case <-ctx.Done():
+		return FetchResult{}, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return FetchResult{}, res.Err
+		}
+		fetched := res.Val.(FetchResult)
+		if res.Shared {
+			// the callers of a flight must not share the fetched value
+			fetched.Value = nil
+		}
+		return fetched, nil
+	}
+}
+
+// decodeFetched : decodes a cached or fetched value, or returns the cached "not found"
+func decodeFetched(key string, data []byte, dest any) error {
+	if isNotFoundMarker(data) {
+		return notFoundError(key)
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("failed to unmarshal into dest: %w", err)
+	}
+	return nil
+}
+
+// cachedForFetch : the cached value of key, scheduling a background refresh when it is past its
+// logical expiration or when XFetch picks this read to refresh it early
+func (c *Cache) cachedForFetch(ctx context.Context, key string, fetchFromDB func(ctx context.Context) (any, error), encode EncodeFunc, expiration time.Duration) ([]byte, error) {
+	local := c.local.Load()
+	var epoch uint64
+	if local != nil {
+		if data, ok := local.get(key); ok {
+			c.counters.l1Hits.Add(1)
+			return data, nil
+		}
+		c.counters.l1Misses.Add(1)
+		epoch = local.currentEpoch(key)
+	}
+
+	pipe := c.client.Pipeline()
+	get := pipe.Get(ctx, c.buildKey(key))
+	ttl := pipe.PTTL(ctx, c.buildKey(key))
+	rawMeta := pipe.Get(ctx, c.buildKey(fetchMetaKey(key)))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	data, err := get.Bytes()
+	c.countL2(err)
+	if err == redis.Nil {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := ttl.Val()
+	var meta fetchMeta
+	if raw, err := rawMeta.Bytes(); err == nil && json.Unmarshal(raw, &meta) == nil {
+		remaining -= meta.Stale
+		if remaining <= 0 || refreshEarly(meta.Delta, remaining) {
+			c.refreshInBackground(ctx, key, fetchFromDB, encode, expiration)
+		}
+	}
+
+	// a stale value is served from L2 only, so the refresh shows up as soon as it lands
+	if local != nil && remaining > 0 {
+		local.set(key, data, remaining, epoch)
+	}
+	return data, nil
+}
+
+// refreshEarly : XFetch, the closer the expiration and the slower the fetch, the likelier a
+// read refreshes the value before it expires
+func refreshEarly(delta, remaining time.Duration) bool {
+	return -float64(delta)*xfetchBeta*math.Log(rand.Float64()) >= float64(remaining)
+}
+
+func (c *Cache) refreshInBackground(ctx context.Context, key string, fetchFromDB func(ctx context.Context) (any, error), encode EncodeFunc, expiration time.Duration) {
+	go func() {
+		_, _, _ = c.refreshes.Do(key, func() (any, error) {
+			refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+			defer cancel()
+
+			_, err := c.fetchLocked(refreshCtx, key, fetchFromDB, encode, expiration, false)
+			if err != nil && !errors.Is(err, errRefreshInProgress) && !errors.Is(err, ErrUnavailable) {
+				utils.LogErrorWithLevel("warn", utils.CacheRefreshFailed.Type, utils.CacheRefreshFailed.Code, utils.CacheRefreshFailed.Msg, err)
+			}
+			return nil, err
+		})
+	}()
+}
+
+// fetchLocked : fetches and stores key while holding its fill lock, when another instance holds it
+// a miss (wait) waits for that fill and a refresh gives up
+func (c *Cache) fetchLocked(ctx context.Context, key string, fetchFromDB func(ctx context.Context) (any, error), encode EncodeFunc, expiration time.Duration, wait bool) (FetchResult, error) {
+	token := newOrigin()
+	locked, err := c.client.SetNX(ctx, c.buildKey(fillLockKey(key)), token, lockTTL).Result()
+	if errors.Is(err, ErrUnavailable) {
+		// nothing to refresh into
+		if !wait {
+			return FetchResult{}, err
+		}
+		return fetchUncached(ctx, fetchFromDB, encode)
+	}
+	if err != nil {
+		// no lock is no reason to fail the read, at worst instances fetch concurrently
+		utils.LogErrorWithLevel("warn", utils.CacheLockFailed.Type, utils.CacheLockFailed.Code, utils.CacheLockFailed.Msg, err)
+		return c.fetchAndStore(ctx, key, fetchFromDB, encode, expiration)
+	}
+
+	if !locked {
+		if !wait {
+			return FetchResult{}, errRefreshInProgress
+		}
+		if data, ok := c.waitForFill(ctx, key); ok {
+			return FetchResult{Data: data}, nil
+		}
+		// the holder is slow or gone, stop waiting for it
+		return c.fetchAndStore(ctx, key, fetchFromDB, encode, expiration)
+	}
+	defer func() {
+		if err := unlockScript.Run(c.ctx, c.client, []string{c.buildKey(fillLockKey(key))}, token).Err(); err != nil && err != redis.Nil {
+			utils.LogErrorWithLevel("warn", utils.CacheLockFailed.Type, utils.CacheLockFailed.Code, "failed to release cache fill lock", err)
+		}
+	}()
+
+	// a miss may have been filled by the previous holder of the lock
+	if wait {
+		if data, err := c.client.Get(ctx, c.buildKey(key)).Bytes(); err == nil {
+			return FetchResult{Data: data}, nil
+		}
+	}
+	return c.fetchAndStore(ctx, key, fetchFromDB, encode, expiration)
+}
+
+// waitForFill : polls key until another instance fills it or lockWait passes
+func (c *Cache) waitForFill(ctx context.Context, key string) ([]byte, bool) {
+	deadline := time.NewTimer(lockWait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(lockPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-deadline.C:
+			return nil, false
+		case <-ticker.C:
+			if data, err := c.client.Get(ctx, c.buildKey(key)).Bytes(); err == nil {
+				return data, true
+			}
+		}
+	}
+}
+
+func (c *Cache) fetchAndStore(ctx context.Context, key string, fetchFromDB func(ctx context.Context) (any, error), encode EncodeFunc, expiration time.Duration) (FetchResult, error) {
+	start := time.Now()
+	value, err := fetchFromDB(ctx)
+	if errors.Is(err, ErrNotFound) {
+		if err := c.storeNotFound(ctx, key); err != nil && !errors.Is(err, ErrUnavailable) {
+			utils.LogErrorWithLevel("warn",
+				utils.DragonflyFailedToWriteCache.Type,
+				utils.DragonflyFailedToWriteCache.Code,
+				utils.DragonflyFailedToWriteCache.Msg,
+				err,
+			)
+		}
+	}
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("failed to fetch data from DB: %w", err)
+	}
+	delta := time.Since(start)
+
+	data, err := encode(value)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("failed to marshal db result: %w", err)
+	}
+
+	// attempt to write to cache (best-effort)
+	if err := c.storeFetched(ctx, key, data, expiration, delta); err != nil && !errors.Is(err, ErrUnavailable) {
+		utils.LogErrorWithLevel("warn",
+			utils.DragonflyFailedToWriteCache.Type,
+			utils.DragonflyFailedToWriteCache.Code,
+			utils.DragonflyFailedToWriteCache.Msg,
+			err,
+		)
+	}
+	return FetchResult{Value: value, Data: data}, nil
+}
+
+// fetchUncached : fetches without storing, while dragonfly is unavailable
+func fetchUncached(ctx context.Context, fetchFromDB func(ctx context.Context) (any, error), encode EncodeFunc) (FetchResult, error) {
+	value, err := fetchFromDB(ctx)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("failed to fetch data from DB: %w", err)
+	}
+	data, err := encode(value)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("failed to marshal db result: %w", err)
+	}
+	return FetchResult{Value: value, Data: data}, nil
+}
+
+// storeFetched : stores a fetched value for its expiration plus the stale window, with the meta
+// the reads need to refresh it in time
+func (c *Cache) storeFetched(ctx context.Context, key string, data []byte, expiration, delta time.Duration) error {
+	local := c.local.Load()
+	var epoch uint64
+	if local != nil {
+		epoch = local.currentEpoch(key)
+	}
+
+	pipe := c.client.Pipeline()
+	if expiration > 0 {
+		stale := time.Duration(float64(expiration) * staleRatio)
+		meta, err := json.Marshal(fetchMeta{Delta: delta, Stale: stale})
+		if err != nil {
+			return fmt.Errorf("failed to marshal fetch meta: %v", err)
+		}
+		pipe.Set(ctx, c.buildKey(key), data, expiration+stale)
+		pipe.Set(ctx, c.buildKey(fetchMetaKey(key)), meta, expiration+stale)
+	} else {
+		pipe.Set(ctx, c.buildKey(key), data, 0)
+		pipe.Del(ctx, c.buildKey(fetchMetaKey(key)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	if local != nil {
+		local.set(key, data, expiration, epoch)
+		c.announce(invalidationMessage{Keys: []string{key}})
+	}
+	return nil
+}
+
+// storeNotFound : caches the "not found" of key for negativeTTL, a Set of the key replaces it
+func (c *Cache) storeNotFound(ctx context.Context, key string) error {
+	local := c.local.Load()
+	var epoch uint64
+	if local != nil {
+		epoch = local.currentEpoch(key)
+	}
+
+	pipe := c.client.Pipeline()
+	pipe.Set(ctx, c.buildKey(key), notFoundMarker, negativeTTL)
+	pipe.Del(ctx, c.buildKey(fetchMetaKey(key)))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	if local != nil {
+		local.set(key, notFoundMarker, negativeTTL, epoch)
+		c.announce(invalidationMessage{Keys: []string{key}})
+	}
+	return nil
+}
+
+// GetFromCacheOrFetchDB implements cache-aside pattern, concurrent misses of a key share one fetch
+// and a "not found" (ErrNotFound) is cached for negativeTTL
+func (m *MemoryCache) GetFromCacheOrFetchDB(ctx context.Context, key string, dest any, fetchFromDB func(ctx context.Context) (any, error), expDate time.Duration) error {
+	res, err := m.FetchBytes(ctx, key, fetchFromDB, json.Marshal, expDate)
+	if err != nil {
+		return err
+	}
+	return decodeFetched(key, res.Data, dest)
+}
+
+// FetchBytes : GetFromCacheOrFetchDB without the decoding, fetched values are cached as encoded by encode
+func (m *MemoryCache) FetchBytes(ctx context.Context, key string, fetchFromDB func(ctx context.Context) (any, error), encode EncodeFunc, expDate time.Duration) (FetchResult, error) {
+	if data, ok := m.getRaw(key); ok {
+		m.counters.l2Hits.Add(1)
+		return FetchResult{Data: data}, nil
+	}
+	m.counters.l2Misses.Add(1)
+
+	result := m.flights.DoChan(key, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+
+		value, err := fetchFromDB(fetchCtx)
+		if errors.Is(err, ErrNotFound) {
+			m.setRaw(key, notFoundMarker, negativeTTL)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch data from DB: %w", err)
+		}
+
+		data, err := encode(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal db result: %w", err)
+		}
+		m.setRaw(key, data, expDate)
+		return FetchResult{Value: value, Data: data}, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return FetchResult{}, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return FetchResult{}, res.Err
+		}
+		fetched := res.Val.(FetchResult)
+		if res.Shared {
+			fetched.Value = nil
+		}
+		return fetched, nil
+	}
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// refreshRate : share of the reads refreshing a value early
+func refreshRate(delta, remaining time.Duration) float64 {
+	const reads = 20000
+	refreshed := 0
+	for range reads {
+		if refreshEarly(delta, remaining) {
+			refreshed++
+		}
+	}
+	return float64(refreshed) / reads
+}
+
+func TestRefreshEarly(t *testing.T) {
+	beta := xfetchBeta
+	t.Cleanup(func() { xfetchBeta = beta })
+
+	// an instant fetch is never refreshed early, an expired value always is
+	if refreshRate(0, time.Second) != 0 {
+		t.Fatal("refreshed a value that is instant to fetch")
+	}
+	if refreshRate(time.Millisecond, 0) != 1 {
+		t.Fatal("kept an expired value")
+	}
+
+	// a read refreshes with a probability of exp(-remaining / (delta * beta))
+	for _, tt := range []struct {
+		beta             float64
+		delta, remaining time.Duration
+	}{
+		{beta: 1, delta: time.Second, remaining: time.Second},
+		{beta: 1, delta: time.Second, remaining: 3 * time.Second},
+		{beta: 2, delta: time.Second, remaining: time.Second},
+	} {
+		xfetchBeta = tt.beta
+		want := math.Exp(-float64(tt.remaining) / (float64(tt.delta) * tt.beta))
+		if got := refreshRate(tt.delta, tt.remaining); math.Abs(got-want) > 0.03 {
+			t.Errorf("beta %v, delta %v, remaining %v: refresh rate = %.3f, want %.3f", tt.beta, tt.delta, tt.remaining, got, want)
+		}
+	}
+}
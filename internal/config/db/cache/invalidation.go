@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// invalidationMessage : payload of the invalidation channel, Origin identifies the publishing
+// Cache so it can skip its own announcements
+type invalidationMessage struct {
+	Origin string   `json:"origin,omitempty"`
+	Keys   []string `json:"keys,omitempty"`
+	Flush  bool     `json:"flush,omitempty"`
+}
+
+// invalidationChannel : pub/sub channel announcing evicted keys, one per prefix
+func invalidationChannel(prefix string) string {
+	if prefix == "" {
+		return "invalidations"
+	}
+	return fmt.Sprintf("%s:invalidations", prefix)
+}
+
+// newOrigin : random identifier of a Cache instance
+func newOrigin() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// PublishInvalidation : announces evicted keys (without prefix) to every subscriber,
+// instances holding local copies drop them
+func (c *Cache) PublishInvalidation(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.publish(ctx, invalidationMessage{Keys: keys})
+}
+
+// SubscribeInvalidations : calls handle with the keys of every announced invalidation until ctx is done,
+// it returns once the subscription is active
+func (c *Cache) SubscribeInvalidations(ctx context.Context, handle func(keys []string)) error {
+	return c.subscribe(ctx, func(msg invalidationMessage) {
+		if len(msg.Keys) > 0 {
+			handle(msg.Keys)
+		}
+	})
+}
+
+func (c *Cache) publish(ctx context.Context, msg invalidationMessage) error {
+	msg.Origin = c.origin
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invalidation: %v", err)
+	}
+	return c.client.Publish(ctx, invalidationChannel(c.prefix), data).Err()
+}
+
+func (c *Cache) subscribe(ctx context.Context, handle func(msg invalidationMessage)) error {
+	sub := c.client.Subscribe(ctx, invalidationChannel(c.prefix))
+
+	// wait for the subscription confirmation
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return fmt.Errorf("failed to subscribe to invalidations: %w", err)
+	}
+
+	go func() {
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var invalidation invalidationMessage
+				if err := json.Unmarshal([]byte(msg.Payload), &invalidation); err == nil {
+					handle(invalidation)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// PublishInvalidation : calls the subscribers of this MemoryCache synchronously
+func (m *MemoryCache) PublishInvalidation(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	m.subMu.Lock()
+	handlers := make([]func(keys []string), 0, len(m.subscribers))
+	for _, handle := range m.subscribers {
+		handlers = append(handlers, handle)
+	}
+	m.subMu.Unlock()
+
+	for _, handle := range handlers {
+		handle(keys)
+	}
+	return nil
+}
+
+// SubscribeInvalidations : registers handle until ctx is done
+func (m *MemoryCache) SubscribeInvalidations(ctx context.Context, handle func(keys []string)) error {
+	m.subMu.Lock()
+	id := m.nextSubscriber
+	m.nextSubscriber++
+	m.subscribers[id] = handle
+	m.subMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.subMu.Lock()
+		delete(m.subscribers, id)
+		m.subMu.Unlock()
+	}()
+	return nil
+}
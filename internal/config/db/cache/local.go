@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// localEpochShards : number of invalidation epochs, an invalidation only drops the fills in
+// flight of the keys that hash to the same epoch
+const localEpochShards = 256
+
+// LocalCacheOptions : limits of the in-process L1 tier, TTL bounds how long an entry outlives an
+// invalidation lost while the pub/sub connection was down
+type LocalCacheOptions struct {
+	MaxEntries int
+	MaxBytes   int64
+	TTL        time.Duration
+}
+
+// localCache : LRU of encoded values bounded by entry count and total size
+type localCache struct {
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	order   *list.List // front is the most recently used
+	bytes   int64
+	opts    LocalCacheOptions
+	seed    maphash.Seed
+	epochs  [localEpochShards]uint64 // bumped by the invalidations of their keys, fills read before one are dropped
+	evicted int64
+}
+
+type localEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+func newLocalCache(opts LocalCacheOptions) *localCache {
+	return &localCache{
+		items: map[string]*list.Element{},
+		order: list.New(),
+		opts:  opts,
+		seed:  maphash.MakeSeed(),
+	}
+}
+
+// shard : index of the epoch of the key
+func (l *localCache) shard(key string) int {
+	return int(maphash.String(l.seed, key) % localEpochShards)
+}
+
+// get : the data of a live entry, marking it as recently used
+func (l *localCache) get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*localEntry)
+	if time.Now().After(entry.expiresAt) {
+		l.remove(element)
+		return nil, false
+	}
+
+	l.order.MoveToFront(element)
+	return entry.data, true
+}
+
+// currentEpoch : the epoch of the key, to be read before fetching its value from the L2 tier,
+// see set
+func (l *localCache) currentEpoch(key string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.epochs[l.shard(key)]
+}
+
+// set : stores data for at most expiration (capped by the TTL), unless an invalidation of the key
+// (or of another key of the same shard) happened since epoch was read, in which case data may
+// already be stale
+func (l *localCache) set(key string, data []byte, expiration time.Duration, epoch uint64) {
+	if expiration <= 0 || expiration > l.opts.TTL {
+		expiration = l.opts.TTL
+	}
+	size := int64(len(key) + len(data))
+	if size > l.opts.MaxBytes {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if epoch != l.epochs[l.shard(key)] {
+		return
+	}
+	if element, ok := l.items[key]; ok {
+		l.remove(element)
+	}
+
+	entry := &localEntry{key: key, data: data, expiresAt: time.Now().Add(expiration)}
+	l.items[key] = l.order.PushFront(entry)
+	l.bytes += size
+
+	for len(l.items) > l.opts.MaxEntries || l.bytes > l.opts.MaxBytes {
+		l.remove(l.order.Back())
+		l.evicted++
+	}
+}
+
+// invalidate : drops the keys and fails their fills in flight
+func (l *localCache) invalidate(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		l.epochs[l.shard(key)]++
+		if element, ok := l.items[key]; ok {
+			l.remove(element)
+		}
+	}
+}
+
+func (l *localCache) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.epochs {
+		l.epochs[i]++
+	}
+	clear(l.items)
+	l.order.Init()
+	l.bytes = 0
+}
+
+// usage : number of entries, their size and the entries evicted to stay within the limits
+func (l *localCache) usage() (int, int64, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items), l.bytes, l.evicted
+}
+
+func (l *localCache) remove(element *list.Element) {
+	entry := l.order.Remove(element).(*localEntry)
+	delete(l.items, entry.key)
+	l.bytes -= int64(len(entry.key) + len(entry.data))
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLocalCacheEpochs(t *testing.T) {
+	local := newLocalCache(LocalCacheOptions{MaxEntries: 100, MaxBytes: 1 << 20, TTL: time.Minute})
+
+	// other : a key whose epoch is not the one of key
+	other := func(key string) string {
+		for i := 0; ; i++ {
+			candidate := fmt.Sprintf("other:%d", i)
+			if local.shard(candidate) != local.shard(key) {
+				return candidate
+			}
+		}
+	}
+
+	// a fill survives the invalidation of an unrelated key
+	epoch := local.currentEpoch("user:S0001")
+	local.invalidate(other("user:S0001"))
+	local.set("user:S0001", []byte("fresh"), time.Minute, epoch)
+	if data, ok := local.get("user:S0001"); !ok || string(data) != "fresh" {
+		t.Fatalf("get after an unrelated invalidation = %q, %v", data, ok)
+	}
+
+	// a fill read before an invalidation of its key may be stale
+	epoch = local.currentEpoch("user:S0002")
+	local.invalidate("user:S0002")
+	local.set("user:S0002", []byte("stale"), time.Minute, epoch)
+	if data, ok := local.get("user:S0002"); ok {
+		t.Fatalf("get after the invalidation of the key = %q, want a miss", data)
+	}
+
+	// a flush fails every fill in flight
+	epoch = local.currentEpoch("user:S0003")
+	local.flush()
+	local.set("user:S0003", []byte("stale"), time.Minute, epoch)
+	if data, ok := local.get("user:S0003"); ok {
+		t.Fatalf("get after a flush = %q, want a miss", data)
+	}
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// MemoryCache : in-process Store with the same semantics as Cache (JSON values, prefixed keys,
+// expiration), meant for tests and for running without dragonfly
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	tags    map[string]map[string]struct{} // tag set -> tagged keys
+	prefix  string
+
+	subMu          sync.Mutex
+	subscribers    map[int]func(keys []string)
+	nextSubscriber int
+
+	counters tierCounters
+	flights  singleflight.Group
+}
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time // zero means no expiration
+}
+
+func NewMemoryCache(prefix string) *MemoryCache {
+	return &MemoryCache{
+		entries:     map[string]memoryEntry{},
+		tags:        map[string]map[string]struct{}{},
+		prefix:      prefix,
+		subscribers: map[int]func(keys []string){},
+	}
+}
+
+// buildKey adds prefix to the key
+func (m *MemoryCache) buildKey(key string) string {
+	if m.prefix == "" {
+		return key
+	}
+	return fmt.Sprintf("%s:%s", m.prefix, key)
+}
+
+// HealthCheck : the in-memory cache is always healthy
+func (m *MemoryCache) HealthCheck() error {
+	return nil
+}
+
+// Set : stores a value with expiration
+func (m *MemoryCache) Set(key string, value any, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %v", err)
+	}
+
+	m.setRaw(key, data, expiration)
+	return nil
+}
+
+// setRaw : stores already encoded data
+func (m *MemoryCache) setRaw(key string, data []byte, expiration time.Duration) {
+	entry := memoryEntry{data: data}
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[m.buildKey(key)] = entry
+}
+
+// getRaw : returns the stored data if present and not expired
+func (m *MemoryCache) getRaw(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	builtKey := m.buildKey(key)
+	entry, ok := m.entries[builtKey]
+	if !ok {
+		return nil, false
+	}
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(m.entries, builtKey)
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// Get : retrieves a value
+func (m *MemoryCache) Get(key string, dest any) error {
+	return m.GetWithContext(context.Background(), key, dest)
+}
+
+// GetWithContext : retrieves a value with context
+func (m *MemoryCache) GetWithContext(ctx context.Context, key string, dest any) error {
+	data, ok := m.getRaw(key)
+	if !ok {
+		m.counters.l2Misses.Add(1)
+		return missError(key)
+	}
+	m.counters.l2Hits.Add(1)
+	if isNotFoundMarker(data) {
+		return notFoundError(key)
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// Delete removes a single key from cache
+func (m *MemoryCache) Delete(key string) error {
+	return m.Invalidate(key)
+}
+
+// Invalidate removes multiple keys at once
+func (m *MemoryCache) Invalidate(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, m.buildKey(key))
+	}
+	return nil
+}
+
+// Flush : clears all keys with the cache prefix
+func (m *MemoryCache) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.entries {
+		if m.prefix == "" || strings.HasPrefix(key, m.prefix+":") {
+			delete(m.entries, key)
+		}
+	}
+	for key := range m.tags {
+		if m.prefix == "" || strings.HasPrefix(key, m.prefix+":") {
+			delete(m.tags, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// metricsCollector : exports the Stats and Health of a store when prometheus scrapes it
+type metricsCollector struct {
+	store Store
+
+	up          *prometheus.Desc
+	trips       *prometheus.Desc
+	bypassed    *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	l1Entries   *prometheus.Desc
+	l1Bytes     *prometheus.Desc
+	l1Evictions *prometheus.Desc
+}
+
+// RegisterMetrics : registers the cache metrics of store, the gin middleware serves the default
+// registerer on /metrics
+func RegisterMetrics(store Store, registerer prometheus.Registerer) error {
+	return registerer.Register(&metricsCollector{
+		store:       store,
+		up:          prometheus.NewDesc("cache_up", "Whether dragonfly is used (1) or bypassed because it is unavailable (0).", nil, nil),
+		trips:       prometheus.NewDesc("cache_breaker_trips_total", "Times the cache was bypassed after dragonfly became unavailable.", nil, nil),
+		bypassed:    prometheus.NewDesc("cache_bypassed_commands_total", "Cache commands not sent to dragonfly while it was unavailable.", nil, nil),
+		hits:        prometheus.NewDesc("cache_hits_total", "Cache hits by tier.", []string{"tier"}, nil),
+		misses:      prometheus.NewDesc("cache_misses_total", "Cache misses by tier.", []string{"tier"}, nil),
+		l1Entries:   prometheus.NewDesc("cache_l1_entries", "Entries in the in-process tier.", nil, nil),
+		l1Bytes:     prometheus.NewDesc("cache_l1_bytes", "Bytes held by the in-process tier.", nil, nil),
+		l1Evictions: prometheus.NewDesc("cache_l1_evictions_total", "Entries evicted from the in-process tier to stay in its limits.", nil, nil),
+	})
+}
+
+func (m *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.up
+	ch <- m.trips
+	ch <- m.bypassed
+	ch <- m.hits
+	ch <- m.misses
+	ch <- m.l1Entries
+	ch <- m.l1Bytes
+	ch <- m.l1Evictions
+}
+
+func (m *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	health := m.store.Health()
+	stats := m.store.Stats()
+
+	up := 0.0
+	if health.Available {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(m.up, prometheus.GaugeValue, up)
+	ch <- prometheus.MustNewConstMetric(m.trips, prometheus.CounterValue, float64(health.Trips))
+	ch <- prometheus.MustNewConstMetric(m.bypassed, prometheus.CounterValue, float64(health.Bypassed))
+	ch <- prometheus.MustNewConstMetric(m.hits, prometheus.CounterValue, float64(stats.L1Hits), "l1")
+	ch <- prometheus.MustNewConstMetric(m.hits, prometheus.CounterValue, float64(stats.L2Hits), "l2")
+	ch <- prometheus.MustNewConstMetric(m.misses, prometheus.CounterValue, float64(stats.L1Misses), "l1")
+	ch <- prometheus.MustNewConstMetric(m.misses, prometheus.CounterValue, float64(stats.L2Misses), "l2")
+	ch <- prometheus.MustNewConstMetric(m.l1Entries, prometheus.GaugeValue, float64(stats.L1Entries))
+	ch <- prometheus.MustNewConstMetric(m.l1Bytes, prometheus.GaugeValue, float64(stats.L1Bytes))
+	ch <- prometheus.MustNewConstMetric(m.l1Evictions, prometheus.CounterValue, float64(stats.L1Evictions))
+}
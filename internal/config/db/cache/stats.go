@@ -0,0 +1,47 @@
+package cache
+
+import "sync/atomic"
+
+// Stats : counters since the cache was created, the L1 fields stay zero while the
+// in-process tier is disabled
+type Stats struct {
+	L1Enabled   bool  `json:"l1_enabled"`
+	L1Hits      int64 `json:"l1_hits"`
+	L1Misses    int64 `json:"l1_misses"`
+	L1Entries   int   `json:"l1_entries"`
+	L1Bytes     int64 `json:"l1_bytes"`
+	L1Evictions int64 `json:"l1_evictions"`
+	L2Hits      int64 `json:"l2_hits"`
+	L2Misses    int64 `json:"l2_misses"`
+}
+
+type tierCounters struct {
+	l1Hits   atomic.Int64
+	l1Misses atomic.Int64
+	l2Hits   atomic.Int64
+	l2Misses atomic.Int64
+}
+
+func (t *tierCounters) snapshot() Stats {
+	return Stats{
+		L1Hits:   t.l1Hits.Load(),
+		L1Misses: t.l1Misses.Load(),
+		L2Hits:   t.l2Hits.Load(),
+		L2Misses: t.l2Misses.Load(),
+	}
+}
+
+// Stats : hit and miss counters per tier
+func (c *Cache) Stats() Stats {
+	stats := c.counters.snapshot()
+	if local := c.local.Load(); local != nil {
+		stats.L1Enabled = true
+		stats.L1Entries, stats.L1Bytes, stats.L1Evictions = local.usage()
+	}
+	return stats
+}
+
+// Stats : the memory cache is a single tier, it is reported as L2
+func (m *MemoryCache) Stats() Stats {
+	return m.counters.snapshot()
+}
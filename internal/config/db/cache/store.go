@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store : the cache operations used by the rest of the application,
+// implemented by the dragonfly backed Cache and the in-memory MemoryCache
+type Store interface {
+	HealthCheck() error
+	GetFromCacheOrFetchDB(ctx context.Context, key string, dest any, fetchFromDB func(ctx context.Context) (any, error), expDate time.Duration) error
+	Set(key string, value any, expiration time.Duration) error
+	Get(key string, dest any) error
+	GetWithContext(ctx context.Context, key string, dest any) error
+	Delete(key string) error
+	Invalidate(keys ...string) error
+	Tag(ctx context.Context, key string, expiration time.Duration, tags ...string) error
+	InvalidateTags(ctx context.Context, tags ...string) error
+	Flush() error
+	PublishInvalidation(ctx context.Context, keys ...string) error
+	SubscribeInvalidations(ctx context.Context, handle func(keys []string)) error
+	Stats() Stats
+	Health() Health
+
+	ByteStore
+}
+
+var (
+	_ Store = (*Cache)(nil)
+	_ Store = (*MemoryCache)(nil)
+)
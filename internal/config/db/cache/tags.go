@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// flushBatch : keys asked per SCAN and deleted per UNLINK, keeps every command short so
+// dragonfly keeps serving other clients while a large keyspace is flushed
+const flushBatch = 500
+
+// tagKey : the set holding the keys tagged with tag
+func tagKey(tag string) string {
+	return fmt.Sprintf("tag:%s", tag)
+}
+
+// escapeGlob : key as a literal SCAN pattern
+func escapeGlob(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Tag : adds key to the tags so InvalidateTags can remove it with every related entry, the tags
+// are kept at least as long as expiration (0 never expires them)
+func (c *Cache) Tag(ctx context.Context, key string, expiration time.Duration, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	pipe := c.client.Pipeline()
+	for _, tag := range tags {
+		builtTag := c.buildKey(tagKey(tag))
+		pipe.SAdd(ctx, builtTag, key)
+		if expiration <= 0 {
+			pipe.Persist(ctx, builtTag)
+			continue
+		}
+		// NX covers the set just created, GT only ever extends the set of a longer lived key
+		pipe.ExpireNX(ctx, builtTag, expiration)
+		pipe.ExpireGT(ctx, builtTag, expiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// InvalidateTags : removes every key tagged with one of the tags and the tags themselves, while
+// dragonfly is unavailable the tags are invalidated once it is back
+func (c *Cache) InvalidateTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	// read and drop the sets atomically, a key tagged meanwhile lands in a new set
+	pipe := c.client.TxPipeline()
+	members := make([]*redis.StringSliceCmd, len(tags))
+	for i, tag := range tags {
+		members[i] = pipe.SMembers(ctx, c.buildKey(tagKey(tag)))
+		pipe.Unlink(ctx, c.buildKey(tagKey(tag)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, ErrUnavailable) {
+			c.breaker.deferTagInvalidation(tags...)
+			return nil
+		}
+		return err
+	}
+
+	seen := map[string]struct{}{}
+	keys := []string{}
+	for _, cmd := range members {
+		for _, key := range cmd.Val() {
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	for start := 0; start < len(keys); start += flushBatch {
+		end := min(start+flushBatch, len(keys))
+		if err := c.Invalidate(keys[start:end]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanDelete : unlinks the keys matching pattern a batch at a time, keys written while it runs
+// may survive
+func (c *Cache) scanDelete(ctx context.Context, pattern string) error {
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, flushBatch).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := c.client.Unlink(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Tag : adds key to the tags, the tags of the in-memory cache do not expire
+func (m *MemoryCache) Tag(ctx context.Context, key string, expiration time.Duration, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tag := range tags {
+		builtTag := m.buildKey(tagKey(tag))
+		if m.tags[builtTag] == nil {
+			m.tags[builtTag] = map[string]struct{}{}
+		}
+		m.tags[builtTag][key] = struct{}{}
+	}
+	return nil
+}
+
+// InvalidateTags : removes every key tagged with one of the tags and the tags themselves
+func (m *MemoryCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tag := range tags {
+		builtTag := m.buildKey(tagKey(tag))
+		for key := range m.tags[builtTag] {
+			delete(m.entries, m.buildKey(key))
+		}
+		delete(m.tags, builtTag)
+	}
+	return nil
+}
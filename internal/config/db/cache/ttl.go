@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"go.uber.org/zap"
+)
+
+// fallbackTTL : DefaultTTL until a config sets it
+const fallbackTTL = 5 * time.Minute
+
+// defaultTTL : expiration of the entries the repositories cache, follows dragonflydb.ttl
+var defaultTTL atomic.Int64
+
+func init() {
+	defaultTTL.Store(int64(fallbackTTL))
+}
+
+// DefaultTTL : the expiration to cache entries with, read it on every write since a config
+// reload changes it
+func DefaultTTL() time.Duration {
+	return time.Duration(defaultTTL.Load())
+}
+
+// setDefaultTTL : takes the ttl of the config, the unset one keeps the current value
+func setDefaultTTL(cfg *config.Config) {
+	if cfg == nil || cfg.DragonflyDB == nil || cfg.DragonflyDB.TTL <= 0 {
+		return
+	}
+	defaultTTL.Store(int64(cfg.DragonflyDB.TTL))
+}
+
+// applyConfig : applies a reloaded ttl, the entries already cached keep theirs
+func applyConfig(cfg *config.Config, changes config.Changes) {
+	if !changes.Has("dragonflydb.ttl") {
+		return
+	}
+	setDefaultTTL(cfg)
+	utils.LogInfo(utils.ConfigApplied.Type, utils.ConfigApplied.Msg, zap.String("key", "dragonflydb.ttl"), zap.Duration("ttl", DefaultTTL()))
+}
@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// ByteStore : access to the cached bytes, what TypedCache is built on. The bytes are framed by
+// the TypedCache and must be stored and returned as they are
+type ByteStore interface {
+	// LoadBytes : the cached bytes of each key, nil for the missing ones
+	LoadBytes(ctx context.Context, keys []string) ([][]byte, error)
+	// StoreBytes : stores every entry for expiration
+	StoreBytes(ctx context.Context, entries map[string][]byte, expiration time.Duration) error
+	// FetchBytes : the cached bytes of key, on a miss the value of fetchFromDB is cached as
+	// encoded by encode and returned in FetchResult.Value too
+	FetchBytes(ctx context.Context, key string, fetchFromDB func(ctx context.Context) (any, error), encode EncodeFunc, expDate time.Duration) (FetchResult, error)
+}
+
+// TypedCacheOptions : how the values of a TypedCache are stored
+type TypedCacheOptions struct {
+	Codec Codec // JSONCodec when nil
+	// CompressAbove : encoded values of at least this many bytes are gzipped, 0 never compresses
+	CompressAbove int
+}
+
+// TypedCache : cache of the values of one type, the values are framed so a key must only be
+// written through TypedCaches with the same codec, never through Set or GetFromCacheOrFetchDB;
+// an entry that cannot be decoded is treated as a miss
+type TypedCache[T any] struct {
+	store         Store
+	codec         Codec
+	compressAbove int
+}
+
+func NewTypedCache[T any](store Store, opts TypedCacheOptions) *TypedCache[T] {
+	if opts.Codec == nil {
+		opts.Codec = JSONCodec
+	}
+	return &TypedCache[T]{
+		store:         store,
+		codec:         opts.Codec,
+		compressAbove: opts.CompressAbove,
+	}
+}
+
+func (t *TypedCache[T]) encode(value any) ([]byte, error) {
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value with %s: %w", t.codec.Name(), err)
+	}
+	return frame(data, t.compressAbove)
+}
+
+func (t *TypedCache[T]) decode(key string, framed []byte) (T, error) {
+	var value T
+	if isNotFoundMarker(framed) {
+		return value, notFoundError(key)
+	}
+
+	data, err := unframe(framed)
+	if err != nil {
+		return value, fmt.Errorf("invalid cached value of %s: %w", key, err)
+	}
+	if err := t.codec.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("failed to unmarshal %s with %s: %w", key, t.codec.Name(), err)
+	}
+	return value, nil
+}
+
+// Get : the cached value of key, ErrMiss when there is none and ErrNotFound when the
+// key holds a cached "not found"
+func (t *TypedCache[T]) Get(ctx context.Context, key string) (T, error) {
+	var zero T
+	values, err := t.store.LoadBytes(ctx, []string{key})
+	if err != nil {
+		return zero, err
+	}
+	if values[0] == nil {
+		return zero, missError(key)
+	}
+
+	value, err := t.decode(key, values[0])
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return zero, fmt.Errorf("%w: %w", ErrMiss, err)
+	}
+	return value, err
+}
+
+// Set : stores a value with expiration
+func (t *TypedCache[T]) Set(ctx context.Context, key string, value T, expiration time.Duration) error {
+	return t.MSet(ctx, map[string]T{key: value}, expiration)
+}
+
+// MGet : the cached values of the keys in one round trip, the missing keys (and the cached
+// "not found" ones) are left out of the result
+func (t *TypedCache[T]) MGet(ctx context.Context, keys ...string) (map[string]T, error) {
+	values := make(map[string]T, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	cached, err := t.store.LoadBytes(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i, data := range cached {
+		if data == nil || isNotFoundMarker(data) {
+			continue
+		}
+		value, err := t.decode(keys[i], data)
+		if err != nil {
+			continue
+		}
+		values[keys[i]] = value
+	}
+	return values, nil
+}
+
+// MSet : stores the values in one round trip, all with the same expiration
+func (t *TypedCache[T]) MSet(ctx context.Context, values map[string]T, expiration time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	entries := make(map[string][]byte, len(values))
+	for key, value := range values {
+		data, err := t.encode(value)
+		if err != nil {
+			return err
+		}
+		entries[key] = data
+	}
+	return t.store.StoreBytes(ctx, entries, expiration)
+}
+
+// GetOrFetch : GetFromCacheOrFetchDB for T, the caller that fetched gets the fetched value as is
+// instead of decoding it again
+func (t *TypedCache[T]) GetOrFetch(ctx context.Context, key string, fetchFromDB func(ctx context.Context) (T, error), expDate time.Duration) (T, error) {
+	fetch := func(ctx context.Context) (any, error) {
+		return fetchFromDB(ctx)
+	}
+
+	res, err := t.store.FetchBytes(ctx, key, fetch, t.encode, expDate)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if value, ok := res.Value.(T); ok {
+		return value, nil
+	}
+
+	value, err := t.decode(key, res.Data)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		return value, err
+	}
+
+	// written with another codec or an older shape of T, replace it
+	utils.LogErrorWithLevel("warn", utils.CacheValueUndecodable.Type, utils.CacheValueUndecodable.Code, utils.CacheValueUndecodable.Msg, err)
+	if err := t.store.Invalidate(key); err != nil {
+		var zero T
+		return zero, err
+	}
+	res, err = t.store.FetchBytes(ctx, key, fetch, t.encode, expDate)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if value, ok := res.Value.(T); ok {
+		return value, nil
+	}
+	return t.decode(key, res.Data)
+}
+
+// Delete : removes the keys
+func (t *TypedCache[T]) Delete(keys ...string) error {
+	return t.store.Invalidate(keys...)
+}
+
+func (c *Cache) LoadBytes(ctx context.Context, keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	local := c.local.Load()
+
+	pending := make([]int, 0, len(keys))
+	for i, key := range keys {
+		if local != nil {
+			if data, ok := local.get(key); ok {
+				c.counters.l1Hits.Add(1)
+				values[i] = data
+				continue
+			}
+			c.counters.l1Misses.Add(1)
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return values, nil
+	}
+
+	epochs := make([]uint64, len(pending))
+	if local != nil {
+		for j, i := range pending {
+			epochs[j] = local.currentEpoch(keys[i])
+		}
+	}
+
+	pipe := c.client.Pipeline()
+	gets := make([]*redis.StringCmd, len(pending))
+	ttls := make([]*redis.DurationCmd, len(pending))
+	for j, i := range pending {
+		gets[j] = pipe.Get(ctx, c.buildKey(keys[i]))
+		// the L1 copy must not outlive the L2 one
+		if local != nil {
+			ttls[j] = pipe.PTTL(ctx, c.buildKey(keys[i]))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for j, i := range pending {
+		data, err := gets[j].Bytes()
+		c.countL2(err)
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[i] = data
+		if local != nil {
+			local.set(keys[i], data, ttls[j].Val(), epochs[j])
+		}
+	}
+	return values, nil
+}
+
+func (c *Cache) StoreBytes(ctx context.Context, entries map[string][]byte, expiration time.Duration) error {
+	local := c.local.Load()
+	epochs := make(map[string]uint64, len(entries))
+	if local != nil {
+		// read before writing, a newer write announced meanwhile must win over ours
+		for key := range entries {
+			epochs[key] = local.currentEpoch(key)
+		}
+	}
+
+	pipe := c.client.Pipeline()
+	for key, data := range entries {
+		pipe.Set(ctx, c.buildKey(key), data, expiration)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, ErrUnavailable) {
+			for key := range entries {
+				c.breaker.deferInvalidation(key)
+			}
+		}
+		return err
+	}
+
+	if local != nil {
+		keys := make([]string, 0, len(entries))
+		for key, data := range entries {
+			local.set(key, data, expiration, epochs[key])
+			keys = append(keys, key)
+		}
+		c.announce(invalidationMessage{Keys: keys})
+	}
+	return nil
+}
+
+func (m *MemoryCache) LoadBytes(ctx context.Context, keys []string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		data, ok := m.getRaw(key)
+		if !ok {
+			m.counters.l2Misses.Add(1)
+			continue
+		}
+		m.counters.l2Hits.Add(1)
+		values[i] = data
+	}
+	return values, nil
+}
+
+func (m *MemoryCache) StoreBytes(ctx context.Context, entries map[string][]byte, expiration time.Duration) error {
+	for key, data := range entries {
+		m.setRaw(key, data, expiration)
+	}
+	return nil
+}
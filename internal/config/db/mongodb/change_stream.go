@@ -0,0 +1,161 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// server error codes of a resume token that fell off the oplog
+const (
+	changeStreamFatalError  = 280
+	changeStreamHistoryLost = 286
+)
+
+var changeStreamRetryDelay = 5 * time.Second
+
+// ChangeEvent : the parts of a change stream event the handlers need, FullDocument is the document
+// after an insert, update or replace and FullDocumentBeforeChange the document before an update,
+// replace or delete (only when the collection records pre-images)
+type ChangeEvent struct {
+	OperationType            string   `bson:"operationType"`
+	DocumentKey              bson.Raw `bson:"documentKey"`
+	FullDocument             bson.Raw `bson:"fullDocument,omitempty"`
+	FullDocumentBeforeChange bson.Raw `bson:"fullDocumentBeforeChange,omitempty"`
+}
+
+// ChangeHandler : reacts to one change, an error makes the watcher deliver the event again
+type ChangeHandler func(ctx context.Context, event *ChangeEvent) error
+
+// ChangeWatcher : tails the change stream of a collection and hands every insert, update, replace
+// and delete to a handler. The resume token of the last handled event is persisted in the
+// change_stream_tokens collection so a restarted watcher carries on where it stopped, events are
+// delivered at least once. Change streams need MongoDB to run as a replica set.
+type ChangeWatcher struct {
+	name       string
+	collection *mongo.Collection
+	tokens     *mongo.Collection
+	handler    ChangeHandler
+}
+
+// NewChangeWatcher : watches the collection, name identifies the persisted resume token
+func NewChangeWatcher(database *mongo.Database, collection, name string, handler ChangeHandler) *ChangeWatcher {
+	return &ChangeWatcher{
+		name:       name,
+		collection: database.Collection(collection),
+		tokens:     database.Collection("change_stream_tokens"),
+		handler:    handler,
+	}
+}
+
+// Run : watches until ctx is cancelled, the stream is reopened from the last persisted token after failures
+func (w *ChangeWatcher) Run(ctx context.Context) {
+	for {
+		err := w.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && (cmdErr.Code == changeStreamHistoryLost || cmdErr.Code == changeStreamFatalError) {
+			// the events since the token are gone, start again from now and let the
+			// cache expiration catch up with what was missed
+			utils.LogErrorWithLevel("warn", utils.ChangeStreamFailed.Type, utils.ChangeStreamFailed.Code,
+				"resume token expired, changes were missed", err, zap.String("watcher", w.name))
+			if err := w.clearToken(ctx); err != nil {
+				utils.LogErrorWithLevel("error", utils.ChangeStreamFailed.Type, utils.ChangeStreamFailed.Code,
+					utils.ChangeStreamFailed.Msg, err, zap.String("watcher", w.name))
+			}
+		} else {
+			utils.LogErrorWithLevel("error", utils.ChangeStreamFailed.Type, utils.ChangeStreamFailed.Code,
+				utils.ChangeStreamFailed.Msg, err, zap.String("watcher", w.name))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(changeStreamRetryDelay):
+		}
+	}
+}
+
+// watch : opens the stream after the persisted token and handles events until an error
+func (w *ChangeWatcher) watch(ctx context.Context) error {
+	token, err := w.loadToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+	}
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	if token != nil {
+		opts.SetStartAfter(token)
+	}
+
+	stream, err := w.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var event ChangeEvent
+		if err := stream.Decode(&event); err != nil {
+			return fmt.Errorf("failed to decode change event: %w", err)
+		}
+		if err := w.handler(ctx, &event); err != nil {
+			return fmt.Errorf("failed to handle %s event: %w", event.OperationType, err)
+		}
+		if err := w.saveToken(ctx, stream.ResumeToken()); err != nil {
+			return err
+		}
+	}
+	return stream.Err()
+}
+
+// loadToken : the persisted resume token, nil when the watcher never ran
+func (w *ChangeWatcher) loadToken(ctx context.Context) (bson.Raw, error) {
+	var saved struct {
+		Token bson.Raw `bson:"token"`
+	}
+	err := w.tokens.FindOne(ctx, bson.M{"_id": w.name}).Decode(&saved)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load resume token: %w", err)
+	}
+	return saved.Token, nil
+}
+
+// saveToken : persists the resume token of the last handled event
+func (w *ChangeWatcher) saveToken(ctx context.Context, token bson.Raw) error {
+	_, err := w.tokens.UpdateOne(ctx,
+		bson.M{"_id": w.name},
+		bson.M{"$set": bson.M{"token": token, "updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save resume token: %w", err)
+	}
+	return nil
+}
+
+// clearToken : forgets the persisted resume token
+func (w *ChangeWatcher) clearToken(ctx context.Context) error {
+	if _, err := w.tokens.DeleteOne(ctx, bson.M{"_id": w.name}); err != nil {
+		return fmt.Errorf("failed to clear resume token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// UnitOfWork : runs a group of writes as one multi-document transaction.
+// Transactions need MongoDB to run as a replica set (a single node one is enough).
+type UnitOfWork struct {
+	client *mongo.Client
+}
+
+func NewUnitOfWork(client *mongo.Client) *UnitOfWork {
+	return &UnitOfWork{client: client}
+}
+
+// Do : runs fn inside a transaction and commits it, every operation in fn must use the
+// given session context. The driver retries the body on TransientTransactionError and the
+// commit on UnknownTransactionCommitResult for up to 120 seconds, so fn may be called more
+// than once and must not have side effects outside of the database.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(sessCtx mongo.SessionContext) error) error {
+	session, err := u.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	opts := options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.Majority())
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (any, error) {
+		return nil, fn(sessCtx)
+	}, opts)
+	return err
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// envPrefix : every key can be overridden by APP_ followed by the key in upper case with its
+// dots replaced by underscores (e.g. APP_MONGODB_PASSWORD for mongodb.password), and by the same
+// variable suffixed with _FILE naming a file holding the value (e.g. a mounted secret)
+const envPrefix = "APP"
+
+// committedJWTSecrets : sha256 of the secrets committed in the repository, anyone can sign
+// tokens with them so they are refused in release mode
+var committedJWTSecrets = map[string]bool{
+	"ebeeee9945b4d84c42c776165f979467e681432f15dd5d27b459a6bbfedfb484": true,
+}
+
+// envName : the variable overriding key
+func envName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeys : the keys of every setting of Config, named after their mapstructure tags
+func configKeys(t reflect.Type, parent string) []string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var keys []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		if parent != "" {
+			name = parent + "." + name
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}) {
+			keys = append(keys, configKeys(fieldType, name)...)
+			continue
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+// bindEnv : applies the environment overrides of every key, including the keys missing from
+// the config file
+func bindEnv(viperInst *viper.Viper) error {
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		name := envName(key)
+		if err := viperInst.BindEnv(key, name); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", name, err)
+		}
+
+		path, ok := os.LookupEnv(name + "_FILE")
+		if !ok {
+			continue
+		}
+		if _, set := os.LookupEnv(name); set {
+			return fmt.Errorf("both %s and %s_FILE are set", name, name)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		// files written by editors and secret managers often end with a newline
+		viperInst.Set(key, strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
+
+// ValidateRelease : refuses the secrets that must be replaced before running in release mode
+func (config *Config) ValidateRelease() error {
+	if config.JWTAuth == nil || config.JWTAuth.Secret == "" {
+		return fmt.Errorf("jwt_auth.secret is not set, set %s or %s_FILE", envName("jwt_auth.secret"), envName("jwt_auth.secret"))
+	}
+
+	sum := sha256.Sum256([]byte(config.JWTAuth.Secret))
+	if committedJWTSecrets[hex.EncodeToString(sum[:])] {
+		return fmt.Errorf("jwt_auth.secret is the default committed secret, set %s or %s_FILE", envName("jwt_auth.secret"), envName("jwt_auth.secret"))
+	}
+	return nil
+}
@@ -18,6 +18,8 @@ import (
 var (
 	ZapLogger *zap.Logger
 	once      sync.Once
+	// atomicLevel : the level of ZapLogger, changed in place when the config is reloaded
+	atomicLevel = zap.NewAtomicLevel()
 )
 
 // InitLogger : initializes the Zap logger once
@@ -25,10 +27,26 @@ func InitLogger(cfg config.Config) error {
 	var initErr error
 	once.Do(func() {
 		ZapLogger, initErr = LogWithZap(cfg)
+		if initErr == nil {
+			config.Subscribe(applyConfig)
+		}
 	})
 	return initErr
 }
 
+// applyConfig : applies a reloaded log level, the config validated it already
+func applyConfig(cfg *config.Config, changes config.Changes) {
+	if !changes.Has("zap_logger.level") {
+		return
+	}
+	level, err := zapcore.ParseLevel(cfg.ZapLogger.Level)
+	if err != nil {
+		GetLogger().Warn("ignoring the reloaded log level", zap.Error(err))
+		return
+	}
+	atomicLevel.SetLevel(level)
+}
+
 // GetLogger : return a zap log instance
 func GetLogger() *zap.Logger {
 	// fallback measure
@@ -99,8 +117,9 @@ func LogWithZap(cfg config.Config) (*zap.Logger, error) {
 	consoleEncoder := zapcore.NewConsoleEncoder(zapEncodeCfg)
 	fileEncoder := zapcore.NewJSONEncoder(zapEncodeCfg)
 
-	consoleCore := zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), level)
-	fileCore := zapcore.NewCore(fileEncoder, zapcore.AddSync(lumberjack), level)
+	atomicLevel.SetLevel(level)
+	consoleCore := zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), atomicLevel)
+	fileCore := zapcore.NewCore(fileEncoder, zapcore.AddSync(lumberjack), atomicLevel)
 
 	core := zapcore.NewTee(consoleCore, fileCore)
 
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadable : the keys a reload applies, a key covers the keys under it; the others (addresses,
+// connections, secrets, log files) only change on restart
+var reloadable = []string{
+	"zap_logger.level",
+	"rate_limit",
+	"dragonflydb.ttl",
+	"features",
+}
+
+// reloadDebounce : editors save a file in several writes, they are reloaded once
+const reloadDebounce = 200 * time.Millisecond
+
+// Change : a key whose value differs between two configs, the values of secrets are redacted
+type Change struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Changes : the changes of a reload, sorted by key
+type Changes []Change
+
+// Has : reports whether key or a key under it changed
+func (c Changes) Has(key string) bool {
+	for _, change := range c {
+		if underKey(change.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReloadResult : what a reload applied and what it left for the next restart
+type ReloadResult struct {
+	Applied  Changes
+	Rejected Changes
+}
+
+var (
+	// source : the config read by Init or Setup, read again by Reload
+	source   Source
+	reloadMu sync.Mutex
+
+	subMu       sync.Mutex
+	subscribers []func(cfg *Config, changes Changes)
+)
+
+// Subscribe : fn is called with the new config and its changes after every reload that
+// applied something, in the order of subscription
+func Subscribe(fn func(cfg *Config, changes Changes)) {
+	subMu.Lock()
+	defer subMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Reload : reads the config file again and applies the changes of the reloadable keys, an
+// invalid file changes nothing
+func Reload() (ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	var result ReloadResult
+	old := current.Load()
+	if old == nil {
+		return result, fmt.Errorf("configuration not initialized you must call config.Init() first")
+	}
+
+	next, err := Load(source)
+	if err != nil {
+		return result, err
+	}
+
+	for _, change := range diff(old, next) {
+		if isReloadable(change.Key) {
+			result.Applied = append(result.Applied, change)
+		} else {
+			result.Rejected = append(result.Rejected, change)
+		}
+	}
+	if len(result.Applied) == 0 {
+		return result, nil
+	}
+
+	// readers keep the config they loaded, the new one is a modified copy
+	applied := cloneConfig(old)
+	for _, key := range reloadable {
+		if result.Applied.Has(key) {
+			fieldAt(reflect.ValueOf(applied), key).Set(fieldAt(reflect.ValueOf(next), key))
+		}
+	}
+	current.Store(applied)
+
+	subMu.Lock()
+	notify := slices.Clone(subscribers)
+	subMu.Unlock()
+	for _, fn := range notify {
+		fn(applied, result.Applied)
+	}
+	return result, nil
+}
+
+// Watch : reloads the config when one of its files changes or the process gets SIGHUP until
+// ctx is done, onReload gets the outcome of every reload that found a change or failed; a layer
+// created after Watch is only read on SIGHUP
+func Watch(ctx context.Context, onReload func(result ReloadResult, err error)) error {
+	files, err := source.Files()
+	if err != nil {
+		return err
+	}
+
+	triggers := make(chan struct{}, 1)
+	trigger := func() {
+		select {
+		case triggers <- struct{}{}:
+		default:
+		}
+	}
+
+	for _, file := range files {
+		watcher := viper.New()
+		watcher.SetConfigFile(file)
+		if err := watcher.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", file, err)
+		}
+		watcher.OnConfigChange(func(fsnotify.Event) {
+			if ctx.Err() == nil {
+				trigger()
+			}
+		})
+		watcher.WatchConfig()
+	}
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hangups)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangups:
+				trigger()
+			case <-triggers:
+				time.Sleep(reloadDebounce)
+				select {
+				case <-triggers:
+				default:
+				}
+
+				result, err := Reload()
+				if err != nil || len(result.Applied) > 0 || len(result.Rejected) > 0 {
+					onReload(result, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func underKey(key, parent string) bool {
+	return key == parent || strings.HasPrefix(key, parent+".")
+}
+
+func isReloadable(key string) bool {
+	for _, parent := range reloadable {
+		if underKey(key, parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// diff : the keys whose value differs between old and next, flags of a map are keys of their own
+func diff(old, next *Config) Changes {
+	oldValues, nextValues := flatten(reflect.ValueOf(old), ""), flatten(reflect.ValueOf(next), "")
+
+	keys := map[string]struct{}{}
+	for key := range oldValues {
+		keys[key] = struct{}{}
+	}
+	for key := range nextValues {
+		keys[key] = struct{}{}
+	}
+
+	var changes Changes
+	for key := range keys {
+		oldValue, inOld := oldValues[key]
+		nextValue, inNext := nextValues[key]
+		if inOld == inNext && oldValue == nextValue {
+			continue
+		}
+		changes = append(changes, Change{Key: key, Old: redact(key, oldValue, inOld), New: redact(key, nextValue, inNext)})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// flatten : the value of every key of the config, formatted
+func flatten(value reflect.Value, parent string) map[string]string {
+	values := map[string]string{}
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return values
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := range value.NumField() {
+			name := value.Type().Field(i).Tag.Get("mapstructure")
+			if name == "" || name == "-" {
+				continue
+			}
+			if parent != "" {
+				name = parent + "." + name
+			}
+			for key, v := range flatten(value.Field(i), name) {
+				values[key] = v
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			values[fmt.Sprintf("%s.%v", parent, iter.Key())] = fmt.Sprint(iter.Value())
+		}
+	default:
+		values[parent] = fmt.Sprint(value)
+	}
+	return values
+}
+
+// redact : hides the values of the keys that may hold credentials
+func redact(key, value string, present bool) string {
+	if !present {
+		return "<unset>"
+	}
+	name := key[strings.LastIndex(key, ".")+1:]
+	if value != "" && (name == "password" || name == "secret" || name == "uri") {
+		return "<redacted>"
+	}
+	return value
+}
+
+// fieldAt : the field of the config holding key
+func fieldAt(value reflect.Value, key string) reflect.Value {
+	for _, name := range strings.Split(key, ".") {
+		if value.Kind() == reflect.Pointer {
+			value = value.Elem()
+		}
+		for i := range value.NumField() {
+			if value.Type().Field(i).Tag.Get("mapstructure") == name {
+				value = value.Field(i)
+				break
+			}
+		}
+	}
+	return value
+}
+
+// cloneConfig : copy of cfg with its own sections, maps are shared since they are never modified
+func cloneConfig(cfg *Config) *Config {
+	clone := *cfg
+	value := reflect.ValueOf(&clone).Elem()
+	for i := range value.NumField() {
+		field := value.Field(i)
+		if field.Kind() != reflect.Pointer || field.IsNil() {
+			continue
+		}
+		section := reflect.New(field.Type().Elem())
+		section.Elem().Set(field.Elem())
+		field.Set(section)
+	}
+	return &clone
+}
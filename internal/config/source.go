@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// appName : the directory of the config under /etc and the XDG config directory
+const appName = "senior-project"
+
+// localLayer : the last layer, for the overrides of one machine, never committed
+const localLayer = "local"
+
+// validProfile : profiles end up in file names
+var validProfile = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Source : where the config is read from, the first of Paths holding File is used and the layers
+// found next to it are merged over it in order: File.yaml, File.<Profile>.yaml, File.local.yaml
+type Source struct {
+	Paths   []string // directories searched in order
+	File    string   // name of the base file without extension
+	Profile string   // optional, e.g. "production"
+}
+
+// NewSource : the source of the flags and environment, an empty path searches the default paths
+// (APP_CONFIG_PATH first when set) and an empty profile falls back to APP_PROFILE
+func NewSource(path, file, profile string) Source {
+	if profile == "" {
+		profile = os.Getenv(envName("profile"))
+	}
+	if file == "" {
+		file = "config"
+	}
+
+	var paths []string
+	switch {
+	case path != "":
+		paths = []string{path}
+	case os.Getenv(envName("config_path")) != "":
+		paths = []string{os.Getenv(envName("config_path"))}
+	default:
+		paths = DefaultSearchPaths()
+	}
+	return Source{Paths: paths, File: file, Profile: profile}
+}
+
+// DefaultSearchPaths : the working directory, the repository layout (for go run), next to the
+// executable, the XDG config directory and /etc
+func DefaultSearchPaths() []string {
+	paths := []string{".", filepath.Join("internal", "config")}
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(exe), "internal", "config"))
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, appName))
+	}
+	return append(paths, filepath.Join("/etc", appName))
+}
+
+// Files : the layers of the config that exist, the base file first
+func (s Source) Files() ([]string, error) {
+	if s.Profile != "" && !validProfile.MatchString(s.Profile) {
+		return nil, fmt.Errorf("invalid profile %q, only letters, digits, '-' and '_' are allowed", s.Profile)
+	}
+	if s.Profile == localLayer {
+		return nil, fmt.Errorf("%q is reserved for the local overrides", localLayer)
+	}
+
+	var dir string
+	for _, path := range s.Paths {
+		if exists(filepath.Join(path, s.File+".yaml")) {
+			dir = path
+			break
+		}
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("%s.yaml not found in %s", s.File, strings.Join(s.Paths, ", "))
+	}
+
+	files := []string{filepath.Join(dir, s.File+".yaml")}
+	if s.Profile != "" {
+		profileFile := filepath.Join(dir, s.File+"."+s.Profile+".yaml")
+		if !exists(profileFile) {
+			return nil, fmt.Errorf("profile %q has no %s", s.Profile, profileFile)
+		}
+		files = append(files, profileFile)
+	}
+	if localFile := filepath.Join(dir, s.File+"."+localLayer+".yaml"); exists(localFile) {
+		files = append(files, localFile)
+	}
+	return files, nil
+}
+
+func exists(file string) bool {
+	info, err := os.Stat(file)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.uber.org/zap/zapcore"
+)
+
+// minJWTSecretLength : HS256 keys shorter than the hash size weaken the signature
+const minJWTSecretLength = 32
+
+// FieldError : a key of the config and what is wrong with it
+type FieldError struct {
+	Key string
+	Msg string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Msg)
+}
+
+// ValidationErrors : every problem found in a config, one per line
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, fmt.Sprintf("invalid configuration (%d error(s)):", len(e)))
+	for _, fieldErr := range e {
+		lines = append(lines, "  - "+fieldErr.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator : collects the errors of a validation pass
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(key, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Key: key, Msg: fmt.Sprintf(format, args...)})
+}
+
+// section : reports a missing section, true when it is present
+func (v *validator) section(key string, present bool) bool {
+	if !present {
+		v.add(key, "section is missing")
+	}
+	return present
+}
+
+func (v *validator) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(key, "is required")
+	}
+}
+
+func (v *validator) port(key, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		v.add(key, "%q is not a port number (1-65535)", value)
+	}
+}
+
+func (v *validator) positive(key string, value time.Duration) {
+	if value <= 0 {
+		v.add(key, "must be a positive duration (e.g. \"30s\"), got %q", value)
+	}
+}
+
+func (v *validator) notNegative(key string, value time.Duration) {
+	if value < 0 {
+		v.add(key, "must not be negative, got %q", value)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+}
+
+// Validate : checks every section of the config, the error is a ValidationErrors listing all
+// the problems at once
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if v.section("http_server", c.HTTPServer != nil) {
+		c.HTTPServer.validate(v)
+	}
+	if v.section("mongodb", c.MongoDB != nil) {
+		c.MongoDB.validate(v)
+	}
+	if v.section("dragonflydb", c.DragonflyDB != nil) {
+		c.DragonflyDB.validate(v)
+	}
+	if v.section("zap_logger", c.ZapLogger != nil) {
+		c.ZapLogger.validate(v)
+	}
+	if v.section("lumberjack", c.Lumberjack != nil) {
+		c.Lumberjack.validate(v)
+	}
+	if v.section("jwt_auth", c.JWTAuth != nil) {
+		c.JWTAuth.validate(v)
+	}
+	if v.section("trash", c.Trash != nil) {
+		c.Trash.validate(v)
+	}
+	if v.section("rate_limit", c.RateLimit != nil) {
+		c.RateLimit.validate(v)
+	}
+
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+func (c *HTTPServerConf) validate(v *validator) {
+	v.required("http_server.address", c.Addr)
+	v.port("http_server.port", c.Port)
+	// a missing pair is generated in tls_cert_dir, half of one is a mistake
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		v.add("http_server.cert_file", "cert_file and key_file must be set together")
+	}
+	if c.CertFile == "" {
+		v.required("http_server.tls_cert_dir", c.CertDir)
+	}
+}
+
+func (c *MongoDBConf) validate(v *validator) {
+	v.required("mongodb.database", c.Database)
+	if c.URI != "" {
+		if uri, err := url.Parse(c.URI); err != nil || (uri.Scheme != "mongodb" && uri.Scheme != "mongodb+srv") {
+			v.add("mongodb.uri", "must be a mongodb:// or mongodb+srv:// URI")
+		}
+	} else {
+		v.required("mongodb.host", c.Host)
+		v.port("mongodb.port", c.Port)
+	}
+	if c.Password != "" && c.Username == "" {
+		v.add("mongodb.username", "is required when mongodb.password is set")
+	}
+
+	if c.ReadPreference != "" {
+		if _, err := readpref.ModeFromString(c.ReadPreference); err != nil {
+			v.oneOf("mongodb.read_preference", c.ReadPreference, "primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest")
+		}
+	}
+	if c.ReadConcern != "" {
+		v.oneOf("mongodb.read_concern", c.ReadConcern, "local", "available", "majority", "linearizable", "snapshot")
+	}
+	if c.WriteConcern != "" && c.WriteConcern != "majority" {
+		if members, err := strconv.Atoi(c.WriteConcern); err != nil || members < 0 {
+			v.add("mongodb.write_concern", "%q is neither \"majority\" nor a number of members", c.WriteConcern)
+		}
+	}
+
+	if c.MaxPoolSize > 0 && c.MinPoolSize > c.MaxPoolSize {
+		v.add("mongodb.min_pool_size", "%d is above max_pool_size %d", c.MinPoolSize, c.MaxPoolSize)
+	}
+	v.notNegative("mongodb.write_timeout", c.WriteTimeout)
+	v.notNegative("mongodb.max_conn_idle_time", c.MaxConnIdleTime)
+	v.notNegative("mongodb.connect_timeout", c.ConnectTimeout)
+	v.notNegative("mongodb.server_selection_timeout", c.ServerSelectionTimeout)
+
+	if !c.TLS.Enabled && (c.TLS.CAFile != "" || c.TLS.CertKeyFile != "") {
+		v.add("mongodb.tls.enabled", "must be true when tls.ca_file or tls.cert_key_file is set")
+	}
+}
+
+func (c *DragonflyDBConf) validate(v *validator) {
+	v.required("dragonflydb.host", c.Host)
+	v.port("dragonflydb.port", c.Port)
+	if c.DB < 0 {
+		v.add("dragonflydb.db", "must not be negative, got %d", c.DB)
+	}
+	v.positive("dragonflydb.ttl", c.TTL)
+
+	if c.Local.Enabled {
+		if c.Local.MaxEntries <= 0 {
+			v.add("dragonflydb.local.max_entries", "must be positive when the local cache is enabled, got %d", c.Local.MaxEntries)
+		}
+		if c.Local.MaxBytes <= 0 {
+			v.add("dragonflydb.local.max_bytes", "must be positive when the local cache is enabled, got %d", c.Local.MaxBytes)
+		}
+		v.positive("dragonflydb.local.ttl", c.Local.TTL)
+	}
+
+	if c.Breaker.FailureThreshold <= 0 {
+		v.add("dragonflydb.breaker.failure_threshold", "must be positive, got %d", c.Breaker.FailureThreshold)
+	}
+	v.positive("dragonflydb.breaker.probe_interval", c.Breaker.ProbeInterval)
+	v.positive("dragonflydb.breaker.probe_timeout", c.Breaker.ProbeTimeout)
+	if c.Breaker.ProbeTimeout > c.Breaker.ProbeInterval && c.Breaker.ProbeInterval > 0 {
+		v.add("dragonflydb.breaker.probe_timeout", "%q is longer than probe_interval %q", c.Breaker.ProbeTimeout, c.Breaker.ProbeInterval)
+	}
+}
+
+func (c *ZapLoggerConf) validate(v *validator) {
+	if _, err := zapcore.ParseLevel(c.Level); err != nil {
+		v.oneOf("zap_logger.level", c.Level, "debug", "info", "warn", "error", "dpanic", "panic", "fatal")
+	}
+	v.oneOf("zap_logger.encoding", c.Encoding, "json", "console")
+	v.required("zap_logger.log_dir", c.DirPath)
+	v.required("zap_logger.log_file", c.FileName)
+}
+
+func (c *LumberjackConf) validate(v *validator) {
+	if c.MaxSize < 0 {
+		v.add("lumberjack.max_size", "must not be negative, got %d", c.MaxSize)
+	}
+	if c.MaxAge < 0 {
+		v.add("lumberjack.max_age", "must not be negative, got %d", c.MaxAge)
+	}
+	if c.MaxBackups < 0 {
+		v.add("lumberjack.max_backups", "must not be negative, got %d", c.MaxBackups)
+	}
+}
+
+func (c *JWTAuthConf) validate(v *validator) {
+	switch {
+	case c.Secret == "":
+		v.add("jwt_auth.secret", "is required, set it or %s", envName("jwt_auth.secret"))
+	case len(c.Secret) < minJWTSecretLength:
+		v.add("jwt_auth.secret", "must be at least %d bytes, got %d", minJWTSecretLength, len(c.Secret))
+	}
+}
+
+func (c *TrashConf) validate(v *validator) {
+	v.positive("trash.retention", c.Retention)
+	v.positive("trash.purge_interval", c.PurgeInterval)
+}
+
+func (c *RateLimitConf) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	if c.RequestsPerSecond <= 0 {
+		v.add("rate_limit.requests_per_second", "must be positive when the rate limit is enabled, got %v", c.RequestsPerSecond)
+	}
+	if c.Burst < 1 {
+		v.add("rate_limit.burst", "must be at least 1 when the rate limit is enabled, got %d", c.Burst)
+	}
+}
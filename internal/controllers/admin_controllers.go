@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/dto/request"
+	"github.com/Glorified-Toaster/senior-project/internal/dto/response"
+	"github.com/Glorified-Toaster/senior-project/internal/importer"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var maxImportFileSize int64 = 10 << 20 // 10MB
+
+func (ctrl *Controllers) DeactivateStudent() gin.HandlerFunc {
+	return ctrl.changeAccountStatus(models.StatusDeactivated)
+}
+
+func (ctrl *Controllers) ReactivateStudent() gin.HandlerFunc {
+	return ctrl.changeAccountStatus(models.StatusActive)
+}
+
+func (ctrl *Controllers) ArchiveStudent() gin.HandlerFunc {
+	return ctrl.changeAccountStatus(models.StatusArchived)
+}
+
+func (ctrl *Controllers) UpdateStudent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		studentID := ctx.Param("id")
+		if studentID == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "student ID is required"})
+			return
+		}
+
+		ctrl.updateProfile(ctx, studentID)
+	}
+}
+
+func (ctrl *Controllers) SuspendStudent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var suspendRequest request.SuspendAccountRequest
+
+		if err := ctx.BindJSON(&suspendRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+			return
+		}
+
+		if validationErr := ctrl.validator.Struct(suspendRequest); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+			return
+		}
+
+		if !suspendRequest.Until.After(time.Now()) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": "until must be in the future"})
+			return
+		}
+
+		ctrl.applyAccountStatus(ctx, models.AccountStatusChange{
+			Status:         models.StatusSuspended,
+			Reason:         suspendRequest.Reason,
+			ChangedBy:      ctx.GetString("userID"),
+			SuspendedUntil: &suspendRequest.Until,
+		})
+	}
+}
+
+// changeAccountStatus : handler for the status changes that only need a reason
+func (ctrl *Controllers) changeAccountStatus(status string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var statusRequest request.AccountStatusRequest
+
+		if err := ctx.BindJSON(&statusRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+			return
+		}
+
+		if validationErr := ctrl.validator.Struct(statusRequest); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+			return
+		}
+
+		ctrl.applyAccountStatus(ctx, models.AccountStatusChange{
+			Status:    status,
+			Reason:    statusRequest.Reason,
+			ChangedBy: ctx.GetString("userID"),
+		})
+	}
+}
+
+func (ctrl *Controllers) applyAccountStatus(ctx *gin.Context, change models.AccountStatusChange) {
+	c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	studentID := ctx.Param("id")
+	if studentID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "student ID is required"})
+		return
+	}
+
+	match, ok := ifMatchTags(ctx)
+	if !ok {
+		return
+	}
+
+	// snapshot for the audit diff, exact when the change is made with If-Match
+	before, _ := ctrl.StudentRepo.GetStudentByIDFromBD(c, studentID)
+	var current *int64
+	if before != nil {
+		current = &before.Version
+	}
+	change.ExpectedVersion = match.expectedVersion(current)
+
+	student, err := ctrl.StudentRepo.SetAccountStatus(c, studentID, change)
+	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			respondPreconditionFailed(ctx)
+			return
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "student not found"})
+			return
+		}
+
+		utils.LogErrorWithLevel("error", "HTTP_SERVER", "ACCOUNT_STATUS_UPDATE_ERROR", "failed to update account status", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":         "failed to update account status",
+			"error_details": err.Error(),
+		})
+		return
+	}
+
+	ctrl.recordAudit(ctx, &models.AuditEntry{
+		Action:     models.AuditStudentStatus,
+		TargetType: models.AuditTargetStudent,
+		TargetID:   studentID,
+		Changes:    auditChanges(before, student),
+		Metadata:   map[string]string{"reason": change.Reason},
+	})
+
+	setETag(ctx, student.Version)
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Account status updated successfully",
+		"data":    toStudentResponse(student),
+	})
+}
+
+func (ctrl *Controllers) ImportStudents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Minute)
+		defer cancel()
+
+		dryRun, err := queryBool(ctx, "dry_run")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportFileSize)
+
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "a csv or xlsx file is required in the 'file' field", "error_details": err.Error()})
+			return
+		}
+
+		format, err := importer.FormatFromFilename(fileHeader.Filename)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported file", "error_details": err.Error()})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read the uploaded file", "error_details": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		rows, err := importer.Parse(file, format)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse the uploaded file", "error_details": err.Error()})
+			return
+		}
+
+		report, err := importer.NewImporter(ctrl.StudentRepo, ctrl.validator).Import(c, rows, importer.Options{
+			DryRun: dryRun != nil && *dryRun,
+		})
+		// a failed import may still have created some students
+		if report != nil && !report.DryRun && report.Created > 0 {
+			ctrl.recordAudit(ctx, &models.AuditEntry{
+				Action:     models.AuditStudentImport,
+				TargetType: models.AuditTargetStudent,
+				Metadata: map[string]string{
+					"file":     fileHeader.Filename,
+					"total":    strconv.Itoa(report.Total),
+					"created":  strconv.Itoa(report.Created),
+					"failed":   strconv.Itoa(report.Failed),
+					"complete": strconv.FormatBool(report.Complete),
+				},
+			})
+		}
+		if err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "STUDENT_IMPORT_ERROR", "failed to import students", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":         "failed to import students",
+				"error_details": err.Error(),
+				"data":          report,
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Import finished",
+			"data":    report,
+		})
+	}
+}
+
+func (ctrl *Controllers) ListStudents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		filter := models.StudentListFilter{
+			Department: ctx.Query("department"),
+			Search:     ctx.Query("q"),
+			SortBy:     ctx.Query("sort"),
+			Cursor:     ctx.Query("cursor"),
+		}
+
+		switch ctx.DefaultQuery("order", "desc") {
+		case "asc":
+		case "desc":
+			filter.SortDesc = true
+		default:
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": "order must be asc or desc"})
+			return
+		}
+
+		var err error
+		if filter.Limit, err = queryInt64(ctx, "limit"); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+		if filter.IsActive, err = queryBool(ctx, "is_active"); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+		if filter.CreatedFrom, err = queryTime(ctx, "created_from"); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+		if filter.CreatedTo, err = queryTime(ctx, "created_to"); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+		if examID := ctx.Query("required_exam"); examID != "" {
+			objectID, err := primitive.ObjectIDFromHex(examID)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": "required_exam must be an exam ID"})
+				return
+			}
+			filter.RequiredExam = &objectID
+		}
+		if filter.SortBy != "" && !slices.Contains(repository.StudentSortFields, filter.SortBy) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": "sort must be one of " + strings.Join(repository.StudentSortFields, ", ")})
+			return
+		}
+
+		students, nextCursor, err := ctrl.StudentRepo.ListStudents(ctx.Request.Context(), filter)
+		if err != nil {
+			if errors.Is(err, repository.ErrInvalidCursor) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+				return
+			}
+
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "STUDENT_LIST_ERROR", "failed to list students", err)
+			if errors.Is(err, repository.ErrSearchUnavailable) {
+				ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "student search is unavailable", "error_details": repository.ErrSearchUnavailable.Error()})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list students"})
+			return
+		}
+
+		data := make([]response.StudentResponse, len(students))
+		for i := range students {
+			data[i] = toStudentResponse(&students[i])
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":     "Students retrieved successfully",
+			"data":        data,
+			"next_cursor": nextCursor,
+			"has_more":    nextCursor != "",
+		})
+	}
+}
+
+func (ctrl *Controllers) GetCacheStats() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Cache stats retrieved successfully",
+			"data":    ctrl.cache.Stats(),
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// fields left out of audit diffs, they change on every write
+var auditIgnoredFields = []string{"updated_at", "version"}
+
+var auditExportColumns = []string{
+	"seq", "timestamp", "actor_id", "actor_role", "actor_email", "action", "target_type", "target_id",
+	"changes", "metadata", "ip", "request_id", "prev_hash", "hash",
+}
+
+func (ctrl *Controllers) SearchAuditLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		filter, err := auditFilterFromQuery(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+
+		entries, err := ctrl.AuditRepo.Search(ctx.Request.Context(), filter)
+		if err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "AUDIT_LOG_READ_ERROR", "failed to search audit log", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search audit log"})
+			return
+		}
+
+		// the next page starts below the oldest entry returned
+		var nextBeforeSeq int64
+		if len(entries) > 0 {
+			nextBeforeSeq = entries[len(entries)-1].Seq
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":         "Audit log retrieved successfully",
+			"data":            entries,
+			"next_before_seq": nextBeforeSeq,
+		})
+	}
+}
+
+func (ctrl *Controllers) ExportAuditLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		filter, err := auditFilterFromQuery(ctx)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+
+		format := ctx.DefaultQuery("format", "jsonl")
+		var write func(entry *models.AuditEntry) error
+
+		switch format {
+		case "jsonl":
+			encoder := json.NewEncoder(ctx.Writer)
+			ctx.Header("Content-Type", "application/x-ndjson")
+			write = func(entry *models.AuditEntry) error { return encoder.Encode(entry) }
+		case "csv":
+			writer := csv.NewWriter(ctx.Writer)
+			defer writer.Flush()
+			ctx.Header("Content-Type", "text/csv")
+			header := false
+			write = func(entry *models.AuditEntry) error {
+				if !header {
+					header = true
+					if err := writer.Write(auditExportColumns); err != nil {
+						return err
+					}
+				}
+				record, err := auditCSVRecord(entry)
+				if err != nil {
+					return err
+				}
+				return writer.Write(record)
+			}
+		default:
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": "format must be jsonl or csv"})
+			return
+		}
+
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.%s"`, time.Now().UTC().Format("20060102T150405Z"), format))
+
+		if err := ctrl.AuditRepo.Export(ctx.Request.Context(), filter, write); err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "AUDIT_LOG_EXPORT_ERROR", "failed to export audit log", err)
+			// once streaming started the status is already sent, the client gets a truncated file
+			if !ctx.Writer.Written() {
+				ctx.Header("Content-Disposition", "")
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export audit log"})
+			}
+		}
+	}
+}
+
+func (ctrl *Controllers) VerifyAuditLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Minute)
+		defer cancel()
+
+		verification, err := ctrl.AuditRepo.Verify(c)
+		if err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "AUDIT_LOG_VERIFY_ERROR", "failed to verify audit log", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify audit log"})
+			return
+		}
+
+		if !verification.Valid {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "AUDIT_LOG_TAMPERED", "audit log chain is broken",
+				fmt.Errorf("%s", verification.Reason), zap.Int64p("broken_at", verification.BrokenAt))
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Audit log verified",
+			"data":    verification,
+		})
+	}
+}
+
+// recordAudit : stamps the entry with the actor, IP and request ID of the request and appends it to
+// the audit log, the audited write already happened so failures are logged and the request goes on
+func (ctrl *Controllers) recordAudit(ctx *gin.Context, entry *models.AuditEntry) {
+	if ctrl.AuditRepo == nil {
+		return
+	}
+
+	if entry.ActorID == "" {
+		entry.ActorID = ctx.GetString("userID")
+		entry.ActorRole = ctx.GetString("role")
+		entry.ActorEmail = ctx.GetString("email")
+	}
+	entry.IP = ctx.ClientIP()
+	entry.RequestID = ctx.GetString("requestID")
+
+	// a client hanging up must not lose the record
+	c, cancel := context.WithTimeout(context.WithoutCancel(ctx.Request.Context()), 5*time.Second)
+	defer cancel()
+
+	if err := ctrl.AuditRepo.Record(c, entry); err != nil {
+		utils.LogErrorWithLevel("error", "HTTP_SERVER", "AUDIT_LOG_WRITE_ERROR", "failed to record audit entry", err,
+			zap.String("action", entry.Action),
+			zap.String("target_id", entry.TargetID),
+			zap.String("actor_id", entry.ActorID),
+			zap.String("request_id", entry.RequestID))
+	}
+}
+
+// auditChanges : top level fields that differ between the JSON of before and after, a nil side
+// records the creation or removal of the whole document
+func auditChanges(before, after any) []models.AuditChange {
+	beforeFields, beforeErr := auditFields(before)
+	afterFields, afterErr := auditFields(after)
+	if beforeErr != nil || afterErr != nil {
+		return nil
+	}
+
+	fields := make([]string, 0, len(beforeFields)+len(afterFields))
+	for field := range beforeFields {
+		fields = append(fields, field)
+	}
+	for field := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+
+	var changes []models.AuditChange
+	for _, field := range fields {
+		if slices.Contains(auditIgnoredFields, field) {
+			continue
+		}
+		if bytes.Equal(beforeFields[field], afterFields[field]) {
+			continue
+		}
+		changes = append(changes, models.AuditChange{
+			Field:  field,
+			Before: beforeFields[field],
+			After:  afterFields[field],
+		})
+	}
+	return changes
+}
+
+// auditFields : the top level JSON fields of a value, nil has none
+func auditFields(value any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// auditFilterFromQuery : parses the audit search parameters
+func auditFilterFromQuery(ctx *gin.Context) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		ActorID:    ctx.Query("actor_id"),
+		Action:     ctx.Query("action"),
+		TargetType: ctx.Query("target_type"),
+		TargetID:   ctx.Query("target_id"),
+		RequestID:  ctx.Query("request_id"),
+	}
+
+	var err error
+	if filter.Limit, err = queryInt64(ctx, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.BeforeSeq, err = queryInt64(ctx, "before_seq"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = queryTime(ctx, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = queryTime(ctx, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// auditCSVRecord : one export row, changes and metadata are kept as JSON
+func auditCSVRecord(entry *models.AuditEntry) ([]string, error) {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := json.Marshal(entry.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		strconv.FormatInt(entry.Seq, 10),
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		entry.ActorID,
+		entry.ActorRole,
+		entry.ActorEmail,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID,
+		string(changes),
+		string(metadata),
+		entry.IP,
+		entry.RequestID,
+		entry.PrevHash,
+		entry.Hash,
+	}, nil
+}
@@ -9,16 +9,24 @@ import (
 )
 
 type Controllers struct {
-	validator   *validator.Validate
-	StudentRepo repository.StudentRepository
-	cache       cache.Cache
-	jwtAuth     *helpers.JWTAuth
+	validator        *validator.Validate
+	StudentRepo      repository.StudentRepository
+	LoginHistoryRepo repository.LoginHistoryRepository
+	ExamRepo         repository.ExamRepository
+	AuditRepo        repository.AuditRepository
+	FileRepo         repository.FileRepository
+	cache            cache.Store
+	jwtAuth          *helpers.JWTAuth
 }
 
-func NewControllers(valid *validator.Validate, studentRepo repository.StudentRepository, cache cache.Cache, jwt *helpers.JWTAuth) *Controllers {
+func NewControllers(valid *validator.Validate, studentRepo repository.StudentRepository, loginHistoryRepo repository.LoginHistoryRepository, examRepo repository.ExamRepository, auditRepo repository.AuditRepository, fileRepo repository.FileRepository, cache cache.Store, jwt *helpers.JWTAuth) *Controllers {
 	return &Controllers{
 		valid,
 		studentRepo,
+		loginHistoryRepo,
+		examRepo,
+		auditRepo,
+		fileRepo,
 		cache,
 		jwt,
 	}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag : exposes the version of the returned document as its entity tag
+func setETag(ctx *gin.Context, version int64) {
+	ctx.Header("ETag", fmt.Sprintf("%q", strconv.FormatInt(version, 10)))
+}
+
+// ifMatch : the versions listed by an If-Match header, none when the header is missing or "*"
+type ifMatch struct {
+	versions []int64
+}
+
+// expectedVersion : the version an update must be made against, nil when any version will do.
+// current is the version read before the update (nil when it could not be read), with several
+// tags it is expected when it is one of them, so the update still fails if the document moves
+// on before it lands
+func (m ifMatch) expectedVersion(current *int64) *int64 {
+	if len(m.versions) == 0 {
+		return nil
+	}
+	if current != nil && slices.Contains(m.versions, *current) {
+		return current
+	}
+	return &m.versions[0]
+}
+
+// ifMatchTags : parses the If-Match header, "*" or a comma separated list of entity tags
+// (RFC 9110 section 13.1.1). Weak tags and tags that are not one of our versions can never
+// match, a header with nothing else is answered with 412 and ok is false
+func ifMatchTags(ctx *gin.Context) (match ifMatch, ok bool) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return ifMatch{}, true
+	}
+
+	tags, valid := parseEntityTags(header)
+	for _, tag := range tags {
+		if tag.weak {
+			continue
+		}
+		if version, err := strconv.ParseInt(tag.opaque, 10, 64); err == nil {
+			match.versions = append(match.versions, version)
+		}
+	}
+	if !valid || len(match.versions) == 0 {
+		respondPreconditionFailed(ctx)
+		return ifMatch{}, false
+	}
+	return match, true
+}
+
+type entityTag struct {
+	weak   bool
+	opaque string // without the quotes
+}
+
+// parseEntityTags : the tags of a #entity-tag list, valid is false when the list is malformed.
+// Empty elements are allowed, a comma inside the quotes belongs to the tag
+func parseEntityTags(list string) (tags []entityTag, valid bool) {
+	rest := list
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			return tags, true
+		}
+
+		var tag entityTag
+		if strings.HasPrefix(rest, "W/") {
+			tag.weak = true
+			rest = rest[2:]
+		}
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, false
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return nil, false
+		}
+		tag.opaque = rest[1 : end+1]
+		tags = append(tags, tag)
+
+		// the next element starts after a comma
+		rest = strings.TrimLeft(rest[end+2:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, false
+		}
+	}
+}
+
+// respondPreconditionFailed : the document changed since the client read it
+func respondPreconditionFailed(ctx *gin.Context) {
+	ctx.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":         "precondition failed",
+		"error_details": "the resource was modified, fetch it again and retry",
+	})
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatchTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		header  string
+		want    []int64
+		wantErr bool // answered with 412
+	}{
+		{name: "missing", header: ""},
+		{name: "any", header: "*"},
+		{name: "version", header: `"7"`, want: []int64{7}},
+		{name: "spaces", header: `  "0" `, want: []int64{0}},
+		{name: "list", header: `"3", "4"`, want: []int64{3, 4}},
+		{name: "list without spaces", header: `"3","4",`, want: []int64{3, 4}},
+		{name: "empty elements", header: `, "3" ,, "4"`, want: []int64{3, 4}},
+		{name: "foreign tags are skipped", header: `W/"2", "abc", "a,b", "5"`, want: []int64{5}},
+		{name: "weak", header: `W/"7"`, wantErr: true},
+		{name: "unquoted", header: "7", wantErr: true},
+		{name: "not a version", header: `"abc"`, wantErr: true},
+		{name: "any in a list", header: `"3", *`, wantErr: true},
+		{name: "unterminated", header: `"3", "4`, wantErr: true},
+		{name: "missing comma", header: `"3" "4"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPatch, "/", nil)
+			if tt.header != "" {
+				ctx.Request.Header.Set("If-Match", tt.header)
+			}
+
+			got, ok := ifMatchTags(ctx)
+			if tt.wantErr {
+				if ok || recorder.Code != http.StatusPreconditionFailed {
+					t.Fatalf("ifMatchTags = %v, %v with status %d, want 412", got, ok, recorder.Code)
+				}
+				return
+			}
+			if !ok || !slices.Equal(got.versions, tt.want) {
+				t.Fatalf("ifMatchTags = %v, %v, want %v", got.versions, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpectedVersion(t *testing.T) {
+	version := func(v int64) *int64 { return &v }
+
+	if got := (ifMatch{}).expectedVersion(version(3)); got != nil {
+		t.Fatalf("expectedVersion without tags = %d, want nil", *got)
+	}
+
+	match := ifMatch{versions: []int64{3, 4}}
+	for _, tt := range []struct {
+		current *int64
+		want    int64
+	}{
+		{current: version(4), want: 4},
+		{current: version(3), want: 3},
+		// a version that is not listed, the update conflicts
+		{current: version(5), want: 3},
+		{current: nil, want: 3},
+	} {
+		if got := match.expectedVersion(tt.current); got == nil || *got != tt.want {
+			t.Fatalf("expectedVersion(%v) = %v, want %d", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	setETag(ctx, 12)
+
+	// the tag sent back in If-Match is the version
+	ctx.Request = httptest.NewRequest(http.MethodPatch, "/", nil)
+	ctx.Request.Header.Set("If-Match", recorder.Header().Get("ETag"))
+	if got, ok := ifMatchTags(ctx); !ok || !slices.Equal(got.versions, []int64{12}) {
+		t.Fatalf("ifMatchTags of the ETag = %v, %v, want 12", got.versions, ok)
+	}
+}
@@ -0,0 +1,413 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/dto/request"
+	"github.com/Glorified-Toaster/senior-project/internal/dto/response"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (ctrl *Controllers) CreateExam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		var examRequest request.CreateExamRequest
+
+		if err := ctx.BindJSON(&examRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+			return
+		}
+
+		if validationErr := ctrl.validator.Struct(examRequest); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+			return
+		}
+
+		exam := &models.Exam{
+			Title:       examRequest.Title,
+			Description: examRequest.Description,
+			Department:  examRequest.Department,
+			Questions:   toQuestions(examRequest.Questions),
+			PassMark:    examRequest.PassMark,
+			CreatedBy:   ctx.GetString("userID"),
+		}
+
+		if err := ctrl.ExamRepo.CreateExam(c, exam); err != nil {
+			respondExamError(ctx, err, "failed to create exam")
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditExamCreate,
+			TargetType: models.AuditTargetExam,
+			TargetID:   exam.ID.Hex(),
+			Changes:    auditChanges(nil, exam),
+		})
+
+		setETag(ctx, exam.Version)
+		ctx.JSON(http.StatusCreated, gin.H{
+			"message": "Exam created successfully",
+			"data":    exam,
+		})
+	}
+}
+
+func (ctrl *Controllers) GetExam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		exam, err := ctrl.ExamRepo.GetExam(ctx.Request.Context(), examID)
+		if err != nil {
+			respondExamError(ctx, err, "failed to get exam")
+			return
+		}
+
+		setETag(ctx, exam.Version)
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Exam retrieved successfully",
+			"data":    exam,
+		})
+	}
+}
+
+func (ctrl *Controllers) UpdateExam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		match, ok := ifMatchTags(ctx)
+		if !ok {
+			return
+		}
+
+		var updateRequest request.UpdateExamRequest
+
+		if err := ctx.BindJSON(&updateRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+			return
+		}
+
+		if validationErr := ctrl.validator.Struct(updateRequest); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+			return
+		}
+
+		if updateRequest.Title == nil && updateRequest.Description == nil && updateRequest.Department == nil && updateRequest.PassMark == nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "no updatable fields provided"})
+			return
+		}
+
+		// snapshot for the audit diff, exact when the update is made with If-Match
+		before, _ := ctrl.ExamRepo.GetExam(c, examID)
+		var current *int64
+		if before != nil {
+			current = &before.Version
+		}
+
+		exam, err := ctrl.ExamRepo.UpdateExam(c, examID, models.ExamUpdate{
+			Title:           updateRequest.Title,
+			Description:     updateRequest.Description,
+			Department:      updateRequest.Department,
+			PassMark:        updateRequest.PassMark,
+			ExpectedVersion: match.expectedVersion(current),
+		})
+		if err != nil {
+			respondExamError(ctx, err, "failed to update exam")
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditExamUpdate,
+			TargetType: models.AuditTargetExam,
+			TargetID:   examID.Hex(),
+			Changes:    auditChanges(before, exam),
+		})
+
+		setETag(ctx, exam.Version)
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Exam updated successfully",
+			"data":    exam,
+		})
+	}
+}
+
+func (ctrl *Controllers) RegradeExam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), time.Minute)
+		defer cancel()
+
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		match, ok := ifMatchTags(ctx)
+		if !ok {
+			return
+		}
+
+		var regradeRequest request.RegradeExamRequest
+
+		if err := ctx.BindJSON(&regradeRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+			return
+		}
+
+		if validationErr := ctrl.validator.Struct(regradeRequest); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+			return
+		}
+
+		// snapshot for the audit diff, exact when the regrade is made with If-Match
+		before, _ := ctrl.ExamRepo.GetExam(c, examID)
+		var current *int64
+		if before != nil {
+			current = &before.Version
+		}
+
+		regraded, err := ctrl.ExamRepo.RegradeExam(c, examID, models.ExamRegrade{
+			Questions:       toQuestions(regradeRequest.Questions),
+			PassMark:        regradeRequest.PassMark,
+			ExpectedVersion: match.expectedVersion(current),
+		})
+		if err != nil {
+			respondExamError(ctx, err, "failed to regrade exam")
+			return
+		}
+
+		after, _ := ctrl.ExamRepo.GetExam(c, examID)
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditExamRegrade,
+			TargetType: models.AuditTargetExam,
+			TargetID:   examID.Hex(),
+			Changes:    auditChanges(before, after),
+			Metadata:   map[string]string{"regraded_attempts": strconv.Itoa(regraded)},
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Exam regraded successfully",
+			"data":    gin.H{"regraded_attempts": regraded},
+		})
+	}
+}
+
+func (ctrl *Controllers) EnrollStudents() gin.HandlerFunc {
+	return ctrl.changeEnrollment(true)
+}
+
+func (ctrl *Controllers) UnenrollStudents() gin.HandlerFunc {
+	return ctrl.changeEnrollment(false)
+}
+
+// changeEnrollment : handler adding or removing students of an exam
+func (ctrl *Controllers) changeEnrollment(enroll bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		var enrollmentRequest request.EnrollmentRequest
+
+		if err := ctx.BindJSON(&enrollmentRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+			return
+		}
+
+		if validationErr := ctrl.validator.Struct(enrollmentRequest); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+			return
+		}
+
+		action := models.AuditExamUnenroll
+		if enroll {
+			action = models.AuditExamEnroll
+		}
+
+		var err error
+		if enroll {
+			err = ctrl.ExamRepo.EnrollStudents(c, examID, enrollmentRequest.StudentIDs)
+		} else {
+			err = ctrl.ExamRepo.UnenrollStudents(c, examID, enrollmentRequest.StudentIDs)
+		}
+		if err != nil {
+			respondExamError(ctx, err, "failed to update enrollment")
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     action,
+			TargetType: models.AuditTargetExam,
+			TargetID:   examID.Hex(),
+			Metadata:   map[string]string{"student_ids": strings.Join(enrollmentRequest.StudentIDs, ",")},
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Enrollment updated successfully",
+		})
+	}
+}
+
+func (ctrl *Controllers) StartAttempt() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		attempt, err := ctrl.ExamRepo.StartAttempt(c, examID, ctx.GetString("studentID"))
+		if err != nil {
+			respondExamError(ctx, err, "failed to start attempt")
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditAttemptStart,
+			TargetType: models.AuditTargetAttempt,
+			TargetID:   attempt.ID.Hex(),
+			Metadata:   map[string]string{"exam_id": examID.Hex()},
+		})
+
+		exam, err := ctrl.ExamRepo.GetExam(c, examID)
+		if err != nil {
+			respondExamError(ctx, err, "failed to get exam")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Attempt started successfully",
+			"data":    gin.H{"attempt": attempt, "exam": toExamResponse(exam)},
+		})
+	}
+}
+
+func (ctrl *Controllers) SubmitAttempt() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		attemptID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		var submitRequest request.SubmitAttemptRequest
+
+		if err := ctx.BindJSON(&submitRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+			return
+		}
+
+		if validationErr := ctrl.validator.Struct(submitRequest); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+			return
+		}
+
+		attempt, err := ctrl.ExamRepo.SubmitAttempt(c, attemptID, ctx.GetString("studentID"), submitRequest.Answers)
+		if err != nil {
+			respondExamError(ctx, err, "failed to submit attempt")
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditAttemptSubmit,
+			TargetType: models.AuditTargetAttempt,
+			TargetID:   attempt.ID.Hex(),
+			Changes:    auditChanges(nil, attempt),
+			Metadata:   map[string]string{"exam_id": attempt.ExamID.Hex()},
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Attempt submitted successfully",
+			"data":    attempt,
+		})
+	}
+}
+
+// respondExamError : maps the errors of the exam repository to HTTP responses
+func respondExamError(ctx *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "not found", "error_details": err.Error()})
+	case errors.Is(err, repository.ErrInvalidExam), errors.Is(err, repository.ErrInvalidAnswers):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": err.Error()})
+	case errors.Is(err, repository.ErrNotEnrolled):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "not enrolled in this exam"})
+	case errors.Is(err, repository.ErrVersionConflict):
+		respondPreconditionFailed(ctx)
+	case errors.Is(err, repository.ErrAttemptSubmitted):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "attempt is already submitted"})
+	case errors.Is(err, repository.ErrAttemptInProgress):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "an attempt of this exam is already in progress"})
+	default:
+		utils.LogErrorWithLevel("error", "HTTP_SERVER", "EXAM_ERROR", msg, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
+
+// objectIDParam : parses an ObjectID path parameter, responds with 400 when it is invalid
+func objectIDParam(ctx *gin.Context, name string) (primitive.ObjectID, bool) {
+	objectID, err := primitive.ObjectIDFromHex(ctx.Param(name))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name, "error_details": err.Error()})
+		return primitive.NilObjectID, false
+	}
+	return objectID, true
+}
+
+func toQuestions(questions []request.QuestionRequest) []models.Question {
+	result := make([]models.Question, len(questions))
+	for i, question := range questions {
+		result[i] = models.Question{
+			Text:    question.Text,
+			Options: question.Options,
+			Answer:  question.Answer,
+			Marks:   question.Marks,
+		}
+	}
+	return result
+}
+
+func toExamResponse(exam *models.Exam) response.ExamResponse {
+	questions := make([]response.QuestionResponse, len(exam.Questions))
+	for i, question := range exam.Questions {
+		questions[i] = response.QuestionResponse{
+			Text:    question.Text,
+			Options: question.Options,
+			Marks:   question.Marks,
+		}
+	}
+	return response.ExamResponse{
+		ID:          exam.ID,
+		Title:       exam.Title,
+		Description: exam.Description,
+		Department:  exam.Department,
+		Questions:   questions,
+		PassMark:    exam.PassMark,
+	}
+}
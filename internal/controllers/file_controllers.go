@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	maxUploadSize int64 = 10 << 20 // 10MB
+
+	// allowedUploadTypes : sniffed content types accepted for uploads, the type sent by the client is
+	// ignored so html or scripts cannot be smuggled in as images
+	allowedUploadTypes = []string{
+		"image/png",
+		"image/jpeg",
+		"image/gif",
+		"image/webp",
+		"application/pdf",
+		"application/zip",
+		"text/plain; charset=utf-8",
+	}
+)
+
+func (ctrl *Controllers) UploadExamFile() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 2*time.Minute)
+		defer cancel()
+
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		exam, err := ctrl.ExamRepo.GetExam(c, examID)
+		if err != nil {
+			respondExamError(ctx, err, "failed to get exam")
+			return
+		}
+
+		metadata := models.FileMetadata{
+			ExamID:     examID,
+			UploadedBy: ctx.GetString("userID"),
+		}
+		if value := ctx.PostForm("question"); value != "" {
+			question, err := strconv.Atoi(value)
+			if err != nil || question < 0 || question >= len(exam.Questions) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": "question must be the index of a question of the exam"})
+				return
+			}
+			metadata.Question = &question
+		}
+
+		ctrl.storeUpload(ctx, c, metadata)
+	}
+}
+
+func (ctrl *Controllers) UploadAttemptFile() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 2*time.Minute)
+		defer cancel()
+
+		attemptID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		// only the student taking the attempt uploads answers, and only while it is open
+		attempt, ok := ctrl.authorizeAttemptFiles(ctx, c, attemptID, true)
+		if !ok {
+			return
+		}
+		if attempt.StudentID != ctx.GetString("studentID") {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "only the student taking the attempt can upload answers"})
+			return
+		}
+
+		ctrl.storeUpload(ctx, c, models.FileMetadata{
+			ExamID:     attempt.ExamID,
+			AttemptID:  &attempt.ID,
+			UploadedBy: ctx.GetString("userID"),
+		})
+	}
+}
+
+func (ctrl *Controllers) ListExamFiles() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		if !ctrl.authorizeExamFiles(ctx, ctx.Request.Context(), examID, false) {
+			return
+		}
+
+		ctrl.respondFileList(ctx, models.FileFilter{ExamID: examID})
+	}
+}
+
+func (ctrl *Controllers) ListAttemptFiles() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		attemptID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		attempt, ok := ctrl.authorizeAttemptFiles(ctx, ctx.Request.Context(), attemptID, false)
+		if !ok {
+			return
+		}
+
+		ctrl.respondFileList(ctx, models.FileFilter{ExamID: attempt.ExamID, AttemptID: &attempt.ID})
+	}
+}
+
+func (ctrl *Controllers) DownloadFile() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		file, ok := ctrl.authorizedFile(ctx, false)
+		if !ok {
+			return
+		}
+
+		content, err := ctrl.FileRepo.Open(ctx.Request.Context(), file)
+		if err != nil {
+			respondExamError(ctx, err, "failed to open file")
+			return
+		}
+		defer content.Close()
+
+		// diagrams are shown in the page, everything else is downloaded
+		disposition := "attachment"
+		if strings.HasPrefix(file.ContentType, "image/") {
+			disposition = "inline"
+		}
+
+		ctx.Header("Content-Type", file.ContentType)
+		ctx.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": file.Filename}))
+		ctx.Header("X-Content-Type-Options", "nosniff")
+		ctx.Header("Cache-Control", "private, max-age=3600")
+		// stored files never change, the ID is a strong validator
+		ctx.Header("ETag", `"`+file.ID.Hex()+`"`)
+
+		// handles Range, If-Range and the conditional headers
+		http.ServeContent(ctx.Writer, ctx.Request, file.Filename, file.UploadedAt, content)
+	}
+}
+
+func (ctrl *Controllers) DeleteFile() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		file, ok := ctrl.authorizedFile(ctx, true)
+		if !ok {
+			return
+		}
+
+		if err := ctrl.FileRepo.DeleteFile(ctx.Request.Context(), file.ID); err != nil {
+			respondExamError(ctx, err, "failed to delete file")
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditFileDelete,
+			TargetType: models.AuditTargetFile,
+			TargetID:   file.ID.Hex(),
+			Changes:    auditChanges(file, nil),
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "File deleted successfully",
+		})
+	}
+}
+
+// storeUpload : sniffs, checks and stores the multipart "file" field, responds with the stored file
+func (ctrl *Controllers) storeUpload(ctx *gin.Context, c context.Context, metadata models.FileMetadata) {
+	// leave room for the multipart envelope, the file itself is checked below
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxUploadSize+1<<20)
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file is too large", "error_details": "the limit is " + strconv.FormatInt(maxUploadSize>>20, 10) + "MB"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "a file is required in the 'file' field", "error_details": err.Error()})
+		return
+	}
+	if fileHeader.Size > maxUploadSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file is too large", "error_details": "the limit is " + strconv.FormatInt(maxUploadSize>>20, 10) + "MB"})
+		return
+	}
+
+	upload, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read the uploaded file", "error_details": err.Error()})
+		return
+	}
+	defer upload.Close()
+
+	// DetectContentType looks at most at the first 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read the uploaded file", "error_details": err.Error()})
+		return
+	}
+	head = head[:n]
+
+	metadata.ContentType = http.DetectContentType(head)
+	if !slices.Contains(allowedUploadTypes, metadata.ContentType) {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported file type", "error_details": metadata.ContentType})
+		return
+	}
+
+	file := &models.StoredFile{
+		Filename:     sanitizeFilename(fileHeader.Filename),
+		FileMetadata: metadata,
+	}
+	if err := ctrl.FileRepo.Upload(c, file, io.MultiReader(bytes.NewReader(head), upload)); err != nil {
+		utils.LogErrorWithLevel("error", "HTTP_SERVER", "FILE_UPLOAD_ERROR", "failed to store file", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+		return
+	}
+
+	ctrl.recordAudit(ctx, &models.AuditEntry{
+		Action:     models.AuditFileUpload,
+		TargetType: models.AuditTargetFile,
+		TargetID:   file.ID.Hex(),
+		Changes:    auditChanges(nil, file),
+	})
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "File uploaded successfully",
+		"data":    file,
+	})
+}
+
+func (ctrl *Controllers) respondFileList(ctx *gin.Context, filter models.FileFilter) {
+	files, err := ctrl.FileRepo.ListFiles(ctx.Request.Context(), filter)
+	if err != nil {
+		utils.LogErrorWithLevel("error", "HTTP_SERVER", "FILE_LIST_ERROR", "failed to list files", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list files"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Files retrieved successfully",
+		"data":    files,
+	})
+}
+
+// authorizedFile : the file of the "id" parameter if the caller may read it (or change it when write is set)
+func (ctrl *Controllers) authorizedFile(ctx *gin.Context, write bool) (*models.StoredFile, bool) {
+	fileID, ok := objectIDParam(ctx, "id")
+	if !ok {
+		return nil, false
+	}
+
+	file, err := ctrl.FileRepo.GetFile(ctx.Request.Context(), fileID)
+	if err != nil {
+		respondExamError(ctx, err, "failed to get file")
+		return nil, false
+	}
+
+	if file.AttemptID != nil {
+		if _, ok := ctrl.authorizeAttemptFiles(ctx, ctx.Request.Context(), *file.AttemptID, write); !ok {
+			return nil, false
+		}
+		return file, true
+	}
+	if !ctrl.authorizeExamFiles(ctx, ctx.Request.Context(), file.ExamID, write) {
+		return nil, false
+	}
+	return file, true
+}
+
+// authorizeExamFiles : staff may manage the files of an exam, enrolled students may read them
+func (ctrl *Controllers) authorizeExamFiles(ctx *gin.Context, c context.Context, examID primitive.ObjectID, write bool) bool {
+	exam, err := ctrl.ExamRepo.GetExam(c, examID)
+	if err != nil {
+		respondExamError(ctx, err, "failed to get exam")
+		return false
+	}
+
+	if isStaff(ctx) {
+		return true
+	}
+	if write {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return false
+	}
+	if !slices.Contains(exam.EnrolledStudents, ctx.GetString("studentID")) {
+		respondExamError(ctx, repository.ErrNotEnrolled, "failed to get exam")
+		return false
+	}
+	return true
+}
+
+// authorizeAttemptFiles : staff and the student taking the attempt may read its files,
+// changes are only allowed while the attempt is in progress
+func (ctrl *Controllers) authorizeAttemptFiles(ctx *gin.Context, c context.Context, attemptID primitive.ObjectID, write bool) (*models.ExamAttempt, bool) {
+	attempt, err := ctrl.ExamRepo.GetAttempt(c, attemptID)
+	if err != nil {
+		respondExamError(ctx, err, "failed to get attempt")
+		return nil, false
+	}
+
+	// the attempts of other students do not exist as far as a student is concerned
+	if !isStaff(ctx) && attempt.StudentID != ctx.GetString("studentID") {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if write && attempt.Status != models.AttemptInProgress {
+		respondExamError(ctx, repository.ErrAttemptSubmitted, "attempt is already submitted")
+		return nil, false
+	}
+	return attempt, true
+}
+
+// isStaff : whether the caller is an admin or a teacher
+func isStaff(ctx *gin.Context) bool {
+	role := ctx.GetString("role")
+	return role == "admin" || role == "teacher"
+}
+
+// sanitizeFilename : the base name of an uploaded file without control characters, at most 255 bytes
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/mongodb"
+	"github.com/gin-gonic/gin"
+)
+
+// Readiness : 503 while the database is unreachable, an unavailable cache only makes the
+// instance slower so it stays ready and is reported as degraded
+func (ctrl *Controllers) Readiness() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		databaseUp := mongodb.HealthCheck(c) == nil
+		cacheHealth := ctrl.cache.Health()
+
+		status := "ready"
+		if !cacheHealth.Available {
+			status = "degraded"
+		}
+		checks := gin.H{
+			"status":   status,
+			"database": gin.H{"available": databaseUp},
+			"cache":    cacheHealth,
+		}
+
+		if !databaseUp {
+			checks["status"] = "unavailable"
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "database is unavailable",
+				"data":  checks,
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Service is ready",
+			"data":    checks,
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (ctrl *Controllers) GetMyLoginHistory() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit, err := queryInt64(ctx, "limit")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+
+		events, err := ctrl.LoginHistoryRepo.ListForStudent(ctx.Request.Context(), ctx.GetString("studentID"), limit)
+		if err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "LOGIN_HISTORY_READ_ERROR", "failed to read login history", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get login history"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Login history retrieved successfully",
+			"data":    events,
+		})
+	}
+}
+
+func (ctrl *Controllers) SearchLoginHistory() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		filter := models.LoginHistoryFilter{
+			StudentID: ctx.Query("student_id"),
+			IP:        ctx.Query("ip"),
+		}
+
+		var err error
+		if filter.Limit, err = queryInt64(ctx, "limit"); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+		if filter.From, err = queryTime(ctx, "from"); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+		if filter.To, err = queryTime(ctx, "to"); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+		if filter.Success, err = queryBool(ctx, "success"); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+
+		events, err := ctrl.LoginHistoryRepo.Search(ctx.Request.Context(), filter)
+		if err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "LOGIN_HISTORY_READ_ERROR", "failed to search login history", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search login history"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Login history retrieved successfully",
+			"data":    events,
+		})
+	}
+}
+
+// queryInt64 : parses an optional integer query parameter
+func queryInt64(ctx *gin.Context, name string) (int64, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+// queryTime : parses an optional RFC3339 query parameter
+func queryTime(ctx *gin.Context, name string) (*time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// queryBool : parses an optional boolean query parameter
+func queryBool(ctx *gin.Context, name string) (*bool, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a boolean", name)
+	}
+	return &b, nil
+}
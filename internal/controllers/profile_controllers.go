@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/dto/request"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (ctrl *Controllers) GetMe() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		studentID := ctx.GetString("studentID")
+
+		student, err := ctrl.StudentRepo.GetStudentByID(ctx.Request.Context(), studentID)
+		if errors.Is(err, repository.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": "student not found",
+			})
+			return
+		}
+		if err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "PROFILE_GET_ERROR", "failed to get profile", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get profile"})
+			return
+		}
+
+		setETag(ctx, student.Version)
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Profile retrieved successfully",
+			"data":    toStudentResponse(student),
+		})
+	}
+}
+
+func (ctrl *Controllers) UpdateMe() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctrl.updateProfile(ctx, ctx.GetString("studentID"))
+	}
+}
+
+// updateProfile : applies an UpdateProfileRequest to the student, honouring If-Match
+func (ctrl *Controllers) updateProfile(ctx *gin.Context, studentID string) {
+	c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	match, ok := ifMatchTags(ctx)
+	if !ok {
+		return
+	}
+
+	var updateRequest request.UpdateProfileRequest
+
+	if err := ctx.BindJSON(&updateRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+		return
+	}
+
+	if validationErr := ctrl.validator.Struct(updateRequest); validationErr != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+		return
+	}
+
+	if updateRequest.FirstName == nil && updateRequest.LastName == nil && updateRequest.Department == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "no updatable fields provided"})
+		return
+	}
+
+	// snapshot for the audit diff, exact when the update is made with If-Match
+	before, _ := ctrl.StudentRepo.GetStudentByIDFromBD(c, studentID)
+	var current *int64
+	if before != nil {
+		current = &before.Version
+	}
+
+	student, err := ctrl.StudentRepo.UpdateStudentProfile(c, studentID, models.StudentProfileUpdate{
+		FirstName:       updateRequest.FirstName,
+		LastName:        updateRequest.LastName,
+		Department:      updateRequest.Department,
+		ExpectedVersion: match.expectedVersion(current),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrVersionConflict):
+			respondPreconditionFailed(ctx)
+		case errors.Is(err, repository.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "student not found"})
+		default:
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "PROFILE_UPDATE_ERROR", "failed to update student profile", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update profile"})
+		}
+		return
+	}
+
+	ctrl.recordAudit(ctx, &models.AuditEntry{
+		Action:     models.AuditStudentUpdate,
+		TargetType: models.AuditTargetStudent,
+		TargetID:   studentID,
+		Changes:    auditChanges(before, student),
+	})
+
+	setETag(ctx, student.Version)
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"data":    toStudentResponse(student),
+	})
+}
+
+func (ctrl *Controllers) ChangePassword() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		var passwordRequest request.ChangePasswordRequest
+
+		if err := ctx.BindJSON(&passwordRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format", "error_details": err.Error()})
+			return
+		}
+
+		if validationErr := ctrl.validator.Struct(passwordRequest); validationErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": validationErr.Error()})
+			return
+		}
+
+		err := ctrl.StudentRepo.ChangePassword(c, ctx.GetString("studentID"), passwordRequest.CurrentPassword, passwordRequest.NewPassword)
+		if err != nil {
+			switch {
+			case errors.Is(err, repository.ErrInvalidCredentials):
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+			case errors.Is(err, repository.ErrAccountInactive):
+				ctx.JSON(http.StatusForbidden, gin.H{"error": "account is not active"})
+			case errors.Is(err, repository.ErrNotFound):
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "student not found"})
+			case errors.Is(err, repository.ErrInvalidPassword):
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "validation failed", "error_details": err.Error()})
+			default:
+				utils.LogErrorWithLevel("error", "HTTP_SERVER", "PASSWORD_CHANGE_ERROR", "failed to change password", err)
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change password"})
+			}
+			return
+		}
+
+		// the password itself never goes to the audit log
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditStudentPassword,
+			TargetType: models.AuditTargetStudent,
+			TargetID:   ctx.GetString("studentID"),
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Password changed successfully",
+		})
+	}
+}
@@ -2,12 +2,14 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/Glorified-Toaster/senior-project/internal/dto/request"
 	"github.com/Glorified-Toaster/senior-project/internal/dto/response"
 	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
 	"github.com/Glorified-Toaster/senior-project/internal/templates"
 	"github.com/Glorified-Toaster/senior-project/internal/utils"
 	"github.com/gin-gonic/gin"
@@ -67,6 +69,16 @@ func (ctrl *Controllers) Signup() gin.HandlerFunc {
 
 		}
 
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			ActorID:    studentID,
+			ActorRole:  "student",
+			ActorEmail: student.Email,
+			Action:     models.AuditStudentCreate,
+			TargetType: models.AuditTargetStudent,
+			TargetID:   student.StudentID,
+			Changes:    auditChanges(nil, student),
+		})
+
 		additionalClaims := map[string]any{
 			"first_name": student.FirstName,
 			"last_name":  student.LastName,
@@ -118,30 +130,22 @@ func (ctrl *Controllers) GetStudentByID() gin.HandlerFunc {
 		}
 
 		student, err := ctrl.StudentRepo.GetStudentByID(ctx.Request.Context(), studentID)
-		if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{
 				"error": "student not found",
 			})
 			return
 		}
-
-		studentResponse := response.StudentResponse{
-			ID:         student.ID,
-			FirstName:  student.FirstName,
-			LastName:   student.LastName,
-			Role:       student.Role,
-			Department: student.Department,
-			StudentID:  student.StudentID,
-			Email:      student.Email,
-			IsActive:   student.IsActive,
-			LastLogin:  student.LastLogin,
-			CreatedAt:  student.CreatedAt,
-			UpdatedAt:  student.UpdatedAt,
+		if err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "STUDENT_GET_ERROR", "failed to get student", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get student"})
+			return
 		}
 
+		setETag(ctx, student.Version)
 		ctx.JSON(http.StatusOK, gin.H{
 			"message": "Student retrieved successfully",
-			"data":    studentResponse,
+			"data":    toStudentResponse(student),
 		})
 	}
 }
@@ -166,6 +170,8 @@ func (ctrl *Controllers) StudentLogin() gin.HandlerFunc {
 
 		student, err := ctrl.StudentRepo.VerifyPassword(c, studentID, password)
 		if err != nil {
+			ctrl.recordLogin(c, ctx, studentID, loginFailureReason(err))
+
 			render := utils.NewRender(ctx, http.StatusOK, templates.ErrorToast("Login Failed! Try again."))
 			ctx.Render(http.StatusOK, render)
 			ctx.Abort()
@@ -180,12 +186,19 @@ func (ctrl *Controllers) StudentLogin() gin.HandlerFunc {
 			"is_active":  student.IsActive,
 		}
 
-		token, err := ctrl.jwtAuth.GenerateToken(student.Email, student.StudentID, "student", additionalClaims)
+		role := student.Role
+		if role == "" {
+			role = "student"
+		}
+
+		token, err := ctrl.jwtAuth.GenerateToken(student.Email, student.StudentID, role, additionalClaims)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 			return
 		}
 
+		ctrl.recordLogin(c, ctx, student.StudentID, "")
+
 		ctx.SetCookie("auth_token", token, 86400, "/", "", true, true)
 
 		ctx.JSON(http.StatusOK, gin.H{
@@ -199,7 +212,7 @@ func (ctrl *Controllers) StudentLogin() gin.HandlerFunc {
 				"email":      student.Email,
 				"student_id": student.StudentID,
 				"department": student.Department,
-				"role":       "student",
+				"role":       role,
 			},
 		})
 
@@ -207,3 +220,62 @@ func (ctrl *Controllers) StudentLogin() gin.HandlerFunc {
 		ctx.Render(http.StatusOK, render)
 	}
 }
+
+// toStudentResponse : maps a student model to its public representation
+func toStudentResponse(student *models.Student) response.StudentResponse {
+	return response.StudentResponse{
+		ID:             student.ID,
+		FirstName:      student.FirstName,
+		LastName:       student.LastName,
+		Role:           student.Role,
+		Department:     student.Department,
+		StudentID:      student.StudentID,
+		Email:          student.Email,
+		IsActive:       student.IsActive,
+		Status:         student.Status,
+		StatusReason:   student.StatusReason,
+		SuspendedUntil: student.SuspendedUntil,
+		LastLogin:      student.LastLogin,
+		CreatedAt:      student.CreatedAt,
+		UpdatedAt:      student.UpdatedAt,
+		Version:        student.Version,
+		DeletedAt:      student.DeletedAt,
+		DeletedBy:      student.DeletedBy,
+	}
+}
+
+// recordLogin : stores the login attempt and, on success, the last login time (best-effort)
+func (ctrl *Controllers) recordLogin(c context.Context, ctx *gin.Context, studentID, failureReason string) {
+	loginAt := time.Now()
+
+	event := &models.LoginEvent{
+		StudentID: studentID,
+		Success:   failureReason == "",
+		Reason:    failureReason,
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+		Timestamp: loginAt,
+	}
+
+	if err := ctrl.LoginHistoryRepo.Record(c, event); err != nil {
+		utils.LogErrorWithLevel("warn", "HTTP_SERVER", "LOGIN_HISTORY_WRITE_ERROR", "failed to record login attempt", err)
+	}
+
+	if event.Success {
+		if err := ctrl.StudentRepo.UpdateLastLogin(c, studentID, loginAt); err != nil {
+			utils.LogErrorWithLevel("warn", "HTTP_SERVER", "LAST_LOGIN_WRITE_ERROR", "failed to update last login", err)
+		}
+	}
+}
+
+// loginFailureReason : maps a VerifyPassword error to the reason stored in the login history
+func loginFailureReason(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return models.LoginFailedUnknownStudent
+	case errors.Is(err, repository.ErrAccountInactive):
+		return models.LoginFailedAccountInactive
+	default:
+		return models.LoginFailedInvalidPassword
+	}
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/dto/response"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (ctrl *Controllers) DeleteStudent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		studentID := ctx.Param("id")
+		if studentID == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "student ID is required"})
+			return
+		}
+
+		// the whole student goes to the audit log, purging it later loses nothing
+		before, _ := ctrl.StudentRepo.GetStudentByIDFromBD(c, studentID)
+
+		if err := ctrl.StudentRepo.SoftDeleteStudent(c, studentID, ctx.GetString("userID")); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "student not found"})
+				return
+			}
+
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "STUDENT_DELETE_ERROR", "failed to delete student", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete student"})
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditStudentDelete,
+			TargetType: models.AuditTargetStudent,
+			TargetID:   studentID,
+			Changes:    auditChanges(before, nil),
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Student moved to the trash",
+		})
+	}
+}
+
+func (ctrl *Controllers) RestoreStudent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		studentID := ctx.Param("id")
+		if studentID == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "student ID is required"})
+			return
+		}
+
+		student, err := ctrl.StudentRepo.RestoreStudent(c, studentID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "student not found in the trash"})
+				return
+			}
+
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "STUDENT_RESTORE_ERROR", "failed to restore student", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore student"})
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditStudentRestore,
+			TargetType: models.AuditTargetStudent,
+			TargetID:   studentID,
+		})
+
+		setETag(ctx, student.Version)
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Student restored successfully",
+			"data":    toStudentResponse(student),
+		})
+	}
+}
+
+func (ctrl *Controllers) ListDeletedStudents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit, err := queryInt64(ctx, "limit")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+
+		students, err := ctrl.StudentRepo.ListDeletedStudents(ctx.Request.Context(), limit)
+		if err != nil {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER", "TRASH_LIST_ERROR", "failed to list deleted students", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deleted students"})
+			return
+		}
+
+		data := make([]response.StudentResponse, len(students))
+		for i := range students {
+			data[i] = toStudentResponse(&students[i])
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Deleted students retrieved successfully",
+			"data":    data,
+		})
+	}
+}
+
+func (ctrl *Controllers) DeleteExam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		// the whole exam goes to the audit log, purging it later loses nothing
+		before, _ := ctrl.ExamRepo.GetExam(c, examID)
+
+		if err := ctrl.ExamRepo.SoftDeleteExam(c, examID, ctx.GetString("userID")); err != nil {
+			respondExamError(ctx, err, "failed to delete exam")
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditExamDelete,
+			TargetType: models.AuditTargetExam,
+			TargetID:   examID.Hex(),
+			Changes:    auditChanges(before, nil),
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Exam moved to the trash",
+		})
+	}
+}
+
+func (ctrl *Controllers) RestoreExam() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		c, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		examID, ok := objectIDParam(ctx, "id")
+		if !ok {
+			return
+		}
+
+		exam, err := ctrl.ExamRepo.RestoreExam(c, examID)
+		if err != nil {
+			respondExamError(ctx, err, "failed to restore exam")
+			return
+		}
+
+		ctrl.recordAudit(ctx, &models.AuditEntry{
+			Action:     models.AuditExamRestore,
+			TargetType: models.AuditTargetExam,
+			TargetID:   examID.Hex(),
+		})
+
+		setETag(ctx, exam.Version)
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Exam restored successfully",
+			"data":    exam,
+		})
+	}
+}
+
+func (ctrl *Controllers) ListDeletedExams() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit, err := queryInt64(ctx, "limit")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid query", "error_details": err.Error()})
+			return
+		}
+
+		exams, err := ctrl.ExamRepo.ListDeletedExams(ctx.Request.Context(), limit)
+		if err != nil {
+			respondExamError(ctx, err, "failed to list deleted exams")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message": "Deleted exams retrieved successfully",
+			"data":    exams,
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package request
+
+type QuestionRequest struct {
+	Text    string   `json:"text" validate:"required,max=1024"`
+	Options []string `json:"options" validate:"required,min=2,max=10,dive,required,max=256"`
+	Answer  int      `json:"answer" validate:"min=0"`
+	Marks   float64  `json:"marks" validate:"gt=0"`
+}
+
+type CreateExamRequest struct {
+	Title       string            `json:"title" validate:"required,min=2,max=128"`
+	Description string            `json:"description,omitempty" validate:"max=1024"`
+	Department  string            `json:"department,omitempty" validate:"max=64"`
+	Questions   []QuestionRequest `json:"questions" validate:"required,min=1,dive"`
+	PassMark    float64           `json:"pass_mark" validate:"min=0"`
+}
+
+type UpdateExamRequest struct {
+	Title       *string  `json:"title,omitempty" validate:"omitempty,min=2,max=128"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=1024"`
+	Department  *string  `json:"department,omitempty" validate:"omitempty,max=64"`
+	PassMark    *float64 `json:"pass_mark,omitempty" validate:"omitempty,min=0"`
+}
+
+type RegradeExamRequest struct {
+	Questions []QuestionRequest `json:"questions" validate:"required,min=1,dive"`
+	PassMark  float64           `json:"pass_mark" validate:"min=0"`
+}
+
+type EnrollmentRequest struct {
+	StudentIDs []string `json:"student_ids" validate:"required,min=1,max=500,dive,required"`
+}
+
+type SubmitAttemptRequest struct {
+	Answers []int `json:"answers" validate:"required"`
+}
@@ -1,5 +1,7 @@
 package request
 
+import "time"
+
 type CreateStudentRequest struct {
 	FirstName  string `json:"first_name" validate:"required,min=2,max=32"`
 	LastName   string `json:"last_name" validate:"required,min=2,max=32"`
@@ -19,3 +21,23 @@ type AdminResetPasswordRequest struct {
 	StudentID   string `json:"student_id" validate:"required"`
 	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
+
+type AccountStatusRequest struct {
+	Reason string `json:"reason" validate:"required,max=256"`
+}
+
+type SuspendAccountRequest struct {
+	Reason string    `json:"reason" validate:"required,max=256"`
+	Until  time.Time `json:"until" validate:"required"`
+}
+
+type UpdateProfileRequest struct {
+	FirstName  *string `json:"first_name,omitempty" validate:"omitempty,min=2,max=32"`
+	LastName   *string `json:"last_name,omitempty" validate:"omitempty,min=2,max=32"`
+	Department *string `json:"department,omitempty" validate:"omitempty,max=64"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
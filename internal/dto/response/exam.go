@@ -0,0 +1,19 @@
+package response
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ExamResponse : exam as shown to a student taking it, without the answers
+type ExamResponse struct {
+	ID          primitive.ObjectID `json:"id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description,omitempty"`
+	Department  string             `json:"department,omitempty"`
+	Questions   []QuestionResponse `json:"questions"`
+	PassMark    float64            `json:"pass_mark"`
+}
+
+type QuestionResponse struct {
+	Text    string   `json:"text"`
+	Options []string `json:"options"`
+	Marks   float64  `json:"marks"`
+}
@@ -7,17 +7,23 @@ import (
 )
 
 type StudentResponse struct {
-	ID         primitive.ObjectID `json:"id"`
-	FirstName  string             `json:"first_name"`
-	LastName   string             `json:"last_name"`
-	Role       string             `json:"role"`
-	Department string             `json:"department,omitempty"`
-	StudentID  string             `json:"student_id"`
-	Email      string             `json:"email"`
-	IsActive   bool               `json:"is_active"`
-	LastLogin  *time.Time         `json:"last_login,omitempty"`
-	CreatedAt  time.Time          `json:"created_at"`
-	UpdatedAt  time.Time          `json:"updated_at"`
+	ID             primitive.ObjectID `json:"id"`
+	FirstName      string             `json:"first_name"`
+	LastName       string             `json:"last_name"`
+	Role           string             `json:"role"`
+	Department     string             `json:"department,omitempty"`
+	StudentID      string             `json:"student_id"`
+	Email          string             `json:"email"`
+	IsActive       bool               `json:"is_active"`
+	Status         string             `json:"status,omitempty"`
+	StatusReason   string             `json:"status_reason,omitempty"`
+	SuspendedUntil *time.Time         `json:"suspended_until,omitempty"`
+	LastLogin      *time.Time         `json:"last_login,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	Version        int64              `json:"version"`
+	DeletedAt      *time.Time         `json:"deleted_at,omitempty"`
+	DeletedBy      string             `json:"deleted_by,omitempty"`
 }
 
 type PasswordResetResponse struct {
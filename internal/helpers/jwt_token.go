@@ -22,10 +22,24 @@ type Claims struct {
 	Email      string `json:"email"`
 	IsActive   bool   `json:"is_active"`
 	UserID     string `json:"user_id"`
+	// IssuedAtMicro : issue time in unix microseconds, iat only has seconds and a token issued
+	// right after a revocation must not fall under it
+	IssuedAtMicro int64 `json:"iat_us,omitempty"`
 
 	jwt.StandardClaims
 }
 
+// IssuedAtTime : the issue time of the token, at second precision for tokens without iat_us
+func (c *Claims) IssuedAtTime() time.Time {
+	if c.IssuedAtMicro != 0 {
+		return time.UnixMicro(c.IssuedAtMicro)
+	}
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// TokenExpiry : lifetime of an issued token
+var TokenExpiry = 24 * time.Hour
+
 var (
 	// Define common errors
 	ErrInvalidToken = errors.New("invalid token")
@@ -107,16 +121,18 @@ func (j *JWTAuth) GenerateToken(email, userID, role string, additionalClaims map
 	}
 
 	// Token expiration time
-	tokenExpiry := time.Now().Add(24 * time.Hour)
+	issuedAt := time.Now()
+	tokenExpiry := issuedAt.Add(TokenExpiry)
 
 	// Create base claims
 	claims := &Claims{
-		Email:  email,
-		Role:   role,
-		UserID: userID,
+		Email:         email,
+		Role:          role,
+		UserID:        userID,
+		IssuedAtMicro: issuedAt.UnixMicro(),
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: tokenExpiry.Unix(),
-			IssuedAt:  time.Now().Unix(),
+			IssuedAt:  issuedAt.Unix(),
 			Issuer:    "e-exam",
 			Subject:   userID,
 		},
@@ -151,7 +167,8 @@ func (j *JWTAuth) GenerateToken(email, userID, role string, additionalClaims map
 	return signedToken, nil
 }
 
-// RefreshToken generates a new token with extended expiration
+// RefreshToken generates a new token with extended expiration,
+// the caller checks that the old token was not revoked
 func (j *JWTAuth) RefreshToken(oldToken string) (string, error) {
 	claims, err := j.ValidateToken(oldToken)
 	if err != nil {
@@ -1,7 +1,9 @@
 package helpers
 
 import (
+	"crypto/rand"
 	"fmt"
+	"math/big"
 	"unicode"
 
 	"golang.org/x/crypto/bcrypt"
@@ -62,3 +64,48 @@ func CheckWithHashedPassword(password, hashedPassword string) error {
 
 	return nil
 }
+
+const (
+	passwordUpper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordLower   = "abcdefghijkmnopqrstuvwxyz"
+	passwordDigits  = "23456789"
+	passwordSymbols = "!@#$%*?"
+)
+
+// GeneratePassword : returns a random password of the given length that passes ValidatePassword,
+// look-alike characters (0/O, 1/l/I) are left out since these are handed out on paper
+func GeneratePassword(length int) (string, error) {
+	if length < 8 {
+		length = 8
+	}
+
+	all := passwordUpper + passwordLower + passwordDigits + passwordSymbols
+	// one character from each class, then fill the rest from all of them
+	classes := []string{passwordUpper, passwordLower, passwordDigits, passwordSymbols}
+
+	password := make([]byte, length)
+	for i := range password {
+		set := all
+		if i < len(classes) {
+			set = classes[i]
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		password[i] = set[n.Int64()]
+	}
+
+	// shuffle so the character classes are not always at the start
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Glorified-Toaster/senior-project/internal/helpers"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/go-playground/validator"
+)
+
+var (
+	defaultBatchSize      int = 100
+	initialPasswordLength int = 12
+)
+
+// row statuses
+const (
+	RowValid   = "valid"
+	RowCreated = "created"
+	RowFailed  = "failed"
+)
+
+type Options struct {
+	DryRun    bool
+	BatchSize int
+}
+
+type RowResult struct {
+	Line            int      `json:"line"`
+	StudentID       string   `json:"student_id"`
+	Email           string   `json:"email"`
+	Status          string   `json:"status"`
+	Errors          []string `json:"errors,omitempty"`
+	InitialPassword string   `json:"initial_password,omitempty"`
+}
+
+type Report struct {
+	DryRun   bool        `json:"dry_run"`
+	Total    int         `json:"total"`
+	Valid    int         `json:"valid"`
+	Created  int         `json:"created"`
+	Failed   int         `json:"failed"`
+	Rows     []RowResult `json:"rows"`
+	Complete bool        `json:"complete"`
+}
+
+type Importer struct {
+	repo      repository.StudentRepository
+	validator *validator.Validate
+}
+
+func NewImporter(repo repository.StudentRepository, valid *validator.Validate) *Importer {
+	return &Importer{
+		repo:      repo,
+		validator: valid,
+	}
+}
+
+// Import : validates every row and, unless it is a dry run, creates the valid ones in batches.
+// Rows are validated with the same rules as the signup request, the initial password is generated
+func (im *Importer) Import(ctx context.Context, rows []Row, opts Options) (*Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	report := &Report{
+		DryRun: opts.DryRun,
+		Total:  len(rows),
+		Rows:   make([]RowResult, len(rows)),
+	}
+
+	// catch duplicates inside the file
+	seenIDs := map[string]int{}
+	seenEmails := map[string]int{}
+
+	var studentIDs, emails []string
+	for i, row := range rows {
+		result := &report.Rows[i]
+		result.Line = row.Line
+		result.StudentID = row.Student.StudentID
+		result.Email = row.Student.Email
+
+		password, err := helpers.GeneratePassword(initialPasswordLength)
+		if err != nil {
+			return nil, err
+		}
+		rows[i].Student.Password = password
+
+		if err := im.validator.Struct(rows[i].Student); err != nil {
+			result.Errors = append(result.Errors, validationMessages(err)...)
+		}
+
+		if line, ok := seenIDs[row.Student.StudentID]; ok && row.Student.StudentID != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("student_id is duplicated on line %d", line))
+		} else {
+			seenIDs[row.Student.StudentID] = row.Line
+		}
+		if line, ok := seenEmails[row.Student.Email]; ok && row.Student.Email != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("email is duplicated on line %d", line))
+		} else {
+			seenEmails[row.Student.Email] = row.Line
+		}
+
+		studentIDs = append(studentIDs, row.Student.StudentID)
+		emails = append(emails, row.Student.Email)
+	}
+
+	// catch students that already exist
+	takenIDs, takenEmails, err := im.repo.FindExistingStudents(ctx, studentIDs, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	var valid []int
+	for i, row := range rows {
+		result := &report.Rows[i]
+		if takenIDs[row.Student.StudentID] {
+			result.Errors = append(result.Errors, "student_id already exists")
+		}
+		if takenEmails[row.Student.Email] {
+			result.Errors = append(result.Errors, "email already exists")
+		}
+
+		if len(result.Errors) > 0 {
+			result.Status = RowFailed
+			report.Failed++
+			continue
+		}
+
+		result.Status = RowValid
+		report.Valid++
+		valid = append(valid, i)
+	}
+
+	if opts.DryRun {
+		report.Complete = true
+		return report, nil
+	}
+
+	for start := 0; start < len(valid); start += opts.BatchSize {
+		end := min(start+opts.BatchSize, len(valid))
+		batch := valid[start:end]
+
+		students := make([]*models.Student, len(batch))
+		passwords := make([]string, len(batch))
+		for j, i := range batch {
+			students[j] = &models.Student{
+				FirstName:  rows[i].Student.FirstName,
+				LastName:   rows[i].Student.LastName,
+				Email:      rows[i].Student.Email,
+				StudentID:  rows[i].Student.StudentID,
+				Department: rows[i].Student.Department,
+			}
+			passwords[j] = rows[i].Student.Password
+		}
+
+		failed, err := im.repo.InsertStudents(ctx, students, passwords)
+		if err != nil {
+			// rows of this and later batches keep the "valid" status, they were not inserted
+			return report, fmt.Errorf("batch starting at line %d failed: %w", rows[batch[0]].Line, err)
+		}
+
+		for j, i := range batch {
+			result := &report.Rows[i]
+			if err, ok := failed[j]; ok {
+				// the row passed validation but the insert rejected it
+				result.Status = RowFailed
+				result.Errors = append(result.Errors, err.Error())
+				report.Valid--
+				report.Failed++
+				continue
+			}
+
+			result.Status = RowCreated
+			result.InitialPassword = passwords[j]
+			report.Created++
+		}
+	}
+
+	report.Complete = true
+	return report, nil
+}
+
+// validationMessages : flattens validator errors to one message per field
+func validationMessages(err error) []string {
+	fieldErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []string{err.Error()}
+	}
+
+	messages := make([]string, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		if fieldErr.Param() != "" {
+			messages = append(messages, fmt.Sprintf("%s failed on %s=%s", fieldErr.Field(), fieldErr.Tag(), fieldErr.Param()))
+		} else {
+			messages = append(messages, fmt.Sprintf("%s failed on %s", fieldErr.Field(), fieldErr.Tag()))
+		}
+	}
+	return messages
+}
@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Glorified-Toaster/senior-project/internal/dto/request"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/go-playground/validator"
+)
+
+const classList = `First Name,Last Name,Email,Student ID,Department
+Jane,Doe,Jane@Example.com,S0001,CS
+J,Doe,j@example.com,S0002,CS
+John,Smith,not-an-email,S0003,CS
+Ada,Lovelace,ada@example.com,S0001,CS
+Alan,Turing,jane@example.com,S0004,CS
+,,,,
+Grace,Hopper,grace@example.com,S0100,CS
+`
+
+// staleLookup : a repository whose existence check misses the stored students, like a
+// student created between the check and the insert
+type staleLookup struct {
+	repository.StudentRepository
+}
+
+func (staleLookup) FindExistingStudents(ctx context.Context, studentIDs, emails []string) (map[string]bool, map[string]bool, error) {
+	return map[string]bool{}, map[string]bool{}, nil
+}
+
+// withExisting : a repository already holding S0100
+func withExisting(t *testing.T) *repository.MemoryStudentRepository {
+	t.Helper()
+	repo := repository.NewMemoryStudentRepo()
+	existing := &models.Student{FirstName: "Old", LastName: "Student", Email: "old@example.com", StudentID: "S0100"}
+	if _, err := repo.CreateStudent(context.Background(), existing, "Password123"); err != nil {
+		t.Fatalf("CreateStudent: %v", err)
+	}
+	return repo
+}
+
+func parseClassList(t *testing.T) []Row {
+	t.Helper()
+	rows, err := ParseCSV(strings.NewReader(classList))
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	return rows
+}
+
+func TestParseCSV(t *testing.T) {
+	rows := parseClassList(t)
+
+	// the blank line is skipped, lines keep their position in the file
+	if len(rows) != 6 || rows[5].Line != 8 {
+		t.Fatalf("parsed %d rows, last on line %d", len(rows), rows[len(rows)-1].Line)
+	}
+	if rows[0].Student.Email != "jane@example.com" || rows[0].Student.StudentID != "S0001" || rows[0].Student.Department != "CS" {
+		t.Fatalf("first row = %+v", rows[0].Student)
+	}
+
+	if _, err := ParseCSV(strings.NewReader("first_name,last_name\nJane,Doe\n")); err == nil || !strings.Contains(err.Error(), "email, student_id") {
+		t.Fatalf("ParseCSV without the required columns error = %v", err)
+	}
+}
+
+func TestValidationMessages(t *testing.T) {
+	student := request.CreateStudentRequest{
+		FirstName: "J",
+		LastName:  "Doe",
+		Email:     "not-an-email",
+		StudentID: "S0001",
+		Password:  "Password123",
+	}
+
+	want := []string{"FirstName failed on min=2", "Email failed on email"}
+	got := validationMessages(validator.New().Struct(student))
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("validationMessages = %q, want %q", got, want)
+	}
+}
+
+func TestImportValidation(t *testing.T) {
+	report, err := NewImporter(withExisting(t), validator.New()).Import(context.Background(), parseClassList(t), Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	wantErrors := []string{
+		"",
+		"FirstName failed on min=2",
+		"Email failed on email",
+		"student_id is duplicated on line 2",
+		"email is duplicated on line 2",
+		"student_id already exists",
+	}
+	for i, want := range wantErrors {
+		row := report.Rows[i]
+		if want == "" {
+			if row.Status != RowValid || len(row.Errors) != 0 {
+				t.Errorf("line %d = %+v, want valid", row.Line, row)
+			}
+			continue
+		}
+		if row.Status != RowFailed || len(row.Errors) != 1 || row.Errors[0] != want {
+			t.Errorf("line %d errors = %q, want %q", row.Line, row.Errors, want)
+		}
+	}
+	if report.Total != 6 || report.Valid != 1 || report.Failed != 5 || report.Created != 0 || !report.Complete {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestImportCreatesValidRows(t *testing.T) {
+	repo := withExisting(t)
+
+	report, err := NewImporter(repo, validator.New()).Import(context.Background(), parseClassList(t), Options{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if report.Valid != 1 || report.Created != 1 || report.Failed != 5 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	created := report.Rows[0]
+	if created.Status != RowCreated || len(created.InitialPassword) != initialPasswordLength {
+		t.Fatalf("created row = %+v", created)
+	}
+	if _, err := repo.VerifyPassword(context.Background(), "S0001", created.InitialPassword); err != nil {
+		t.Fatalf("login with the initial password: %v", err)
+	}
+}
+
+func TestImportCountsRowsRejectedByTheInsert(t *testing.T) {
+	// S0100 passes the existence check but the insert rejects it
+	report, err := NewImporter(staleLookup{withExisting(t)}, validator.New()).Import(context.Background(), parseClassList(t), Options{})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	rejected := report.Rows[5]
+	if rejected.Status != RowFailed || len(rejected.Errors) != 1 {
+		t.Fatalf("rejected row = %+v", rejected)
+	}
+	if report.Valid != 1 || report.Created != 1 || report.Failed != 5 {
+		t.Fatalf("report = %+v", report)
+	}
+}
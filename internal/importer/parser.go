@@ -0,0 +1,151 @@
+// Package importer implements bulk student import from CSV and XLSX class lists.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/Glorified-Toaster/senior-project/internal/dto/request"
+	"github.com/xuri/excelize/v2"
+)
+
+// supported file formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Row : one student parsed from the sheet, Line is the 1-based line/row in the file
+type Row struct {
+	Line    int
+	Student request.CreateStudentRequest
+}
+
+// columns every sheet must have (header names are matched case-insensitively)
+var requiredColumns = []string{"first_name", "last_name", "email", "student_id"}
+
+// FormatFromFilename : picks the format from the file extension
+func FormatFromFilename(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported file type %q, expected .csv or .xlsx", filepath.Ext(name))
+	}
+}
+
+// Parse : reads the students from a CSV or XLSX file
+func Parse(r io.Reader, format string) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r)
+	case FormatXLSX:
+		return ParseXLSX(r)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// ParseCSV : reads the students from a CSV file with a header line
+func ParseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+
+	return rowsFromRecords(records)
+}
+
+// ParseXLSX : reads the students from the first sheet of an XLSX workbook
+func ParseXLSX(r io.Reader) ([]Row, error) {
+	workbook, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xlsx: %w", err)
+	}
+	defer workbook.Close()
+
+	sheets := workbook.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("xlsx workbook has no sheets")
+	}
+
+	records, err := workbook.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %q: %w", sheets[0], err)
+	}
+
+	return rowsFromRecords(records)
+}
+
+// rowsFromRecords : maps raw records to rows using the header in the first record
+func rowsFromRecords(records [][]string) ([]Row, error) {
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		// excel likes to prepend a BOM to utf-8 csv files
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[strings.ReplaceAll(name, " ", "_")] = i
+	}
+
+	var missing []string
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+
+	cell := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := make([]Row, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if isBlank(record) {
+			continue
+		}
+
+		rows = append(rows, Row{
+			// +2 : 1-based and skipping the header
+			Line: i + 2,
+			Student: request.CreateStudentRequest{
+				FirstName:  cell(record, "first_name"),
+				LastName:   cell(record, "last_name"),
+				Email:      strings.ToLower(cell(record, "email")),
+				StudentID:  cell(record, "student_id"),
+				Department: cell(record, "department"),
+			},
+		})
+	}
+
+	return rows, nil
+}
+
+// isBlank : reports whether every cell of the record is empty
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+// Package jobs provides the background jobs started with the server.
+package jobs
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"go.uber.org/zap"
+)
+
+// TrashPurger : hard deletes the students and exams that stayed in the trash longer than the retention
+type TrashPurger struct {
+	students  repository.StudentRepository
+	exams     repository.ExamRepository
+	audit     repository.AuditRepository
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewTrashPurger(students repository.StudentRepository, exams repository.ExamRepository, audit repository.AuditRepository, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{
+		students:  students,
+		exams:     exams,
+		audit:     audit,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run : purges the trash every interval until the context is cancelled, the first purge runs immediately
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.Purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge : runs a single purge, failures are logged and retried on the next run
+func (p *TrashPurger) Purge(ctx context.Context) {
+	deletedBefore := time.Now().Add(-p.retention)
+
+	// exams first, purging them also removes them from the students' required exams
+	exams, err := p.exams.PurgeDeletedExams(ctx, deletedBefore)
+	if err != nil {
+		utils.LogErrorWithLevel("error", utils.TrashPurgeFailed.Type, utils.TrashPurgeFailed.Code, utils.TrashPurgeFailed.Msg, err, zap.String("entity", "exams"))
+	}
+
+	students, err := p.students.PurgeDeletedStudents(ctx, deletedBefore)
+	if err != nil {
+		utils.LogErrorWithLevel("error", utils.TrashPurgeFailed.Type, utils.TrashPurgeFailed.Code, utils.TrashPurgeFailed.Msg, err, zap.String("entity", "students"))
+	}
+
+	if exams == 0 && students == 0 {
+		return
+	}
+	utils.LogInfo(utils.TrashPurged.Type, utils.TrashPurged.Msg, zap.Int64("exams", exams), zap.Int64("students", students))
+
+	if err := p.audit.Record(ctx, &models.AuditEntry{
+		ActorID:    "system",
+		Action:     models.AuditTrashPurge,
+		TargetType: models.AuditTargetTrash,
+		Metadata: map[string]string{
+			"deleted_before": deletedBefore.UTC().Format(time.RFC3339),
+			"exams":          strconv.FormatInt(exams, 10),
+			"students":       strconv.FormatInt(students, 10),
+		},
+	}); err != nil {
+		utils.LogErrorWithLevel("error", utils.AuditRecordFailed.Type, utils.AuditRecordFailed.Code, utils.AuditRecordFailed.Msg, err, zap.String("action", models.AuditTrashPurge))
+	}
+}
@@ -1,26 +1,38 @@
 package middleware
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/Glorified-Toaster/senior-project/internal/helpers"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
 	"github.com/Glorified-Toaster/senior-project/internal/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 type AuthMiddleware struct {
-	jwt *helpers.JWTAuth
+	jwt      *helpers.JWTAuth
+	students repository.StudentRepository
 }
 
-func NewAuthMiddleware(jwt *helpers.JWTAuth) *AuthMiddleware {
+func NewAuthMiddleware(jwt *helpers.JWTAuth, students repository.StudentRepository) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwt: jwt,
+		jwt:      jwt,
+		students: students,
 	}
 }
 
+// tokenRevoked : reports whether a token issued at issuedAt was revoked by a change of the account
+func tokenRevoked(student *models.Student, issuedAt time.Time) bool {
+	return student.TokensValidAfter != nil && issuedAt.Before(*student.TokensValidAfter)
+}
+
 func (m *AuthMiddleware) AuthenticationMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// get client info for logging
@@ -104,11 +116,61 @@ func (m *AuthMiddleware) AuthenticationMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// the revocations live on the student document, a token is refused when it cannot be checked
+		student, err := m.students.GetStudentByID(ctx.Request.Context(), claims.StudentID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			utils.LogErrorWithLevel("error", "HTTP_SERVER_ERROR", "TOKEN_REVOCATION_CHECK_ERROR", "failed to check token revocation", err)
+
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "could not verify the token",
+				"code":    "TOKEN_CHECK_UNAVAILABLE",
+				"message": "Please try again later",
+			})
+			ctx.Abort()
+			return
+		}
+		// a deleted student has no document outside the trash
+		if err != nil || tokenRevoked(student, claims.IssuedAtTime()) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "token has been revoked",
+				"code":    "TOKEN_REVOKED",
+				"message": "Please login again to get a new token",
+			})
+			ctx.Abort()
+			return
+		}
+
 		setClaimsInContext(ctx, claims)
 		ctx.Next()
 	}
 }
 
+// RequireRoles : only lets authenticated users with one of the given roles through,
+// must be used after AuthenticationMiddleware
+func (m *AuthMiddleware) RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		role := ctx.GetString("role")
+
+		if !slices.Contains(roles, role) {
+			utils.LogInfo("HTTP_SERVER",
+				"Access denied",
+				zap.String("IP address", ctx.ClientIP()),
+				zap.String("role", role),
+				zap.String("path", ctx.Request.URL.Path))
+
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error":   "insufficient permissions",
+				"code":    "FORBIDDEN",
+				"message": "You are not allowed to access this resource",
+			})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
 func setClaimsInContext(ctx *gin.Context, claims *helpers.Claims) {
 	ctx.Set("claims", claims)
 	ctx.Set("userID", claims.UserID)
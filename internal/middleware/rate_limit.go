@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// idleBucketAfter : buckets unused for this long are full again, they are dropped
+const idleBucketAfter = 10 * time.Minute
+
+// bucket : tokens left for a client and when they were counted
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter : token bucket per client IP, its limits follow the reloads of the config
+type RateLimiter struct {
+	mu        sync.Mutex
+	limits    config.RateLimitConf
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter : a limiter with the rate_limit section of the config, disabled without one
+func NewRateLimiter() *RateLimiter {
+	l := &RateLimiter{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+	if cfg, err := config.GetConfig(); err == nil && cfg.RateLimit != nil {
+		l.limits = *cfg.RateLimit
+	}
+	config.Subscribe(l.applyConfig)
+	return l
+}
+
+// applyConfig : takes the reloaded limits, the clients keep the tokens they have left
+func (l *RateLimiter) applyConfig(cfg *config.Config, changes config.Changes) {
+	if !changes.Has("rate_limit") || cfg.RateLimit == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = *cfg.RateLimit
+}
+
+// allow : takes a token of the client, otherwise how long until the next one
+func (l *RateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.limits.Enabled {
+		return true, 0
+	}
+	rate, burst := l.limits.RequestsPerSecond, float64(l.limits.Burst)
+
+	if now.Sub(l.lastSweep) > idleBucketAfter {
+		for key, b := range l.buckets {
+			if now.Sub(b.last) > idleBucketAfter {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Middleware : answers 429 with Retry-After to the clients over their limit
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		allowed, wait := l.allow(ctx.ClientIP(), time.Now())
+		if !allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			ctx.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "too many requests",
+				"code":    "RATE_LIMITED",
+				"message": "Please slow down and retry later",
+			})
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
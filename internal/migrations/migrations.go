@@ -0,0 +1,346 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// registered : every migration of the application, never edit an applied one, add a new version instead
+var registered = []Migration{
+	{
+		Version:     1,
+		Description: "unique indexes on students.student_id and students.email",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("students"), []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "student_id", Value: 1}},
+					Options: options.Index().SetName("student_id_unique").SetUnique(true),
+				},
+				{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email_unique").SetUnique(true),
+				},
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("students"), "student_id_unique", "email_unique")
+		},
+	},
+	// versions 2 and 3 keep the default index names, these indexes used to be created by the repositories
+	{
+		Version:     2,
+		Description: "student listing indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("students"), []mongo.IndexModel{
+				{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+				{Keys: bson.D{{Key: "last_name", Value: 1}, {Key: "_id", Value: 1}}},
+				{Keys: bson.D{{Key: "first_name", Value: 1}, {Key: "_id", Value: 1}}},
+				{Keys: bson.D{{Key: "department", Value: 1}, {Key: "created_at", Value: 1}}},
+				{Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "created_at", Value: 1}}},
+				{Keys: bson.D{{Key: "required_exams", Value: 1}}},
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("students"),
+				"created_at_1__id_1", "last_name_1__id_1", "first_name_1__id_1", "department_1_created_at_1", "is_active_1_created_at_1", "required_exams_1")
+		},
+	},
+	{
+		Version:     3,
+		Description: "login history lookup and retention (TTL) indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("login_history"), []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "timestamp", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(int32(models.LoginHistoryRetention.Seconds())),
+				},
+				{Keys: bson.D{{Key: "student_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+				{Keys: bson.D{{Key: "ip", Value: 1}, {Key: "timestamp", Value: -1}}},
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("login_history"), "timestamp_1", "student_id_1_timestamp_-1", "ip_1_timestamp_-1")
+		},
+	},
+	{
+		Version:     4,
+		Description: "backfill students.status from is_active",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			students := db.Collection("students")
+			missing := bson.M{"status": bson.M{"$exists": false}}
+
+			if _, err := students.UpdateMany(ctx,
+				bson.M{"$and": bson.A{missing, bson.M{"is_active": true}}},
+				bson.M{"$set": bson.M{"status": "active"}},
+			); err != nil {
+				return err
+			}
+			_, err := students.UpdateMany(ctx,
+				bson.M{"$and": bson.A{missing, bson.M{"is_active": bson.M{"$ne": true}}}},
+				bson.M{"$set": bson.M{"status": "deactivated"}},
+			)
+			return err
+		},
+		// the status is not removed again, it is consistent with is_active
+		Down: func(ctx context.Context, db *mongo.Database) error { return nil },
+	},
+	{
+		Version:     5,
+		Description: "JSON schema validator on students",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return setValidator(ctx, db, "students", studentSchemaV1)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return setValidator(ctx, db, "students", bson.M{})
+		},
+	},
+	{
+		Version:     6,
+		Description: "exam attempt indexes",
+		// creating the indexes also creates the collections, which older servers
+		// do not allow inside the exam transactions
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			if err := createIndexes(ctx, db.Collection("exam_attempts"), []mongo.IndexModel{
+				{Keys: bson.D{{Key: "exam_id", Value: 1}, {Key: "status", Value: 1}}},
+				{Keys: bson.D{{Key: "student_id", Value: 1}, {Key: "exam_id", Value: 1}}},
+			}); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db.Collection("exams"), []mongo.IndexModel{
+				{Keys: bson.D{{Key: "created_by", Value: 1}}},
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			if err := dropIndexes(ctx, db.Collection("exam_attempts"), "exam_id_1_status_1", "student_id_1_exam_id_1"); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, db.Collection("exams"), "created_by_1")
+		},
+	},
+	{
+		Version:     7,
+		Description: "backfill the version of students and exams",
+		// versioned updates match on the exact version, so every document needs one
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, collection := range []string{"students", "exams"} {
+				if _, err := db.Collection(collection).UpdateMany(ctx,
+					bson.M{"version": bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{"version": 0}},
+				); err != nil {
+					return fmt.Errorf("failed to backfill version of %s: %w", collection, err)
+				}
+			}
+			return nil
+		},
+		// the version is not removed again, older code ignores it
+		Down: func(ctx context.Context, db *mongo.Database) error { return nil },
+	},
+	{
+		Version:     8,
+		Description: "trash indexes",
+		// sparse, only the documents in the trash have deleted_at
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, collection := range []string{"students", "exams"} {
+				if err := createIndexes(ctx, db.Collection(collection), []mongo.IndexModel{
+					{Keys: bson.D{{Key: "deleted_at", Value: -1}}, Options: options.Index().SetSparse(true)},
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, collection := range []string{"students", "exams"} {
+				if err := dropIndexes(ctx, db.Collection(collection), "deleted_at_-1"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     9,
+		Description: "audit log chain and search indexes",
+		// the unique seq index is what keeps concurrent writers from forking the hash chain
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("audit_log"), []mongo.IndexModel{
+				{
+					Keys:    bson.D{{Key: "seq", Value: 1}},
+					Options: options.Index().SetName("seq_unique").SetUnique(true),
+				},
+				{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "seq", Value: -1}}},
+				{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "seq", Value: -1}}},
+				{Keys: bson.D{{Key: "action", Value: 1}, {Key: "seq", Value: -1}}},
+				{Keys: bson.D{{Key: "request_id", Value: 1}}},
+				{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("audit_log"),
+				"seq_unique", "actor_id_1_seq_-1", "target_type_1_target_id_1_seq_-1", "action_1_seq_-1", "request_id_1", "timestamp_-1")
+		},
+	},
+	{
+		Version:     10,
+		Description: "record change stream pre-images of students",
+		// the cache invalidation watcher needs the deleted (or renamed) student to know which keys to evict
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return setPreImages(ctx, db, "students", true)
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return setPreImages(ctx, db, "students", false)
+		},
+	},
+	{
+		Version:     11,
+		Description: "exam and attempt lookups of gridfs files",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("files.files"), []mongo.IndexModel{
+				{Keys: bson.D{{Key: "metadata.exam_id", Value: 1}, {Key: "metadata.attempt_id", Value: 1}, {Key: "uploadDate", Value: 1}}},
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("files.files"), "metadata.exam_id_1_metadata.attempt_id_1_uploadDate_1")
+		},
+	},
+	{
+		Version:     12,
+		Description: "one in progress attempt per student and exam",
+		// fails while a student still has several in progress attempts of an exam, submit or
+		// remove the extra ones first
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("exam_attempts"), []mongo.IndexModel{
+				{
+					Keys: bson.D{{Key: "exam_id", Value: 1}, {Key: "student_id", Value: 1}},
+					Options: options.Index().
+						SetName("exam_student_in_progress_unique").
+						SetUnique(true).
+						SetPartialFilterExpression(bson.M{"status": "in_progress"}),
+				},
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("exam_attempts"), "exam_student_in_progress_unique")
+		},
+	},
+	{
+		Version:     13,
+		Description: "text index for the student search",
+		// no language, names and IDs are matched as written instead of stemmed
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db.Collection("students"), []mongo.IndexModel{
+				{
+					Keys: bson.D{
+						{Key: "first_name", Value: "text"},
+						{Key: "last_name", Value: "text"},
+						{Key: "email", Value: "text"},
+						{Key: "student_id", Value: "text"},
+					},
+					Options: options.Index().SetName("student_search_text").SetDefaultLanguage("none"),
+				},
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection("students"), "student_search_text")
+		},
+	},
+}
+
+// studentSchemaV1 : validator of the students collection
+var studentSchemaV1 = bson.M{
+	"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": bson.A{"student_id", "email", "first_name", "last_name", "role", "password_hash", "is_active", "created_at", "updated_at"},
+		"properties": bson.M{
+			"student_id":    bson.M{"bsonType": "string", "minLength": 1},
+			"email":         bson.M{"bsonType": "string", "pattern": "^[^@\\s]+@[^@\\s]+$"},
+			"first_name":    bson.M{"bsonType": "string", "minLength": 1, "maxLength": 64},
+			"last_name":     bson.M{"bsonType": "string", "minLength": 1, "maxLength": 64},
+			"role":          bson.M{"enum": bson.A{"student", "teacher", "admin"}},
+			"password_hash": bson.M{"bsonType": "string"},
+			"is_active":     bson.M{"bsonType": "bool"},
+			"status":        bson.M{"enum": bson.A{"active", "deactivated", "suspended", "archived"}},
+			"created_at":    bson.M{"bsonType": "date"},
+			"updated_at":    bson.M{"bsonType": "date"},
+			"last_login":    bson.M{"bsonType": "date"},
+		},
+	},
+}
+
+// setPreImages : turns the change stream pre-images of a collection on or off, servers older than
+// 6.0 do not support them and are left alone, the watcher then only sees the changed documents
+func setPreImages(ctx context.Context, db *mongo.Database, collection string, enabled bool) error {
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": enabled}},
+	}).Err()
+
+	var cmdErr mongo.CommandError
+	// unknown field / InvalidOptions
+	if errors.As(err, &cmdErr) && (cmdErr.Code == 40415 || cmdErr.Code == 72) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set pre-images on %s: %w", collection, err)
+	}
+	return nil
+}
+
+// createIndexes : creates the indexes of a collection
+func createIndexes(ctx context.Context, collection *mongo.Collection, models []mongo.IndexModel) error {
+	if _, err := collection.Indexes().CreateMany(ctx, models); err != nil {
+		return fmt.Errorf("failed to create indexes on %s: %w", collection.Name(), err)
+	}
+	return nil
+}
+
+// dropIndexes : drops the named indexes, missing ones are ignored
+func dropIndexes(ctx context.Context, collection *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		if _, err := collection.Indexes().DropOne(ctx, name); err != nil {
+			var cmdErr mongo.CommandError
+			// IndexNotFound / NamespaceNotFound
+			if errors.As(err, &cmdErr) && (cmdErr.Code == 27 || cmdErr.Code == 26) {
+				continue
+			}
+			return fmt.Errorf("failed to drop index %s on %s: %w", name, collection.Name(), err)
+		}
+	}
+	return nil
+}
+
+// setValidator : sets the validator of a collection, creating the collection if needed,
+// an empty validator removes the validation
+func setValidator(ctx context.Context, db *mongo.Database, collection string, validator bson.M) error {
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": collection})
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	if !slices.Contains(names, collection) {
+		opts := options.CreateCollection().SetValidator(validator).SetValidationLevel("moderate")
+		if err := db.CreateCollection(ctx, collection, opts); err != nil {
+			return fmt.Errorf("failed to create collection %s: %w", collection, err)
+		}
+		return nil
+	}
+
+	// moderate : documents that are already invalid can still be updated
+	err = db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+		{Key: "validationAction", Value: "error"},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to set validator on %s: %w", collection, err)
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+// Package migrations implements versioned MongoDB schema migrations (indexes, validators
+// and data backfills) tracked in the schema_migrations collection.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+var (
+	migrationsCollection = "schema_migrations"
+	lockCollection       = "schema_migrations_lock"
+	lockID               = "migration_lock"
+
+	lockTTL          = 5 * time.Minute  // a crashed instance releases the lock after this
+	lockRefresh      = 1 * time.Minute  // how often a running migration extends the lock
+	lockPollInterval = 2 * time.Second  // how often a waiting instance retries
+	lockWaitTimeout  = 10 * time.Minute // how long an instance waits for the lock
+)
+
+var (
+	// ErrLockTimeout : another instance held the migration lock for too long
+	ErrLockTimeout = errors.New("timed out waiting for the migration lock")
+	// ErrLockLost : the lock expired and was taken by another instance during the run
+	ErrLockLost = errors.New("the migration lock was lost during the run")
+)
+
+// Migration : one versioned schema change, Down undoes Up
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+// appliedMigration : a document of the schema_migrations collection
+type appliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+	DurationMS  int64     `bson:"duration_ms"`
+}
+
+// Status : state of a known migration
+type Status struct {
+	Version     int        `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+	owner      string
+}
+
+// NewRunner : creates a runner over the registered migrations
+func NewRunner(db *mongo.Database) *Runner {
+	return NewRunnerWith(db, registered)
+}
+
+// NewRunnerWith : creates a runner over the given migrations
+func NewRunnerWith(db *mongo.Database, migrations []Migration) *Runner {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	hostname, _ := os.Hostname()
+
+	return &Runner{
+		db:         db,
+		migrations: sorted,
+		owner:      fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// Up : applies every pending migration up to target (0 means latest), returns the applied versions
+func (r *Runner) Up(ctx context.Context, target int) ([]int, error) {
+	var applied []int
+
+	err := r.withLock(ctx, func(ctx context.Context) error {
+		done, err := r.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range r.migrations {
+			if target > 0 && migration.Version > target {
+				break
+			}
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			if err := r.apply(ctx, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration.Version)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down : reverts the last `steps` applied migrations, returns the reverted versions
+func (r *Runner) Down(ctx context.Context, steps int) ([]int, error) {
+	var reverted []int
+
+	err := r.withLock(ctx, func(ctx context.Context) error {
+		done, err := r.appliedVersions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(r.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := r.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			if err := r.revert(ctx, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration.Version)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status : lists every known migration and whether it was applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	done, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(r.migrations))
+	for i, migration := range r.migrations {
+		statuses[i] = Status{
+			Version:     migration.Version,
+			Description: migration.Description,
+		}
+		if record, ok := done[migration.Version]; ok {
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = &record.AppliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// apply : runs the Up step and records it
+func (r *Runner) apply(ctx context.Context, migration Migration) error {
+	start := time.Now()
+
+	if err := migration.Up(ctx, r.db); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+	}
+
+	_, err := r.db.Collection(migrationsCollection).InsertOne(ctx, appliedMigration{
+		Version:     migration.Version,
+		Description: migration.Description,
+		AppliedAt:   time.Now(),
+		DurationMS:  time.Since(start).Milliseconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	utils.LogInfo(utils.MigrationApplied.Type, utils.MigrationApplied.Msg,
+		zap.Int("version", migration.Version),
+		zap.String("description", migration.Description),
+		zap.Duration("duration", time.Since(start)))
+
+	return nil
+}
+
+// revert : runs the Down step and removes its record
+func (r *Runner) revert(ctx context.Context, migration Migration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("migration %d (%s) cannot be reverted", migration.Version, migration.Description)
+	}
+
+	if err := migration.Down(ctx, r.db); err != nil {
+		return fmt.Errorf("reverting migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+	}
+
+	if _, err := r.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+		return fmt.Errorf("failed to remove migration record %d: %w", migration.Version, err)
+	}
+
+	utils.LogInfo(utils.MigrationReverted.Type, utils.MigrationReverted.Msg,
+		zap.Int("version", migration.Version),
+		zap.String("description", migration.Description))
+
+	return nil
+}
+
+// appliedVersions : loads the records of the applied migrations
+func (r *Runner) appliedVersions(ctx context.Context) (map[int]appliedMigration, error) {
+	cursor, err := r.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	var records []appliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+
+	done := make(map[int]appliedMigration, len(records))
+	for _, record := range records {
+		done[record.Version] = record
+	}
+	return done, nil
+}
+
+// withLock : runs fn while holding the cluster-wide migration lock, so only one instance migrates
+func (r *Runner) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := r.acquireLock(ctx); err != nil {
+		return err
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	refreshDone := make(chan struct{})
+
+	// keep the lock alive during long migrations, once another instance holds it the
+	// running migration is cancelled so the two never migrate at the same time
+	go func() {
+		defer close(refreshDone)
+		ticker := time.NewTicker(lockRefresh)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-lockCtx.Done():
+				return
+			case <-ticker.C:
+				acquired, err := r.tryLock(lockCtx)
+				if err != nil {
+					if lockCtx.Err() == nil {
+						utils.LogErrorWithLevel("warn", utils.MigrationLockFailed.Type, utils.MigrationLockFailed.Code, "failed to refresh the migration lock", err)
+					}
+					continue
+				}
+				if !acquired {
+					utils.LogErrorWithLevel("error", utils.MigrationLockFailed.Type, utils.MigrationLockFailed.Code, utils.MigrationLockFailed.Msg, ErrLockLost)
+					cancel(ErrLockLost)
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(lockCtx)
+	lost := errors.Is(context.Cause(lockCtx), ErrLockLost)
+
+	cancel(nil)
+	<-refreshDone
+
+	// release with a fresh context, the caller's may already be done
+	releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer releaseCancel()
+	if _, releaseErr := r.db.Collection(lockCollection).DeleteOne(releaseCtx, bson.M{"_id": lockID, "owner": r.owner}); releaseErr != nil {
+		utils.LogErrorWithLevel("warn", utils.MigrationLockFailed.Type, utils.MigrationLockFailed.Code, "failed to release the migration lock", releaseErr)
+	}
+
+	if lost {
+		return errors.Join(ErrLockLost, err)
+	}
+	return err
+}
+
+// acquireLock : waits until the lock is free or expired and takes it
+func (r *Runner) acquireLock(ctx context.Context) error {
+	deadline := time.Now().Add(lockWaitTimeout)
+
+	for {
+		acquired, err := r.tryLock(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to acquire the migration lock: %w", err)
+		}
+		if acquired {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// tryLock : takes or extends the lock if it is free, expired or already ours
+func (r *Runner) tryLock(ctx context.Context) (bool, error) {
+	now := time.Now()
+
+	_, err := r.db.Collection(lockCollection).UpdateOne(ctx,
+		bson.M{
+			"_id": lockID,
+			"$or": bson.A{
+				bson.M{"owner": r.owner},
+				bson.M{"expires_at": bson.M{"$lt": now}},
+			},
+		},
+		bson.M{"$set": bson.M{
+			"owner":       r.owner,
+			"acquired_at": now,
+			"expires_at":  now.Add(lockTTL),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		// the filter did not match a live lock held by someone else, so the upsert collided on _id
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// audited actions
+const (
+	AuditStudentCreate   = "student.create"
+	AuditStudentUpdate   = "student.update"
+	AuditStudentStatus   = "student.status"
+	AuditStudentPassword = "student.password_change"
+	AuditStudentImport   = "student.import"
+	AuditStudentDelete   = "student.delete"
+	AuditStudentRestore  = "student.restore"
+	AuditExamCreate      = "exam.create"
+	AuditExamUpdate      = "exam.update"
+	AuditExamRegrade     = "exam.regrade"
+	AuditExamEnroll      = "exam.enroll"
+	AuditExamUnenroll    = "exam.unenroll"
+	AuditExamDelete      = "exam.delete"
+	AuditExamRestore     = "exam.restore"
+	AuditAttemptStart    = "attempt.start"
+	AuditAttemptSubmit   = "attempt.submit"
+	AuditFileUpload      = "file.upload"
+	AuditFileDelete      = "file.delete"
+	AuditTrashPurge      = "trash.purge"
+)
+
+// audited target types
+const (
+	AuditTargetStudent = "student"
+	AuditTargetExam    = "exam"
+	AuditTargetAttempt = "attempt"
+	AuditTargetFile    = "file"
+	AuditTargetTrash   = "trash"
+)
+
+// AuditEntry : one privileged action, entries form a hash chain in Seq order where Hash covers
+// every field but ID and PrevHash is the Hash of the previous entry
+type AuditEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Seq        int64              `bson:"seq" json:"seq"`
+	Timestamp  time.Time          `bson:"timestamp" json:"timestamp"`
+	ActorID    string             `bson:"actor_id" json:"actor_id"`
+	ActorRole  string             `bson:"actor_role,omitempty" json:"actor_role,omitempty"`
+	ActorEmail string             `bson:"actor_email,omitempty" json:"actor_email,omitempty"`
+	Action     string             `bson:"action" json:"action"`
+	TargetType string             `bson:"target_type" json:"target_type"`
+	TargetID   string             `bson:"target_id,omitempty" json:"target_id,omitempty"`
+	Changes    []AuditChange      `bson:"changes,omitempty" json:"changes,omitempty"`
+	Metadata   map[string]string  `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	IP         string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	RequestID  string             `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	PrevHash   string             `bson:"prev_hash" json:"prev_hash"`
+	Hash       string             `bson:"hash" json:"hash"`
+}
+
+// AuditChange : before and after value (JSON) of a changed field, a missing side means the field did not exist
+type AuditChange struct {
+	Field  string          `bson:"field" json:"field"`
+	Before json.RawMessage `bson:"before,omitempty" json:"before,omitempty"`
+	After  json.RawMessage `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// AuditFilter : search criteria for audit entries, zero values are ignored,
+// BeforeSeq pages backwards through the newest first results
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+	BeforeSeq  int64
+	Limit      int64
+}
+
+// AuditVerification : result of walking the audit chain, BrokenAt is the first entry that does not verify
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// attempt status values
+const (
+	AttemptInProgress = "in_progress"
+	AttemptSubmitted  = "submitted"
+)
+
+type Exam struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Title            string             `bson:"title" json:"title"`
+	Description      string             `bson:"description,omitempty" json:"description,omitempty"`
+	Department       string             `bson:"department,omitempty" json:"department,omitempty"`
+	Questions        []Question         `bson:"questions" json:"questions"`
+	PassMark         float64            `bson:"pass_mark" json:"pass_mark"`
+	EnrolledStudents []string           `bson:"enrolled_students" json:"enrolled_students"`
+	CreatedBy        string             `bson:"created_by" json:"created_by"`
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+	Version          int64              `bson:"version" json:"version"`
+	DeletedAt        *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy        string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// Question : multiple choice question, Answer is the index of the correct option
+type Question struct {
+	Text    string   `bson:"text" json:"text"`
+	Options []string `bson:"options" json:"options"`
+	Answer  int      `bson:"answer" json:"answer"`
+	Marks   float64  `bson:"marks" json:"marks"`
+}
+
+// ExamAttempt : one try of a student at an exam, Answers[i] is the chosen option of question i (-1 when skipped)
+type ExamAttempt struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ExamID      primitive.ObjectID `bson:"exam_id" json:"exam_id"`
+	StudentID   string             `bson:"student_id" json:"student_id"`
+	Status      string             `bson:"status" json:"status"`
+	Answers     []int              `bson:"answers,omitempty" json:"answers,omitempty"`
+	Score       float64            `bson:"score" json:"score"`
+	TotalMarks  float64            `bson:"total_marks" json:"total_marks"`
+	Passed      bool               `bson:"passed" json:"passed"`
+	StartedAt   time.Time          `bson:"started_at" json:"started_at"`
+	SubmittedAt *time.Time         `bson:"submitted_at,omitempty" json:"submitted_at,omitempty"`
+	GradedAt    *time.Time         `bson:"graded_at,omitempty" json:"graded_at,omitempty"`
+}
+
+// ExamUpdate : exam details a teacher may edit, nil fields are left untouched,
+// the update is refused when ExpectedVersion is set and the exam has moved on
+type ExamUpdate struct {
+	Title           *string
+	Description     *string
+	Department      *string
+	PassMark        *float64
+	ExpectedVersion *int64
+}
+
+// ExamRegrade : new answer key, marks and pass mark of an exam, the number of questions must not change
+type ExamRegrade struct {
+	Questions       []Question
+	PassMark        float64
+	ExpectedVersion *int64
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StoredFile : a file kept in GridFS, maps the GridFS files document. A file belongs to an exam
+// (question material, AttemptID is nil) or to an attempt of that exam (a student's upload).
+type StoredFile struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	Filename     string             `bson:"filename" json:"filename"`
+	Size         int64              `bson:"length" json:"size"`
+	ChunkSize    int32              `bson:"chunkSize" json:"-"`
+	UploadedAt   time.Time          `bson:"uploadDate" json:"uploaded_at"`
+	FileMetadata `bson:"metadata"`
+}
+
+// FileMetadata : what the application stores next to the GridFS content
+type FileMetadata struct {
+	ContentType string              `bson:"content_type" json:"content_type"`
+	ExamID      primitive.ObjectID  `bson:"exam_id" json:"exam_id"`
+	AttemptID   *primitive.ObjectID `bson:"attempt_id,omitempty" json:"attempt_id,omitempty"`
+	Question    *int                `bson:"question,omitempty" json:"question,omitempty"`
+	UploadedBy  string              `bson:"uploaded_by" json:"uploaded_by"`
+}
+
+// FileFilter : the files of an exam (AttemptID nil, attempt uploads excluded) or of an attempt
+type FileFilter struct {
+	ExamID    primitive.ObjectID
+	AttemptID *primitive.ObjectID
+}
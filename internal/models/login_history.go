@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginHistoryRetention : how long login events are kept, the TTL index of the login_history
+// collection removes them afterwards
+const LoginHistoryRetention = 90 * 24 * time.Hour
+
+// login failure reasons
+const (
+	LoginFailedUnknownStudent  = "unknown_student"
+	LoginFailedInvalidPassword = "invalid_password"
+	LoginFailedAccountInactive = "account_inactive"
+)
+
+type LoginEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	StudentID string             `bson:"student_id" json:"student_id"`
+	Success   bool               `bson:"success" json:"success"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	IP        string             `bson:"ip" json:"ip"`
+	UserAgent string             `bson:"user_agent" json:"user_agent"`
+	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+// LoginHistoryFilter : search criteria for login events, zero values are ignored
+type LoginHistoryFilter struct {
+	StudentID string
+	IP        string
+	Success   *bool
+	From      *time.Time
+	To        *time.Time
+	Limit     int64
+}
@@ -6,27 +6,81 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// account status values
+const (
+	StatusActive      = "active"
+	StatusDeactivated = "deactivated"
+	StatusSuspended   = "suspended"
+	StatusArchived    = "archived"
+)
+
 type Student struct {
-	ID             primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	FirstName      string               `bson:"first_name" json:"first_name" validate:"required,min=2,max=32"`
-	LastName       string               `bson:"last_name" json:"last_name" validate:"required,min=2,max=32"`
-	Role           string               `bson:"role" json:"role"`
-	Department     string               `bson:"department,omitempty" json:"department,omitempty"`
-	StudentID      string               `bson:"student_id" json:"student_id"`
-	Email          string               `bson:"email" json:"email" validate:"email,required"`
-	PasswordHash   string               `bson:"password_hash" json:"-"`
-	IsActive       bool                 `bson:"is_active" json:"is_active"`
-	LastLogin      *time.Time           `bson:"last_login,omitempty" json:"last_login,omitempty"`
-	CreatedAt      time.Time            `bson:"created_at" json:"created_at"`
-	UpdatedAt      time.Time            `bson:"updated_at" json:"updated_at"`
-	RequiredExams  []primitive.ObjectID `bson:"required_exams,omitempty" json:"required_exams,omitempty"`
-	CompletedExams []CompletedExam      `bson:"completed_exams,omitempty" json:"completed_exams,omitempty"`
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FirstName       string             `bson:"first_name" json:"first_name" validate:"required,min=2,max=32"`
+	LastName        string             `bson:"last_name" json:"last_name" validate:"required,min=2,max=32"`
+	Role            string             `bson:"role" json:"role"`
+	Department      string             `bson:"department,omitempty" json:"department,omitempty"`
+	StudentID       string             `bson:"student_id" json:"student_id"`
+	Email           string             `bson:"email" json:"email" validate:"email,required"`
+	PasswordHash    string             `bson:"password_hash" json:"-"`
+	IsActive        bool               `bson:"is_active" json:"is_active"`
+	Status          string             `bson:"status,omitempty" json:"status,omitempty"`
+	StatusReason    string             `bson:"status_reason,omitempty" json:"status_reason,omitempty"`
+	StatusChangedBy string             `bson:"status_changed_by,omitempty" json:"status_changed_by,omitempty"`
+	StatusChangedAt *time.Time         `bson:"status_changed_at,omitempty" json:"status_changed_at,omitempty"`
+	SuspendedUntil  *time.Time         `bson:"suspended_until,omitempty" json:"suspended_until,omitempty"`
+	LastLogin       *time.Time         `bson:"last_login,omitempty" json:"last_login,omitempty"`
+	// TokensValidAfter : tokens issued before it are revoked, set when the account is
+	// deactivated, deleted or its password changes
+	TokensValidAfter *time.Time           `bson:"tokens_valid_after,omitempty" json:"tokens_valid_after,omitempty"`
+	CreatedAt        time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time            `bson:"updated_at" json:"updated_at"`
+	RequiredExams    []primitive.ObjectID `bson:"required_exams,omitempty" json:"required_exams,omitempty"`
+	CompletedExams   []CompletedExam      `bson:"completed_exams,omitempty" json:"completed_exams,omitempty"`
+	Version          int64                `bson:"version" json:"version"`
+	DeletedAt        *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy        string               `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
 }
 
 type CompletedExam struct {
-	ExamID      primitive.ObjectID `bson:"exam_id" json:"exam_id"`
+	ExamID primitive.ObjectID `bson:"exam_id" json:"exam_id"`
+	// AttemptID : the graded attempt, missing on the entries written before it was recorded
+	AttemptID   primitive.ObjectID `bson:"attempt_id,omitempty" json:"attempt_id,omitempty"`
 	Score       float64            `bson:"score" json:"score"`
 	TotalMarks  float64            `bson:"total_marks" json:"total_marks"`
 	Passed      bool               `bson:"passed" json:"passed"`
 	CompletedAt time.Time          `bson:"completed_at" json:"completed_at"`
 }
+
+// AccountStatusChange : describes a status transition requested by an admin,
+// the change is refused when ExpectedVersion is set and the student has moved on
+type AccountStatusChange struct {
+	Status          string
+	Reason          string
+	ChangedBy       string
+	SuspendedUntil  *time.Time
+	ExpectedVersion *int64
+}
+
+// StudentProfileUpdate : profile fields a student may change, nil fields are left untouched,
+// the update is refused when ExpectedVersion is set and the student has moved on
+type StudentProfileUpdate struct {
+	FirstName       *string
+	LastName        *string
+	Department      *string
+	ExpectedVersion *int64
+}
+
+// StudentListFilter : filtering, sorting and paging options of a student listing, zero values are ignored
+type StudentListFilter struct {
+	Department   string
+	IsActive     *bool
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	RequiredExam *primitive.ObjectID
+	Search       string // whole words of the names, email or student ID
+	SortBy       string
+	SortDesc     bool
+	Limit        int64
+	Cursor       string
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+)
+
+// Audit entries are chained : every entry stores the hash of the previous one and its own hash
+// covers that link, so editing, removing or reordering a stored entry breaks every following hash.
+// Hashes are computed over the JSON of the entry as it reads back from the store, timestamps are
+// kept at the millisecond precision mongo stores.
+
+// auditHashInput : the hashed fields of an entry, in a fixed order
+type auditHashInput struct {
+	Seq        int64                `json:"seq"`
+	Timestamp  string               `json:"timestamp"`
+	ActorID    string               `json:"actor_id"`
+	ActorRole  string               `json:"actor_role"`
+	ActorEmail string               `json:"actor_email"`
+	Action     string               `json:"action"`
+	TargetType string               `json:"target_type"`
+	TargetID   string               `json:"target_id"`
+	Changes    []models.AuditChange `json:"changes"`
+	Metadata   map[string]string    `json:"metadata"`
+	IP         string               `json:"ip"`
+	RequestID  string               `json:"request_id"`
+	PrevHash   string               `json:"prev_hash"`
+}
+
+// sealAuditEntry : places the entry after the chain head and computes its hash
+func sealAuditEntry(entry *models.AuditEntry, prevSeq int64, prevHash string) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Millisecond)
+	entry.Seq = prevSeq + 1
+	entry.PrevHash = prevHash
+
+	hash, err := auditHash(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	return nil
+}
+
+// auditHash : hex sha256 of the hashed fields, empty changes and metadata hash like missing ones
+func auditHash(entry *models.AuditEntry) (string, error) {
+	input := auditHashInput{
+		Seq:        entry.Seq,
+		Timestamp:  entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		ActorID:    entry.ActorID,
+		ActorRole:  entry.ActorRole,
+		ActorEmail: entry.ActorEmail,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		IP:         entry.IP,
+		RequestID:  entry.RequestID,
+		PrevHash:   entry.PrevHash,
+	}
+	if len(entry.Changes) > 0 {
+		input.Changes = entry.Changes
+	}
+	if len(entry.Metadata) > 0 {
+		input.Metadata = entry.Metadata
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash audit entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// auditChainCheck : verifies entries one by one in Seq order
+type auditChainCheck struct {
+	result   models.AuditVerification
+	prevSeq  int64
+	prevHash string
+}
+
+// next : checks the entry against the previous one, returns false once the chain is broken
+func (c *auditChainCheck) next(entry *models.AuditEntry) bool {
+	fail := func(reason string) bool {
+		seq := entry.Seq
+		c.result.BrokenAt = &seq
+		c.result.Reason = reason
+		return false
+	}
+
+	c.result.Checked++
+	if entry.Seq != c.prevSeq+1 {
+		return fail(fmt.Sprintf("expected entry %d, found %d", c.prevSeq+1, entry.Seq))
+	}
+	if entry.PrevHash != c.prevHash {
+		return fail("previous hash does not match the previous entry")
+	}
+	hash, err := auditHash(entry)
+	if err != nil || hash != entry.Hash {
+		return fail("entry hash does not match its content")
+	}
+
+	c.prevSeq = entry.Seq
+	c.prevHash = entry.Hash
+	return true
+}
+
+// done : the verification result, valid when no entry broke the chain
+func (c *auditChainCheck) done() *models.AuditVerification {
+	c.result.Valid = c.result.BrokenAt == nil
+	return &c.result
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+)
+
+// sealedChain : n entries sealed one after the other
+func sealedChain(t *testing.T, n int) []*models.AuditEntry {
+	t.Helper()
+
+	var entries []*models.AuditEntry
+	var prevSeq int64
+	var prevHash string
+	for i := range n {
+		entry := &models.AuditEntry{
+			ActorID:    "A0001",
+			ActorRole:  "admin",
+			Action:     models.AuditStudentPassword,
+			TargetType: models.AuditTargetStudent,
+			TargetID:   "S0001",
+			Metadata:   map[string]string{"n": string(rune('a' + i))},
+		}
+		if err := sealAuditEntry(entry, prevSeq, prevHash); err != nil {
+			t.Fatalf("sealAuditEntry: %v", err)
+		}
+		prevSeq, prevHash = entry.Seq, entry.Hash
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// verifyChain : runs the chain check over the entries
+func verifyChain(entries []*models.AuditEntry) *models.AuditVerification {
+	var check auditChainCheck
+	for _, entry := range entries {
+		if !check.next(entry) {
+			break
+		}
+	}
+	return check.done()
+}
+
+func TestSealAuditEntry(t *testing.T) {
+	entry := &models.AuditEntry{Action: models.AuditStudentPassword, Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 678901234, time.FixedZone("X", 3600))}
+	if err := sealAuditEntry(entry, 41, "prev"); err != nil {
+		t.Fatalf("sealAuditEntry: %v", err)
+	}
+
+	if entry.Seq != 42 || entry.PrevHash != "prev" || len(entry.Hash) != 64 {
+		t.Fatalf("sealed entry = %+v", entry)
+	}
+	// stored like mongo reads it back
+	if entry.Timestamp.Location() != time.UTC || entry.Timestamp.Nanosecond() != 678000000 {
+		t.Fatalf("timestamp = %v, want UTC at millisecond precision", entry.Timestamp)
+	}
+
+	// empty changes and metadata hash like missing ones
+	empty := *entry
+	empty.Changes = []models.AuditChange{}
+	empty.Metadata = map[string]string{}
+	if hash, err := auditHash(&empty); err != nil || hash != entry.Hash {
+		t.Fatalf("hash with empty changes = %s, %v, want %s", hash, err, entry.Hash)
+	}
+}
+
+func TestAuditChainCheck(t *testing.T) {
+	t.Run("intact", func(t *testing.T) {
+		result := verifyChain(sealedChain(t, 5))
+		if !result.Valid || result.Checked != 5 || result.BrokenAt != nil {
+			t.Fatalf("verification = %+v", result)
+		}
+	})
+
+	t.Run("edited entry", func(t *testing.T) {
+		entries := sealedChain(t, 5)
+		entries[2].TargetID = "S0002"
+
+		result := verifyChain(entries)
+		if result.Valid || result.BrokenAt == nil || *result.BrokenAt != 3 || result.Checked != 3 {
+			t.Fatalf("verification = %+v", result)
+		}
+	})
+
+	t.Run("removed entry", func(t *testing.T) {
+		entries := sealedChain(t, 5)
+		entries = append(entries[:1], entries[2:]...)
+
+		result := verifyChain(entries)
+		if result.Valid || result.BrokenAt == nil || *result.BrokenAt != 3 {
+			t.Fatalf("verification = %+v", result)
+		}
+	})
+
+	t.Run("rehashed entry", func(t *testing.T) {
+		// recomputing the hash of an edited entry still breaks the link of the next one
+		entries := sealedChain(t, 5)
+		entries[1].ActorID = "A0002"
+		hash, err := auditHash(entries[1])
+		if err != nil {
+			t.Fatalf("auditHash: %v", err)
+		}
+		entries[1].Hash = hash
+
+		result := verifyChain(entries)
+		if result.Valid || result.BrokenAt == nil || *result.BrokenAt != 3 {
+			t.Fatalf("verification = %+v", result)
+		}
+	})
+}
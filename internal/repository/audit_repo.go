@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var maxAuditRecordAttempts = 5
+
+// ErrAuditContention : the chain head kept moving while recording an entry
+var ErrAuditContention = errors.New("audit log is busy, too many concurrent writers")
+
+// MongoAuditRepository : append-only audit log, the repository never updates or deletes an entry
+type MongoAuditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepo(database *mongo.Database) *MongoAuditRepository {
+	return &MongoAuditRepository{
+		collection: database.Collection("audit_log"),
+	}
+}
+
+// Record : appends the entry to the chain, concurrent writers race on the unique seq index
+// and the loser retries on the new head
+func (r *MongoAuditRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	if entry == nil {
+		return fmt.Errorf("nil audit entry is provided")
+	}
+
+	for range maxAuditRecordAttempts {
+		var head models.AuditEntry
+		err := r.collection.FindOne(ctx, bson.M{},
+			options.FindOne().
+				SetSort(bson.D{{Key: "seq", Value: -1}}).
+				SetProjection(bson.M{"seq": 1, "hash": 1}),
+		).Decode(&head)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("failed to read the audit chain head: %w", err)
+		}
+
+		if err := sealAuditEntry(entry, head.Seq, head.Hash); err != nil {
+			return err
+		}
+		entry.ID = primitive.NewObjectID()
+
+		_, err = r.collection.InsertOne(ctx, entry)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to record audit entry: %w", err)
+		}
+	}
+	return ErrAuditContention
+}
+
+// Search : returns the entries matching the filter, newest first
+func (r *MongoAuditRepository) Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query := auditQuery(filter)
+	if filter.BeforeSeq > 0 {
+		query["seq"] = bson.M{"$lt": filter.BeforeSeq}
+	}
+
+	cursor, err := r.collection.Find(ctx, query,
+		options.Find().
+			SetSort(bson.D{{Key: "seq", Value: -1}}).
+			SetLimit(historyLimit(filter.Limit)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search audit log: %w", err)
+	}
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// Export : calls fn with every entry matching the filter, oldest first, paging options are ignored
+func (r *MongoAuditRepository) Export(ctx context.Context, filter models.AuditFilter, fn func(entry *models.AuditEntry) error) error {
+	cursor, err := r.collection.Find(ctx, auditQuery(filter), options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to export audit log: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry models.AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return fmt.Errorf("failed to decode audit entry: %w", err)
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// Verify : walks the whole chain and reports the first entry that was tampered with
+func (r *MongoAuditRepository) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var check auditChainCheck
+	for cursor.Next(ctx) {
+		var entry models.AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry: %w", err)
+		}
+		if !check.next(&entry) {
+			break
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return check.done(), nil
+}
+
+// auditQuery : translates the filter to a mongo query
+func auditQuery(filter models.AuditFilter) bson.M {
+	query := bson.M{}
+
+	if filter.ActorID != "" {
+		query["actor_id"] = filter.ActorID
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.TargetType != "" {
+		query["target_type"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		query["target_id"] = filter.TargetID
+	}
+	if filter.RequestID != "" {
+		query["request_id"] = filter.RequestID
+	}
+	if filter.From != nil || filter.To != nil {
+		timestamp := bson.M{}
+		if filter.From != nil {
+			timestamp["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			timestamp["$lte"] = *filter.To
+		}
+		query["timestamp"] = timestamp
+	}
+	return query
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryAuditRepository : in-memory AuditRepository, entries are kept in Seq order
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []models.AuditEntry
+}
+
+func NewMemoryAuditRepo() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+func (r *MemoryAuditRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	if entry == nil {
+		return fmt.Errorf("nil audit entry is provided")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var prevSeq int64
+	var prevHash string
+	if n := len(r.entries); n > 0 {
+		prevSeq, prevHash = r.entries[n-1].Seq, r.entries[n-1].Hash
+	}
+	if err := sealAuditEntry(entry, prevSeq, prevHash); err != nil {
+		return err
+	}
+	entry.ID = primitive.NewObjectID()
+
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *MemoryAuditRepository) Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	limit := historyLimit(filter.Limit)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// newest first
+	matches := []models.AuditEntry{}
+	for i := len(r.entries) - 1; i >= 0 && int64(len(matches)) < limit; i-- {
+		entry := r.entries[i]
+		if filter.BeforeSeq > 0 && entry.Seq >= filter.BeforeSeq {
+			continue
+		}
+		if matchesAuditFilter(&entry, filter) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+func (r *MemoryAuditRepository) Export(ctx context.Context, filter models.AuditFilter, fn func(entry *models.AuditEntry) error) error {
+	r.mu.RLock()
+	entries := slices.Clone(r.entries)
+	r.mu.RUnlock()
+
+	for i := range entries {
+		if !matchesAuditFilter(&entries[i], filter) {
+			continue
+		}
+		if err := fn(&entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryAuditRepository) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var check auditChainCheck
+	for i := range r.entries {
+		if !check.next(&r.entries[i]) {
+			break
+		}
+	}
+	return check.done(), nil
+}
+
+// matchesAuditFilter : in-memory version of auditQuery
+func matchesAuditFilter(entry *models.AuditEntry, filter models.AuditFilter) bool {
+	switch {
+	case filter.ActorID != "" && entry.ActorID != filter.ActorID,
+		filter.Action != "" && entry.Action != filter.Action,
+		filter.TargetType != "" && entry.TargetType != filter.TargetType,
+		filter.TargetID != "" && entry.TargetID != filter.TargetID,
+		filter.RequestID != "" && entry.RequestID != filter.RequestID,
+		filter.From != nil && entry.Timestamp.Before(*filter.From),
+		filter.To != nil && entry.Timestamp.After(*filter.To):
+		return false
+	}
+	return true
+}
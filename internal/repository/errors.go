@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// Define common errors
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidPassword    = errors.New("invalid new password")
+	ErrAccountInactive    = errors.New("account is not active")
+	ErrInvalidExam        = errors.New("invalid exam")
+	ErrInvalidAnswers     = errors.New("invalid answers")
+	ErrNotEnrolled        = errors.New("student is not enrolled in the exam")
+	ErrAttemptSubmitted   = errors.New("attempt is already submitted")
+	ErrAttemptInProgress  = errors.New("an attempt of the exam is already in progress")
+	ErrVersionConflict    = errors.New("document was modified by someone else")
+	ErrNotFound           = errors.New("not found")
+	ErrSearchUnavailable  = errors.New("student search needs the text index of migration 13, run the migrations")
+)
+
+// NotFoundError : a document that does not exist or is in the trash, it matches ErrNotFound and,
+// for callers written against the driver, mongo.ErrNoDocuments
+type NotFoundError struct {
+	Resource string
+	Key      string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Resource, e.Key)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound || target == mongo.ErrNoDocuments
+}
+
+// notFound : the NotFoundError of a missing document, any other error is returned as is
+func notFound(err error, resource, key string) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &NotFoundError{Resource: resource, Key: key}
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkQuestions : every question needs at least two options, a valid answer and positive marks
+func checkQuestions(questions []models.Question) error {
+	if len(questions) == 0 {
+		return fmt.Errorf("%w: at least one question is required", ErrInvalidExam)
+	}
+	for i, question := range questions {
+		if len(question.Options) < 2 {
+			return fmt.Errorf("%w: question %d needs at least two options", ErrInvalidExam, i+1)
+		}
+		if question.Answer < 0 || question.Answer >= len(question.Options) {
+			return fmt.Errorf("%w: answer of question %d is not one of its options", ErrInvalidExam, i+1)
+		}
+		if question.Marks <= 0 {
+			return fmt.Errorf("%w: marks of question %d must be positive", ErrInvalidExam, i+1)
+		}
+	}
+	return nil
+}
+
+// prepareExam : validates a new exam and fills its server-side fields
+func prepareExam(exam *models.Exam) error {
+	if err := checkQuestions(exam.Questions); err != nil {
+		return err
+	}
+	if exam.PassMark < 0 || exam.PassMark > examTotalMarks(exam) {
+		return fmt.Errorf("%w: pass mark must be between 0 and the total marks", ErrInvalidExam)
+	}
+
+	timeNow := time.Now()
+
+	exam.ID = primitive.NewObjectID()
+	exam.CreatedAt = timeNow
+	exam.UpdatedAt = timeNow
+	exam.EnrolledStudents = []string{}
+
+	return nil
+}
+
+// checkAnswers : there must be one answer per question, -1 marks a skipped question
+func checkAnswers(exam *models.Exam, answers []int) error {
+	if len(answers) != len(exam.Questions) {
+		return fmt.Errorf("%w: got %d answers for %d questions", ErrInvalidAnswers, len(answers), len(exam.Questions))
+	}
+	for i, answer := range answers {
+		if answer < -1 || answer >= len(exam.Questions[i].Options) {
+			return fmt.Errorf("%w: answer %d is not one of the options", ErrInvalidAnswers, i+1)
+		}
+	}
+	return nil
+}
+
+// examTotalMarks : sum of the marks of every question
+func examTotalMarks(exam *models.Exam) float64 {
+	total := 0.0
+	for _, question := range exam.Questions {
+		total += question.Marks
+	}
+	return total
+}
+
+// gradeAttempt : scores the answers of the attempt against the exam
+func gradeAttempt(exam *models.Exam, attempt *models.ExamAttempt, gradedAt time.Time) {
+	score := 0.0
+	for i, question := range exam.Questions {
+		if i < len(attempt.Answers) && attempt.Answers[i] == question.Answer {
+			score += question.Marks
+		}
+	}
+
+	attempt.Score = score
+	attempt.TotalMarks = examTotalMarks(exam)
+	attempt.Passed = score >= exam.PassMark
+	attempt.GradedAt = &gradedAt
+}
+
+// completedExam : entry stored in Student.CompletedExams for a submitted attempt
+func completedExam(attempt *models.ExamAttempt) models.CompletedExam {
+	completedAt := attempt.StartedAt
+	if attempt.SubmittedAt != nil {
+		completedAt = *attempt.SubmittedAt
+	}
+	return models.CompletedExam{
+		ExamID:      attempt.ExamID,
+		AttemptID:   attempt.ID,
+		Score:       attempt.Score,
+		TotalMarks:  attempt.TotalMarks,
+		Passed:      attempt.Passed,
+		CompletedAt: completedAt,
+	}
+}
+
+// completedEntryOf : whether the completed exam entry records the attempt, the entries written
+// before the attempt ID was recorded are matched on the exam and the submission time
+func completedEntryOf(entry *models.CompletedExam, attempt *models.ExamAttempt) bool {
+	if !entry.AttemptID.IsZero() {
+		return entry.AttemptID == attempt.ID
+	}
+	return entry.ExamID == attempt.ExamID && attempt.SubmittedAt != nil && entry.CompletedAt.Equal(*attempt.SubmittedAt)
+}
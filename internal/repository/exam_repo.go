@@ -0,0 +1,598 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache"
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/mongodb"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoExamRepository : exams and attempts, every change that also touches students runs
+// in a transaction so an attempt and the exam lists of the student never disagree
+type MongoExamRepository struct {
+	exams    *mongo.Collection
+	attempts *mongo.Collection
+	students *mongo.Collection
+	uow      *mongodb.UnitOfWork
+	files    FileRepository
+	cache    cache.Store
+}
+
+func NewExamRepo(database *mongo.Database, c cache.Store, files FileRepository) *MongoExamRepository {
+	return &MongoExamRepository{
+		exams:    database.Collection("exams"),
+		attempts: database.Collection("exam_attempts"),
+		students: database.Collection("students"),
+		uow:      mongodb.NewUnitOfWork(database.Client()),
+		files:    files,
+		cache:    c,
+	}
+}
+
+func (r *MongoExamRepository) CreateExam(ctx context.Context, exam *models.Exam) error {
+	if exam == nil {
+		return fmt.Errorf("nil exam is provided")
+	}
+
+	if err := prepareExam(exam); err != nil {
+		return err
+	}
+
+	if _, err := r.exams.InsertOne(ctx, exam); err != nil {
+		return fmt.Errorf("failed to create exam: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoExamRepository) GetExam(ctx context.Context, examID primitive.ObjectID) (*models.Exam, error) {
+	var exam models.Exam
+	if err := r.exams.FindOne(ctx, notDeleted(bson.M{"_id": examID})).Decode(&exam); err != nil {
+		return nil, fmt.Errorf("failed to get exam: %w", notFound(err, "exam", examID.Hex()))
+	}
+	return &exam, nil
+}
+
+// GetAttempt : returns an attempt of any student
+func (r *MongoExamRepository) GetAttempt(ctx context.Context, attemptID primitive.ObjectID) (*models.ExamAttempt, error) {
+	var attempt models.ExamAttempt
+	if err := r.attempts.FindOne(ctx, bson.M{"_id": attemptID}).Decode(&attempt); err != nil {
+		return nil, fmt.Errorf("failed to get attempt: %w", notFound(err, "attempt", attemptID.Hex()))
+	}
+	return &attempt, nil
+}
+
+// StartAttempt : opens an attempt for an enrolled student, an attempt that is still in progress is
+// returned as is; the partial unique index on in progress attempts turns a concurrent start into
+// ErrAttemptInProgress
+func (r *MongoExamRepository) StartAttempt(ctx context.Context, examID primitive.ObjectID, studentID string) (*models.ExamAttempt, error) {
+	var attempt models.ExamAttempt
+
+	err := r.uow.Do(ctx, func(sessCtx mongo.SessionContext) error {
+		attempt = models.ExamAttempt{}
+
+		if err := r.exams.FindOne(sessCtx, notDeleted(bson.M{"_id": examID})).Err(); err != nil {
+			return fmt.Errorf("failed to get exam: %w", notFound(err, "exam", examID.Hex()))
+		}
+
+		err := r.students.FindOne(sessCtx, notDeleted(bson.M{"student_id": studentID, "required_exams": examID})).Err()
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotEnrolled
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check enrollment: %w", err)
+		}
+
+		err = r.attempts.FindOne(sessCtx, bson.M{
+			"exam_id":    examID,
+			"student_id": studentID,
+			"status":     models.AttemptInProgress,
+		}).Decode(&attempt)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("failed to look up attempts: %w", err)
+		}
+
+		attempt = models.ExamAttempt{
+			ID:        primitive.NewObjectID(),
+			ExamID:    examID,
+			StudentID: studentID,
+			Status:    models.AttemptInProgress,
+			StartedAt: time.Now(),
+		}
+		if _, err := r.attempts.InsertOne(sessCtx, &attempt); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return ErrAttemptInProgress
+			}
+			return fmt.Errorf("failed to start attempt: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// SubmitAttempt : grades the attempt and, in the same transaction, moves the exam from the
+// required exams of the student to the completed ones
+func (r *MongoExamRepository) SubmitAttempt(ctx context.Context, attemptID primitive.ObjectID, studentID string, answers []int) (*models.ExamAttempt, error) {
+	var attempt models.ExamAttempt
+	var student models.Student
+
+	err := r.uow.Do(ctx, func(sessCtx mongo.SessionContext) error {
+		attempt, student = models.ExamAttempt{}, models.Student{}
+
+		if err := r.attempts.FindOne(sessCtx, bson.M{"_id": attemptID, "student_id": studentID}).Decode(&attempt); err != nil {
+			return fmt.Errorf("failed to get attempt: %w", notFound(err, "attempt", attemptID.Hex()))
+		}
+		if attempt.Status != models.AttemptInProgress {
+			return ErrAttemptSubmitted
+		}
+
+		var exam models.Exam
+		if err := r.exams.FindOne(sessCtx, notDeleted(bson.M{"_id": attempt.ExamID})).Decode(&exam); err != nil {
+			return fmt.Errorf("failed to get exam: %w", notFound(err, "exam", attempt.ExamID.Hex()))
+		}
+		if err := checkAnswers(&exam, answers); err != nil {
+			return err
+		}
+
+		timeNow := time.Now()
+		attempt.Answers = answers
+		attempt.Status = models.AttemptSubmitted
+		attempt.SubmittedAt = &timeNow
+		gradeAttempt(&exam, &attempt, timeNow)
+
+		result, err := r.attempts.UpdateOne(sessCtx,
+			bson.M{"_id": attempt.ID, "status": models.AttemptInProgress},
+			bson.M{"$set": bson.M{
+				"answers":      attempt.Answers,
+				"status":       attempt.Status,
+				"score":        attempt.Score,
+				"total_marks":  attempt.TotalMarks,
+				"passed":       attempt.Passed,
+				"submitted_at": attempt.SubmittedAt,
+				"graded_at":    attempt.GradedAt,
+			}},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update attempt: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			return ErrAttemptSubmitted
+		}
+
+		err = r.students.FindOneAndUpdate(sessCtx,
+			notDeleted(bson.M{"student_id": studentID, "required_exams": attempt.ExamID}),
+			versioned(bson.M{
+				"$pull": bson.M{"required_exams": attempt.ExamID},
+				"$push": bson.M{"completed_exams": completedExam(&attempt)},
+				"$set":  bson.M{"updated_at": timeNow},
+			}),
+			options.FindOneAndUpdate().SetProjection(bson.M{"student_id": 1, "email": 1}),
+		).Decode(&student)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrNotEnrolled
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update student exams: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invalidateCachedStudents(r.cache, &student)
+
+	return &attempt, nil
+}
+
+// updateExamAttempts : tries of an edit of the pass mark made without an expected version,
+// the exam is read again when another edit lands between the read and the update
+const updateExamAttempts = 3
+
+// UpdateExam : edits the details of an exam, the pass mark is checked against the
+// questions of the version that is being updated
+func (r *MongoExamRepository) UpdateExam(ctx context.Context, examID primitive.ObjectID, update models.ExamUpdate) (*models.Exam, error) {
+	for attempt := 1; ; attempt++ {
+		updated, err := r.updateExam(ctx, examID, update)
+		if errors.Is(err, errExamChanged) {
+			if attempt < updateExamAttempts {
+				continue
+			}
+			return nil, ErrVersionConflict
+		}
+		return updated, err
+	}
+}
+
+// errExamChanged : the exam read to check the pass mark was edited before the update landed
+var errExamChanged = errors.New("exam changed since it was read")
+
+// updateExam : one read and update of UpdateExam. The update only matches the version that was
+// read when the client asked for it or when the pass mark has to be checked against the questions
+func (r *MongoExamRepository) updateExam(ctx context.Context, examID primitive.ObjectID, update models.ExamUpdate) (*models.Exam, error) {
+	exam, err := r.GetExam(ctx, examID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(exam.Version, update.ExpectedVersion); err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if update.Title != nil {
+		set["title"] = *update.Title
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.Department != nil {
+		set["department"] = *update.Department
+	}
+	if update.PassMark != nil {
+		if *update.PassMark < 0 || *update.PassMark > examTotalMarks(exam) {
+			return nil, fmt.Errorf("%w: pass mark must be between 0 and the total marks", ErrInvalidExam)
+		}
+		set["pass_mark"] = *update.PassMark
+	}
+
+	filter := notDeleted(bson.M{"_id": examID})
+	if update.ExpectedVersion != nil || update.PassMark != nil {
+		filter["version"] = exam.Version
+	}
+
+	var updated models.Exam
+	err = r.exams.FindOneAndUpdate(ctx,
+		filter,
+		versioned(bson.M{"$set": set}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		switch {
+		case update.ExpectedVersion != nil:
+			return nil, ErrVersionConflict
+		case update.PassMark != nil:
+			return nil, errExamChanged
+		}
+		return nil, fmt.Errorf("failed to update exam: %w", notFound(err, "exam", examID.Hex()))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update exam: %w", err)
+	}
+	return &updated, nil
+}
+
+// RegradeExam : replaces the answer key, marks and pass mark of the exam and regrades every
+// submitted attempt together with the completed exam entries of the students, returns the
+// number of regraded attempts
+func (r *MongoExamRepository) RegradeExam(ctx context.Context, examID primitive.ObjectID, regrade models.ExamRegrade) (int, error) {
+	var students []*models.Student
+	regraded := 0
+
+	err := r.uow.Do(ctx, func(sessCtx mongo.SessionContext) error {
+		students, regraded = nil, 0
+
+		var exam models.Exam
+		if err := r.exams.FindOne(sessCtx, notDeleted(bson.M{"_id": examID})).Decode(&exam); err != nil {
+			return fmt.Errorf("failed to get exam: %w", notFound(err, "exam", examID.Hex()))
+		}
+		if err := checkVersion(exam.Version, regrade.ExpectedVersion); err != nil {
+			return err
+		}
+		if len(regrade.Questions) != len(exam.Questions) {
+			return fmt.Errorf("%w: regrading must keep the %d questions of the exam", ErrInvalidExam, len(exam.Questions))
+		}
+		if err := checkQuestions(regrade.Questions); err != nil {
+			return err
+		}
+
+		exam.Questions = regrade.Questions
+		exam.PassMark = regrade.PassMark
+		if exam.PassMark < 0 || exam.PassMark > examTotalMarks(&exam) {
+			return fmt.Errorf("%w: pass mark must be between 0 and the total marks", ErrInvalidExam)
+		}
+
+		timeNow := time.Now()
+		result, err := r.exams.UpdateOne(sessCtx,
+			notDeleted(bson.M{"_id": examID, "version": exam.Version}),
+			versioned(bson.M{
+				"$set": bson.M{"questions": exam.Questions, "pass_mark": exam.PassMark, "updated_at": timeNow},
+			}),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update exam: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			return ErrVersionConflict
+		}
+
+		cursor, err := r.attempts.Find(sessCtx, bson.M{"exam_id": examID, "status": models.AttemptSubmitted})
+		if err != nil {
+			return fmt.Errorf("failed to list attempts: %w", err)
+		}
+		var attempts []models.ExamAttempt
+		if err := cursor.All(sessCtx, &attempts); err != nil {
+			return fmt.Errorf("failed to decode attempts: %w", err)
+		}
+
+		studentIDs := make([]string, 0, len(attempts))
+		for i := range attempts {
+			attempt := &attempts[i]
+			gradeAttempt(&exam, attempt, timeNow)
+
+			if _, err := r.attempts.UpdateOne(sessCtx,
+				bson.M{"_id": attempt.ID},
+				bson.M{"$set": bson.M{
+					"score":       attempt.Score,
+					"total_marks": attempt.TotalMarks,
+					"passed":      attempt.Passed,
+					"graded_at":   attempt.GradedAt,
+				}},
+			); err != nil {
+				return fmt.Errorf("failed to update attempt: %w", err)
+			}
+
+			// a student can have completed the exam more than once, only the entry of this
+			// attempt gets its grade
+			if _, err := r.students.UpdateOne(sessCtx,
+				bson.M{"student_id": attempt.StudentID, "completed_exams.exam_id": examID},
+				versioned(bson.M{"$set": bson.M{
+					"completed_exams.$[entry].score":       attempt.Score,
+					"completed_exams.$[entry].total_marks": attempt.TotalMarks,
+					"completed_exams.$[entry].passed":      attempt.Passed,
+					"updated_at":                           timeNow,
+				}}),
+				options.Update().SetArrayFilters(options.ArrayFilters{Filters: []any{completedEntryFilter(attempt)}}),
+			); err != nil {
+				return fmt.Errorf("failed to update completed exam of %s: %w", attempt.StudentID, err)
+			}
+			studentIDs = append(studentIDs, attempt.StudentID)
+		}
+
+		students, err = r.findStudentKeys(sessCtx, studentIDs)
+		if err != nil {
+			return err
+		}
+		regraded = len(attempts)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	invalidateCachedStudents(r.cache, students...)
+
+	return regraded, nil
+}
+
+// completedEntryFilter : array filter "entry" of the completed exam entry of the attempt, see
+// completedEntryOf
+func completedEntryFilter(attempt *models.ExamAttempt) bson.M {
+	legacy := bson.M{
+		"entry.attempt_id": bson.M{"$exists": false},
+		"entry.exam_id":    attempt.ExamID,
+	}
+	if attempt.SubmittedAt != nil {
+		legacy["entry.completed_at"] = *attempt.SubmittedAt
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"entry.attempt_id": attempt.ID},
+		legacy,
+	}}
+}
+
+// EnrollStudents : adds the exam to the required exams of every student and the students to the
+// exam roster, nothing is changed when one of the students does not exist
+func (r *MongoExamRepository) EnrollStudents(ctx context.Context, examID primitive.ObjectID, studentIDs []string) error {
+	return r.changeEnrollment(ctx, examID, studentIDs, "$addToSet")
+}
+
+// UnenrollStudents : reverse of EnrollStudents, completed exams are kept
+func (r *MongoExamRepository) UnenrollStudents(ctx context.Context, examID primitive.ObjectID, studentIDs []string) error {
+	return r.changeEnrollment(ctx, examID, studentIDs, "$pull")
+}
+
+// changeEnrollment : applies the update operator to both sides of the enrollment in one transaction
+func (r *MongoExamRepository) changeEnrollment(ctx context.Context, examID primitive.ObjectID, studentIDs []string, operator string) error {
+	if len(studentIDs) == 0 {
+		return nil
+	}
+	studentIDs = slices.Compact(slices.Sorted(slices.Values(studentIDs)))
+
+	var students []*models.Student
+
+	err := r.uow.Do(ctx, func(sessCtx mongo.SessionContext) error {
+		var err error
+		students, err = r.findStudentKeys(sessCtx, studentIDs)
+		if err != nil {
+			return err
+		}
+		if missing := missingStudent(students, studentIDs); missing != "" {
+			return fmt.Errorf("some of the students do not exist: %w", &NotFoundError{Resource: "student", Key: missing})
+		}
+
+		timeNow := time.Now()
+
+		var roster bson.M
+		if operator == "$pull" {
+			roster = bson.M{"enrolled_students": bson.M{"$in": studentIDs}}
+		} else {
+			roster = bson.M{"enrolled_students": bson.M{"$each": studentIDs}}
+		}
+		result, err := r.exams.UpdateOne(sessCtx,
+			notDeleted(bson.M{"_id": examID}),
+			versioned(bson.M{operator: roster, "$set": bson.M{"updated_at": timeNow}}),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update exam roster: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			return &NotFoundError{Resource: "exam", Key: examID.Hex()}
+		}
+
+		if _, err := r.students.UpdateMany(sessCtx,
+			notDeleted(bson.M{"student_id": bson.M{"$in": studentIDs}}),
+			versioned(bson.M{operator: bson.M{"required_exams": examID}, "$set": bson.M{"updated_at": timeNow}}),
+		); err != nil {
+			return fmt.Errorf("failed to update required exams: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	invalidateCachedStudents(r.cache, students...)
+
+	return nil
+}
+
+// findStudentKeys : student_id and email of the given students, used to invalidate their cache entries
+func (r *MongoExamRepository) findStudentKeys(ctx context.Context, studentIDs []string) ([]*models.Student, error) {
+	if len(studentIDs) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.students.Find(ctx,
+		notDeleted(bson.M{"student_id": bson.M{"$in": studentIDs}}),
+		options.Find().SetProjection(bson.M{"student_id": 1, "email": 1}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up students: %w", err)
+	}
+
+	var students []*models.Student
+	if err := cursor.All(ctx, &students); err != nil {
+		return nil, fmt.Errorf("failed to decode students: %w", err)
+	}
+	return students, nil
+}
+
+// missingStudent : the first of the student IDs that was not found, empty when all were
+func missingStudent(students []*models.Student, studentIDs []string) string {
+	found := make(map[string]bool, len(students))
+	for _, student := range students {
+		found[student.StudentID] = true
+	}
+	for _, studentID := range studentIDs {
+		if !found[studentID] {
+			return studentID
+		}
+	}
+	return ""
+}
+
+// SoftDeleteExam : moves the exam to the trash, its attempts and the grades of the students are kept
+func (r *MongoExamRepository) SoftDeleteExam(ctx context.Context, examID primitive.ObjectID, deletedBy string) error {
+	timeNow := time.Now()
+
+	result, err := r.exams.UpdateOne(ctx,
+		notDeleted(bson.M{"_id": examID}),
+		versioned(bson.M{
+			"$set": bson.M{"deleted_at": timeNow, "deleted_by": deletedBy, "updated_at": timeNow},
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete exam: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "exam", Key: examID.Hex()}
+	}
+	return nil
+}
+
+// RestoreExam : takes the exam out of the trash
+func (r *MongoExamRepository) RestoreExam(ctx context.Context, examID primitive.ObjectID) (*models.Exam, error) {
+	var exam models.Exam
+	err := r.exams.FindOneAndUpdate(ctx,
+		inTrash(bson.M{"_id": examID}),
+		versioned(bson.M{
+			"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&exam)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore exam: %w", notFound(err, "deleted exam", examID.Hex()))
+	}
+	return &exam, nil
+}
+
+// ListDeletedExams : exams in the trash, most recently deleted first
+func (r *MongoExamRepository) ListDeletedExams(ctx context.Context, limit int64) ([]models.Exam, error) {
+	results, err := r.exams.Find(ctx,
+		inTrash(bson.M{}),
+		options.Find().
+			SetSort(bson.D{{Key: "deleted_at", Value: -1}}).
+			SetLimit(trashLimit(limit)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted exams: %w", err)
+	}
+
+	exams := []models.Exam{}
+	if err := results.All(ctx, &exams); err != nil {
+		return nil, fmt.Errorf("failed to decode exams: %w", err)
+	}
+	return exams, nil
+}
+
+// PurgeDeletedExams : hard deletes the exams that were moved to the trash before the given time
+// together with their attempts and files and removes them from the required exams of the
+// students, in one transaction. The completed exam entries of the students are kept. Returns
+// the number of purged exams
+func (r *MongoExamRepository) PurgeDeletedExams(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	filter := bson.M{"deleted_at": bson.M{"$lt": deletedBefore}}
+
+	var purged int64
+	err := r.uow.Do(ctx, func(sessCtx mongo.SessionContext) error {
+		examIDs, err := r.exams.Distinct(sessCtx, "_id", filter)
+		if err != nil {
+			return fmt.Errorf("failed to find exams to purge: %w", err)
+		}
+		if len(examIDs) == 0 {
+			purged = 0
+			return nil
+		}
+
+		// the attempt uploads carry the exam ID too
+		if _, err := r.files.DeleteExamFiles(sessCtx, objectIDs(examIDs)); err != nil {
+			return fmt.Errorf("failed to purge exam files: %w", err)
+		}
+
+		if _, err := r.attempts.DeleteMany(sessCtx, bson.M{"exam_id": bson.M{"$in": examIDs}}); err != nil {
+			return fmt.Errorf("failed to purge exam attempts: %w", err)
+		}
+
+		if _, err := r.students.UpdateMany(sessCtx,
+			bson.M{"required_exams": bson.M{"$in": examIDs}},
+			versioned(bson.M{"$pull": bson.M{"required_exams": bson.M{"$in": examIDs}}}),
+		); err != nil {
+			return fmt.Errorf("failed to remove purged exams from students: %w", err)
+		}
+
+		result, err := r.exams.DeleteMany(sessCtx, bson.M{"_id": bson.M{"$in": examIDs}, "deleted_at": bson.M{"$lt": deletedBefore}})
+		if err != nil {
+			return fmt.Errorf("failed to purge exams: %w", err)
+		}
+		purged = result.DeletedCount
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,401 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryExamRepository : in-memory ExamRepository working on the students of a MemoryStudentRepository,
+// changes that touch both are made while holding both locks so they are atomic like the mongo transactions
+type MemoryExamRepository struct {
+	mu       sync.Mutex
+	exams    map[primitive.ObjectID]*models.Exam
+	attempts map[primitive.ObjectID]*models.ExamAttempt
+	students *MemoryStudentRepository
+}
+
+func NewMemoryExamRepo(students *MemoryStudentRepository) *MemoryExamRepository {
+	return &MemoryExamRepository{
+		exams:    map[primitive.ObjectID]*models.Exam{},
+		attempts: map[primitive.ObjectID]*models.ExamAttempt{},
+		students: students,
+	}
+}
+
+// copyExam : returns a copy that does not share slices with the stored exam
+func copyExam(exam *models.Exam) *models.Exam {
+	clone := *exam
+	clone.Questions = slices.Clone(exam.Questions)
+	for i := range clone.Questions {
+		clone.Questions[i].Options = slices.Clone(exam.Questions[i].Options)
+	}
+	clone.EnrolledStudents = slices.Clone(exam.EnrolledStudents)
+	return &clone
+}
+
+// copyAttempt : returns a copy that does not share slices with the stored attempt
+func copyAttempt(attempt *models.ExamAttempt) *models.ExamAttempt {
+	clone := *attempt
+	clone.Answers = slices.Clone(attempt.Answers)
+	return &clone
+}
+
+// liveExam : the stored exam unless it is missing or in the trash, the caller holds the lock
+func (r *MemoryExamRepository) liveExam(examID primitive.ObjectID) (*models.Exam, bool) {
+	exam, ok := r.exams[examID]
+	if !ok || exam.DeletedAt != nil {
+		return nil, false
+	}
+	return exam, true
+}
+
+func (r *MemoryExamRepository) CreateExam(ctx context.Context, exam *models.Exam) error {
+	if exam == nil {
+		return fmt.Errorf("nil exam is provided")
+	}
+
+	if err := prepareExam(exam); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.exams[exam.ID] = copyExam(exam)
+	return nil
+}
+
+func (r *MemoryExamRepository) GetExam(ctx context.Context, examID primitive.ObjectID) (*models.Exam, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exam, ok := r.liveExam(examID)
+	if !ok {
+		return nil, fmt.Errorf("failed to get exam: %w", &NotFoundError{Resource: "exam", Key: examID.Hex()})
+	}
+	return copyExam(exam), nil
+}
+
+func (r *MemoryExamRepository) GetAttempt(ctx context.Context, attemptID primitive.ObjectID) (*models.ExamAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[attemptID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get attempt: %w", &NotFoundError{Resource: "attempt", Key: attemptID.Hex()})
+	}
+	return copyAttempt(attempt), nil
+}
+
+func (r *MemoryExamRepository) StartAttempt(ctx context.Context, examID primitive.ObjectID, studentID string) (*models.ExamAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.liveExam(examID); !ok {
+		return nil, fmt.Errorf("failed to get exam: %w", &NotFoundError{Resource: "exam", Key: examID.Hex()})
+	}
+
+	r.students.mu.RLock()
+	student, ok := r.students.students[studentID]
+	enrolled := ok && student.DeletedAt == nil && slices.Contains(student.RequiredExams, examID)
+	r.students.mu.RUnlock()
+	if !enrolled {
+		return nil, ErrNotEnrolled
+	}
+
+	for _, attempt := range r.attempts {
+		if attempt.ExamID == examID && attempt.StudentID == studentID && attempt.Status == models.AttemptInProgress {
+			return copyAttempt(attempt), nil
+		}
+	}
+
+	attempt := &models.ExamAttempt{
+		ID:        primitive.NewObjectID(),
+		ExamID:    examID,
+		StudentID: studentID,
+		Status:    models.AttemptInProgress,
+		StartedAt: time.Now(),
+	}
+	r.attempts[attempt.ID] = attempt
+	return copyAttempt(attempt), nil
+}
+
+func (r *MemoryExamRepository) SubmitAttempt(ctx context.Context, attemptID primitive.ObjectID, studentID string, answers []int) (*models.ExamAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.students.mu.Lock()
+	defer r.students.mu.Unlock()
+
+	stored, ok := r.attempts[attemptID]
+	if !ok || stored.StudentID != studentID {
+		return nil, fmt.Errorf("failed to get attempt: %w", &NotFoundError{Resource: "attempt", Key: attemptID.Hex()})
+	}
+	if stored.Status != models.AttemptInProgress {
+		return nil, ErrAttemptSubmitted
+	}
+
+	exam, ok := r.liveExam(stored.ExamID)
+	if !ok {
+		return nil, fmt.Errorf("failed to get exam: %w", &NotFoundError{Resource: "exam", Key: stored.ExamID.Hex()})
+	}
+	if err := checkAnswers(exam, answers); err != nil {
+		return nil, err
+	}
+
+	student, ok := r.students.students[studentID]
+	if !ok || student.DeletedAt != nil || !slices.Contains(student.RequiredExams, stored.ExamID) {
+		return nil, ErrNotEnrolled
+	}
+
+	// every check passed, nothing below can fail
+	timeNow := time.Now()
+	attempt := copyAttempt(stored)
+	attempt.Answers = slices.Clone(answers)
+	attempt.Status = models.AttemptSubmitted
+	attempt.SubmittedAt = &timeNow
+	gradeAttempt(exam, attempt, timeNow)
+
+	r.attempts[attempt.ID] = attempt
+	student.RequiredExams = slices.DeleteFunc(student.RequiredExams, func(id primitive.ObjectID) bool { return id == attempt.ExamID })
+	student.CompletedExams = append(student.CompletedExams, completedExam(attempt))
+	student.UpdatedAt = timeNow
+	student.Version++
+
+	return copyAttempt(attempt), nil
+}
+
+func (r *MemoryExamRepository) UpdateExam(ctx context.Context, examID primitive.ObjectID, update models.ExamUpdate) (*models.Exam, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.liveExam(examID)
+	if !ok {
+		return nil, fmt.Errorf("failed to get exam: %w", &NotFoundError{Resource: "exam", Key: examID.Hex()})
+	}
+	if err := checkVersion(stored.Version, update.ExpectedVersion); err != nil {
+		return nil, err
+	}
+
+	exam := copyExam(stored)
+	if update.Title != nil {
+		exam.Title = *update.Title
+	}
+	if update.Description != nil {
+		exam.Description = *update.Description
+	}
+	if update.Department != nil {
+		exam.Department = *update.Department
+	}
+	if update.PassMark != nil {
+		if *update.PassMark < 0 || *update.PassMark > examTotalMarks(exam) {
+			return nil, fmt.Errorf("%w: pass mark must be between 0 and the total marks", ErrInvalidExam)
+		}
+		exam.PassMark = *update.PassMark
+	}
+	exam.UpdatedAt = time.Now()
+	exam.Version++
+
+	r.exams[examID] = exam
+	return copyExam(exam), nil
+}
+
+func (r *MemoryExamRepository) RegradeExam(ctx context.Context, examID primitive.ObjectID, regrade models.ExamRegrade) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.students.mu.Lock()
+	defer r.students.mu.Unlock()
+
+	stored, ok := r.liveExam(examID)
+	if !ok {
+		return 0, fmt.Errorf("failed to get exam: %w", &NotFoundError{Resource: "exam", Key: examID.Hex()})
+	}
+	if err := checkVersion(stored.Version, regrade.ExpectedVersion); err != nil {
+		return 0, err
+	}
+	if len(regrade.Questions) != len(stored.Questions) {
+		return 0, fmt.Errorf("%w: regrading must keep the %d questions of the exam", ErrInvalidExam, len(stored.Questions))
+	}
+	if err := checkQuestions(regrade.Questions); err != nil {
+		return 0, err
+	}
+
+	exam := copyExam(stored)
+	exam.Questions = slices.Clone(regrade.Questions)
+	exam.PassMark = regrade.PassMark
+	if exam.PassMark < 0 || exam.PassMark > examTotalMarks(exam) {
+		return 0, fmt.Errorf("%w: pass mark must be between 0 and the total marks", ErrInvalidExam)
+	}
+
+	timeNow := time.Now()
+	exam.UpdatedAt = timeNow
+	exam.Version++
+	r.exams[examID] = exam
+
+	regraded := 0
+	for _, attempt := range r.attempts {
+		if attempt.ExamID != examID || attempt.Status != models.AttemptSubmitted {
+			continue
+		}
+		gradeAttempt(exam, attempt, timeNow)
+		regraded++
+
+		student, ok := r.students.students[attempt.StudentID]
+		if !ok {
+			continue
+		}
+		if !slices.ContainsFunc(student.CompletedExams, func(entry models.CompletedExam) bool { return entry.ExamID == examID }) {
+			continue
+		}
+		for i := range student.CompletedExams {
+			if completedEntryOf(&student.CompletedExams[i], attempt) {
+				student.CompletedExams[i].Score = attempt.Score
+				student.CompletedExams[i].TotalMarks = attempt.TotalMarks
+				student.CompletedExams[i].Passed = attempt.Passed
+			}
+		}
+		student.UpdatedAt = timeNow
+		student.Version++
+	}
+
+	return regraded, nil
+}
+
+func (r *MemoryExamRepository) EnrollStudents(ctx context.Context, examID primitive.ObjectID, studentIDs []string) error {
+	return r.changeEnrollment(examID, studentIDs, true)
+}
+
+func (r *MemoryExamRepository) UnenrollStudents(ctx context.Context, examID primitive.ObjectID, studentIDs []string) error {
+	return r.changeEnrollment(examID, studentIDs, false)
+}
+
+func (r *MemoryExamRepository) changeEnrollment(examID primitive.ObjectID, studentIDs []string, enroll bool) error {
+	if len(studentIDs) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.students.mu.Lock()
+	defer r.students.mu.Unlock()
+
+	for _, studentID := range studentIDs {
+		if student, ok := r.students.students[studentID]; !ok || student.DeletedAt != nil {
+			return fmt.Errorf("some of the students do not exist: %w", &NotFoundError{Resource: "student", Key: studentID})
+		}
+	}
+	exam, ok := r.liveExam(examID)
+	if !ok {
+		return &NotFoundError{Resource: "exam", Key: examID.Hex()}
+	}
+
+	timeNow := time.Now()
+	exam.UpdatedAt = timeNow
+	exam.Version++
+	for _, studentID := range studentIDs {
+		student := r.students.students[studentID]
+		student.UpdatedAt = timeNow
+		student.Version++
+
+		if enroll {
+			if !slices.Contains(exam.EnrolledStudents, studentID) {
+				exam.EnrolledStudents = append(exam.EnrolledStudents, studentID)
+			}
+			if !slices.Contains(student.RequiredExams, examID) {
+				student.RequiredExams = append(student.RequiredExams, examID)
+			}
+			continue
+		}
+
+		exam.EnrolledStudents = slices.DeleteFunc(exam.EnrolledStudents, func(id string) bool { return id == studentID })
+		student.RequiredExams = slices.DeleteFunc(student.RequiredExams, func(id primitive.ObjectID) bool { return id == examID })
+	}
+
+	return nil
+}
+
+func (r *MemoryExamRepository) SoftDeleteExam(ctx context.Context, examID primitive.ObjectID, deletedBy string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exam, ok := r.liveExam(examID)
+	if !ok {
+		return &NotFoundError{Resource: "exam", Key: examID.Hex()}
+	}
+
+	timeNow := time.Now()
+	exam.DeletedAt = &timeNow
+	exam.DeletedBy = deletedBy
+	exam.UpdatedAt = timeNow
+	exam.Version++
+	return nil
+}
+
+func (r *MemoryExamRepository) RestoreExam(ctx context.Context, examID primitive.ObjectID) (*models.Exam, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exam, ok := r.exams[examID]
+	if !ok || exam.DeletedAt == nil {
+		return nil, fmt.Errorf("failed to restore exam: %w", &NotFoundError{Resource: "deleted exam", Key: examID.Hex()})
+	}
+
+	exam.DeletedAt = nil
+	exam.DeletedBy = ""
+	exam.UpdatedAt = time.Now()
+	exam.Version++
+	return copyExam(exam), nil
+}
+
+func (r *MemoryExamRepository) ListDeletedExams(ctx context.Context, limit int64) ([]models.Exam, error) {
+	r.mu.Lock()
+	deleted := []*models.Exam{}
+	for _, exam := range r.exams {
+		if exam.DeletedAt != nil {
+			deleted = append(deleted, copyExam(exam))
+		}
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(deleted, func(a, b *models.Exam) int { return b.DeletedAt.Compare(*a.DeletedAt) })
+
+	exams := []models.Exam{}
+	for _, exam := range deleted[:min(int64(len(deleted)), trashLimit(limit))] {
+		exams = append(exams, *exam)
+	}
+	return exams, nil
+}
+
+func (r *MemoryExamRepository) PurgeDeletedExams(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.students.mu.Lock()
+	defer r.students.mu.Unlock()
+
+	var purged int64
+	for examID, exam := range r.exams {
+		if exam.DeletedAt == nil || !exam.DeletedAt.Before(deletedBefore) {
+			continue
+		}
+
+		for attemptID, attempt := range r.attempts {
+			if attempt.ExamID == examID {
+				delete(r.attempts, attemptID)
+			}
+		}
+		for _, student := range r.students.students {
+			if slices.Contains(student.RequiredExams, examID) {
+				student.RequiredExams = slices.DeleteFunc(student.RequiredExams, func(id primitive.ObjectID) bool { return id == examID })
+				student.Version++
+			}
+		}
+		delete(r.exams, examID)
+		purged++
+	}
+	return purged, nil
+}
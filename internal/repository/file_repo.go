@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoFileRepository : file contents in the "files" GridFS bucket, the application metadata is
+// stored in the metadata field of the GridFS files documents
+type MongoFileRepository struct {
+	bucket *gridfs.Bucket
+	files  *mongo.Collection
+	chunks *mongo.Collection
+}
+
+func NewFileRepo(database *mongo.Database) (*MongoFileRepository, error) {
+	bucket, err := gridfs.NewBucket(database, options.GridFSBucket().SetName("files"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the files bucket: %w", err)
+	}
+
+	return &MongoFileRepository{
+		bucket: bucket,
+		files:  bucket.GetFilesCollection(),
+		chunks: bucket.GetChunksCollection(),
+	}, nil
+}
+
+// Upload : stores the content, file gets the ID, size and upload date of the stored file
+func (r *MongoFileRepository) Upload(ctx context.Context, file *models.StoredFile, content io.Reader) error {
+	if file == nil {
+		return fmt.Errorf("nil file is provided")
+	}
+
+	fileID := primitive.NewObjectID()
+	stream, err := r.bucket.OpenUploadStreamWithID(fileID, file.Filename, options.GridFSUpload().SetMetadata(file.FileMetadata))
+	if err != nil {
+		return fmt.Errorf("failed to open upload stream: %w", err)
+	}
+	// GridFS streams take deadlines instead of contexts
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := stream.SetWriteDeadline(deadline); err != nil {
+			_ = stream.Abort()
+			return fmt.Errorf("failed to set upload deadline: %w", err)
+		}
+	}
+
+	if _, err := io.Copy(stream, content); err != nil {
+		_ = stream.Abort()
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := stream.Close(); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	stored, err := r.GetFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	*file = *stored
+	return nil
+}
+
+// GetFile : returns the description of a stored file
+func (r *MongoFileRepository) GetFile(ctx context.Context, fileID primitive.ObjectID) (*models.StoredFile, error) {
+	var file models.StoredFile
+	if err := r.files.FindOne(ctx, bson.M{"_id": fileID}).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", notFound(err, "file", fileID.Hex()))
+	}
+	return &file, nil
+}
+
+// ListFiles : the files of an exam or of an attempt, oldest first
+func (r *MongoFileRepository) ListFiles(ctx context.Context, filter models.FileFilter) ([]models.StoredFile, error) {
+	query := bson.M{"metadata.exam_id": filter.ExamID}
+	if filter.AttemptID != nil {
+		query["metadata.attempt_id"] = *filter.AttemptID
+	} else {
+		query["metadata.attempt_id"] = bson.M{"$exists": false}
+	}
+
+	cursor, err := r.files.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "uploadDate", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	files := []models.StoredFile{}
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, fmt.Errorf("failed to decode files: %w", err)
+	}
+	return files, nil
+}
+
+// Open : returns a seekable reader of the content, ctx bounds every read
+func (r *MongoFileRepository) Open(ctx context.Context, file *models.StoredFile) (io.ReadSeekCloser, error) {
+	if file.ChunkSize <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d of file %s", file.ChunkSize, file.ID.Hex())
+	}
+
+	return &gridfsReader{
+		ctx:       ctx,
+		chunks:    r.chunks,
+		fileID:    file.ID,
+		size:      file.Size,
+		chunkSize: int64(file.ChunkSize),
+	}, nil
+}
+
+// DeleteFile : removes the file and its content
+func (r *MongoFileRepository) DeleteFile(ctx context.Context, fileID primitive.ObjectID) error {
+	if err := r.bucket.DeleteContext(ctx, fileID); err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return fmt.Errorf("failed to delete file: %w", &NotFoundError{Resource: "file", Key: fileID.Hex()})
+		}
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// DeleteExamFiles : removes every file of the exams, the uploads of their attempts included,
+// returns the number of deleted files. Works inside a transaction when ctx is a session context
+func (r *MongoFileRepository) DeleteExamFiles(ctx context.Context, examIDs []primitive.ObjectID) (int64, error) {
+	return r.deleteMatching(ctx, bson.M{"metadata.exam_id": bson.M{"$in": examIDs}})
+}
+
+// DeleteAttemptFiles : removes the files uploaded in the attempts, returns the number of deleted
+// files. Works inside a transaction when ctx is a session context
+func (r *MongoFileRepository) DeleteAttemptFiles(ctx context.Context, attemptIDs []primitive.ObjectID) (int64, error) {
+	return r.deleteMatching(ctx, bson.M{"metadata.attempt_id": bson.M{"$in": attemptIDs}})
+}
+
+// deleteMatching : removes the files documents matching the filter and their chunks, the bucket
+// deletes a single file per call so the collections are used directly
+func (r *MongoFileRepository) deleteMatching(ctx context.Context, filter bson.M) (int64, error) {
+	fileIDs, err := r.files.Distinct(ctx, "_id", filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find files to delete: %w", err)
+	}
+	if len(fileIDs) == 0 {
+		return 0, nil
+	}
+
+	if _, err := r.chunks.DeleteMany(ctx, bson.M{"files_id": bson.M{"$in": fileIDs}}); err != nil {
+		return 0, fmt.Errorf("failed to delete file chunks: %w", err)
+	}
+	result, err := r.files.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": fileIDs}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete files: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+// gridfsReader : reads the chunks of a GridFS file directly, a seek only moves the offset and the
+// next read starts at the chunk holding it, so serving a range does not read the start of the file
+type gridfsReader struct {
+	ctx       context.Context
+	chunks    *mongo.Collection
+	fileID    primitive.ObjectID
+	size      int64
+	chunkSize int64
+
+	offset  int64
+	cursor  *mongo.Cursor
+	nextN   int64
+	pending []byte // unread bytes of the current chunk
+}
+
+func (g *gridfsReader) Read(p []byte) (int, error) {
+	if g.offset >= g.size {
+		return 0, io.EOF
+	}
+
+	if len(g.pending) == 0 {
+		if err := g.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, g.pending)
+	g.pending = g.pending[n:]
+	g.offset += int64(n)
+	return n, nil
+}
+
+// nextChunk : loads the chunk holding the offset, opening the cursor after a seek
+func (g *gridfsReader) nextChunk() error {
+	skip := int64(0)
+	if g.cursor == nil {
+		g.nextN = g.offset / g.chunkSize
+		skip = g.offset % g.chunkSize
+
+		cursor, err := g.chunks.Find(g.ctx,
+			bson.M{"files_id": g.fileID, "n": bson.M{"$gte": g.nextN}},
+			options.Find().SetSort(bson.D{{Key: "n", Value: 1}}),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to read file chunks: %w", err)
+		}
+		g.cursor = cursor
+	}
+
+	if !g.cursor.Next(g.ctx) {
+		if err := g.cursor.Err(); err != nil {
+			return fmt.Errorf("failed to read file chunks: %w", err)
+		}
+		return io.ErrUnexpectedEOF
+	}
+
+	var chunk struct {
+		N    int64  `bson:"n"`
+		Data []byte `bson:"data"`
+	}
+	if err := g.cursor.Decode(&chunk); err != nil {
+		return fmt.Errorf("failed to decode file chunk: %w", err)
+	}
+	if chunk.N != g.nextN || skip > int64(len(chunk.Data)) {
+		return fmt.Errorf("file %s is corrupted at chunk %d", g.fileID.Hex(), g.nextN)
+	}
+
+	g.nextN++
+	g.pending = chunk.Data[skip:]
+	return nil
+}
+
+func (g *gridfsReader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = g.offset + offset
+	case io.SeekEnd:
+		target = g.size + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if target < 0 {
+		return 0, fmt.Errorf("negative position %d", target)
+	}
+
+	if target != g.offset {
+		g.closeCursor()
+		g.offset = target
+	}
+	return target, nil
+}
+
+func (g *gridfsReader) Close() error {
+	g.closeCursor()
+	return nil
+}
+
+// closeCursor : drops the current position in the chunks
+func (g *gridfsReader) closeCursor() {
+	if g.cursor != nil {
+		_ = g.cursor.Close(context.Background())
+		g.cursor = nil
+	}
+	g.pending = nil
+}
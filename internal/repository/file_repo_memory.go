@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryFileRepository : in-memory FileRepository, contents are kept as byte slices
+type MemoryFileRepository struct {
+	mu       sync.RWMutex
+	files    map[primitive.ObjectID]models.StoredFile
+	contents map[primitive.ObjectID][]byte
+}
+
+func NewMemoryFileRepo() *MemoryFileRepository {
+	return &MemoryFileRepository{
+		files:    map[primitive.ObjectID]models.StoredFile{},
+		contents: map[primitive.ObjectID][]byte{},
+	}
+}
+
+func (r *MemoryFileRepository) Upload(ctx context.Context, file *models.StoredFile, content io.Reader) error {
+	if file == nil {
+		return fmt.Errorf("nil file is provided")
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	file.ID = primitive.NewObjectID()
+	file.Size = int64(len(data))
+	file.ChunkSize = 255 * 1024
+	file.UploadedAt = time.Now().UTC().Truncate(time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[file.ID] = *file
+	r.contents[file.ID] = data
+	return nil
+}
+
+func (r *MemoryFileRepository) GetFile(ctx context.Context, fileID primitive.ObjectID) (*models.StoredFile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	file, ok := r.files[fileID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get file: %w", &NotFoundError{Resource: "file", Key: fileID.Hex()})
+	}
+	return &file, nil
+}
+
+func (r *MemoryFileRepository) ListFiles(ctx context.Context, filter models.FileFilter) ([]models.StoredFile, error) {
+	r.mu.RLock()
+	files := []models.StoredFile{}
+	for _, file := range r.files {
+		if file.ExamID != filter.ExamID {
+			continue
+		}
+		if filter.AttemptID == nil && file.AttemptID != nil {
+			continue
+		}
+		if filter.AttemptID != nil && (file.AttemptID == nil || *file.AttemptID != *filter.AttemptID) {
+			continue
+		}
+		files = append(files, file)
+	}
+	r.mu.RUnlock()
+
+	// oldest first, the ID breaks ties between uploads of the same millisecond
+	slices.SortFunc(files, func(a, b models.StoredFile) int {
+		if c := a.UploadedAt.Compare(b.UploadedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return files, nil
+}
+
+func (r *MemoryFileRepository) Open(ctx context.Context, file *models.StoredFile) (io.ReadSeekCloser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	data, ok := r.contents[file.ID]
+	if !ok {
+		return nil, fmt.Errorf("failed to open file: %w", &NotFoundError{Resource: "file", Key: file.ID.Hex()})
+	}
+	return memoryFileReader{bytes.NewReader(data)}, nil
+}
+
+func (r *MemoryFileRepository) DeleteFile(ctx context.Context, fileID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.files[fileID]; !ok {
+		return fmt.Errorf("failed to delete file: %w", &NotFoundError{Resource: "file", Key: fileID.Hex()})
+	}
+	delete(r.files, fileID)
+	delete(r.contents, fileID)
+	return nil
+}
+
+func (r *MemoryFileRepository) DeleteExamFiles(ctx context.Context, examIDs []primitive.ObjectID) (int64, error) {
+	return r.deleteMatching(func(file models.StoredFile) bool {
+		return slices.Contains(examIDs, file.ExamID)
+	}), nil
+}
+
+func (r *MemoryFileRepository) DeleteAttemptFiles(ctx context.Context, attemptIDs []primitive.ObjectID) (int64, error) {
+	return r.deleteMatching(func(file models.StoredFile) bool {
+		return file.AttemptID != nil && slices.Contains(attemptIDs, *file.AttemptID)
+	}), nil
+}
+
+// deleteMatching : removes the files for which match is true, returns how many were removed
+func (r *MemoryFileRepository) deleteMatching(match func(file models.StoredFile) bool) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for fileID, file := range r.files {
+		if match(file) {
+			delete(r.files, fileID)
+			delete(r.contents, fileID)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// memoryFileReader : bytes.Reader with a no-op Close
+type memoryFileReader struct {
+	*bytes.Reader
+}
+
+func (memoryFileReader) Close() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	defaultHistoryLimit int64 = 50
+	maxHistoryLimit     int64 = 500
+)
+
+type MongoLoginHistoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLoginHistoryRepo(database *mongo.Database) *MongoLoginHistoryRepository {
+	return &MongoLoginHistoryRepository{
+		collection: database.Collection("login_history"),
+	}
+}
+
+// Record : stores a login attempt
+func (r *MongoLoginHistoryRepository) Record(ctx context.Context, event *models.LoginEvent) error {
+	if event == nil {
+		return fmt.Errorf("nil login event is provided")
+	}
+
+	event.ID = primitive.NewObjectID()
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if _, err := r.collection.InsertOne(ctx, event); err != nil {
+		return fmt.Errorf("failed to record login event: %w", err)
+	}
+	return nil
+}
+
+// ListForStudent : returns the most recent login attempts of a student
+func (r *MongoLoginHistoryRepository) ListForStudent(ctx context.Context, studentID string, limit int64) ([]models.LoginEvent, error) {
+	return r.Search(ctx, models.LoginHistoryFilter{
+		StudentID: studentID,
+		Limit:     limit,
+	})
+}
+
+// Search : returns login attempts matching the filter, newest first
+func (r *MongoLoginHistoryRepository) Search(ctx context.Context, filter models.LoginHistoryFilter) ([]models.LoginEvent, error) {
+	query := bson.M{}
+
+	if filter.StudentID != "" {
+		query["student_id"] = filter.StudentID
+	}
+	if filter.IP != "" {
+		query["ip"] = filter.IP
+	}
+	if filter.Success != nil {
+		query["success"] = *filter.Success
+	}
+	if filter.From != nil || filter.To != nil {
+		timestamp := bson.M{}
+		if filter.From != nil {
+			timestamp["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			timestamp["$lte"] = *filter.To
+		}
+		query["timestamp"] = timestamp
+	}
+
+	cursor, err := r.collection.Find(ctx, query,
+		options.Find().
+			SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+			SetLimit(historyLimit(filter.Limit)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search login history: %w", err)
+	}
+
+	events := []models.LoginEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode login history: %w", err)
+	}
+	return events, nil
+}
+
+// historyLimit : clamps the requested page size
+func historyLimit(limit int64) int64 {
+	if limit <= 0 {
+		return defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		return maxHistoryLimit
+	}
+	return limit
+}
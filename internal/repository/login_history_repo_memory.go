@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryLoginHistoryRepository : in-memory LoginHistoryRepository, events older than the
+// retention are hidden like the TTL index of the collection removes them
+type MemoryLoginHistoryRepository struct {
+	mu     sync.RWMutex
+	events []models.LoginEvent
+}
+
+func NewMemoryLoginHistoryRepo() *MemoryLoginHistoryRepository {
+	return &MemoryLoginHistoryRepository{}
+}
+
+func (r *MemoryLoginHistoryRepository) Record(ctx context.Context, event *models.LoginEvent) error {
+	if event == nil {
+		return fmt.Errorf("nil login event is provided")
+	}
+
+	event.ID = primitive.NewObjectID()
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *MemoryLoginHistoryRepository) ListForStudent(ctx context.Context, studentID string, limit int64) ([]models.LoginEvent, error) {
+	return r.Search(ctx, models.LoginHistoryFilter{
+		StudentID: studentID,
+		Limit:     limit,
+	})
+}
+
+func (r *MemoryLoginHistoryRepository) Search(ctx context.Context, filter models.LoginHistoryFilter) ([]models.LoginEvent, error) {
+	expired := time.Now().Add(-models.LoginHistoryRetention)
+
+	r.mu.RLock()
+	matches := []models.LoginEvent{}
+	for _, event := range r.events {
+		if event.Timestamp.Before(expired) {
+			continue
+		}
+		if filter.StudentID != "" && event.StudentID != filter.StudentID {
+			continue
+		}
+		if filter.IP != "" && event.IP != filter.IP {
+			continue
+		}
+		if filter.Success != nil && event.Success != *filter.Success {
+			continue
+		}
+		if filter.From != nil && event.Timestamp.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && event.Timestamp.After(*filter.To) {
+			continue
+		}
+		matches = append(matches, event)
+	}
+	r.mu.RUnlock()
+
+	// newest first
+	slices.SortStableFunc(matches, func(a, b models.LoginEvent) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+
+	if limit := historyLimit(filter.Limit); int64(len(matches)) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
@@ -0,0 +1,85 @@
+// Package repository implements the data access layer. Every repository is described by an
+// interface with a MongoDB implementation and an in-memory one used in tests.
+package repository
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type StudentRepository interface {
+	CreateStudent(ctx context.Context, student *models.Student, password string) (string, error)
+	InsertStudents(ctx context.Context, students []*models.Student, passwords []string) (map[int]error, error)
+	GetStudentByEmail(ctx context.Context, email string) (*models.Student, error)
+	GetStudentByID(ctx context.Context, studentID string) (*models.Student, error)
+	GetStudentByIDFromBD(ctx context.Context, studentID string) (*models.Student, error)
+	FindExistingStudents(ctx context.Context, studentIDs, emails []string) (map[string]bool, map[string]bool, error)
+	ListStudents(ctx context.Context, filter models.StudentListFilter) ([]models.Student, string, error)
+	VerifyPassword(ctx context.Context, studentID, plainPassword string) (*models.Student, error)
+	ChangePassword(ctx context.Context, studentID, currentPassword, newPassword string) error
+	UpdateStudentProfile(ctx context.Context, studentID string, profile models.StudentProfileUpdate) (*models.Student, error)
+	UpdateLastLogin(ctx context.Context, studentID string, loginAt time.Time) error
+	SetAccountStatus(ctx context.Context, studentID string, change models.AccountStatusChange) (*models.Student, error)
+	SoftDeleteStudent(ctx context.Context, studentID, deletedBy string) error
+	RestoreStudent(ctx context.Context, studentID string) (*models.Student, error)
+	ListDeletedStudents(ctx context.Context, limit int64) ([]models.Student, error)
+	PurgeDeletedStudents(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+type LoginHistoryRepository interface {
+	Record(ctx context.Context, event *models.LoginEvent) error
+	ListForStudent(ctx context.Context, studentID string, limit int64) ([]models.LoginEvent, error)
+	Search(ctx context.Context, filter models.LoginHistoryFilter) ([]models.LoginEvent, error)
+}
+
+type ExamRepository interface {
+	CreateExam(ctx context.Context, exam *models.Exam) error
+	GetExam(ctx context.Context, examID primitive.ObjectID) (*models.Exam, error)
+	GetAttempt(ctx context.Context, attemptID primitive.ObjectID) (*models.ExamAttempt, error)
+	StartAttempt(ctx context.Context, examID primitive.ObjectID, studentID string) (*models.ExamAttempt, error)
+	SubmitAttempt(ctx context.Context, attemptID primitive.ObjectID, studentID string, answers []int) (*models.ExamAttempt, error)
+	UpdateExam(ctx context.Context, examID primitive.ObjectID, update models.ExamUpdate) (*models.Exam, error)
+	RegradeExam(ctx context.Context, examID primitive.ObjectID, regrade models.ExamRegrade) (int, error)
+	EnrollStudents(ctx context.Context, examID primitive.ObjectID, studentIDs []string) error
+	UnenrollStudents(ctx context.Context, examID primitive.ObjectID, studentIDs []string) error
+	SoftDeleteExam(ctx context.Context, examID primitive.ObjectID, deletedBy string) error
+	RestoreExam(ctx context.Context, examID primitive.ObjectID) (*models.Exam, error)
+	ListDeletedExams(ctx context.Context, limit int64) ([]models.Exam, error)
+	PurgeDeletedExams(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// AuditRepository : append-only, hash chained log of privileged actions
+type AuditRepository interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	Export(ctx context.Context, filter models.AuditFilter, fn func(entry *models.AuditEntry) error) error
+	Verify(ctx context.Context) (*models.AuditVerification, error)
+}
+
+// FileRepository : file contents with their metadata, Open returns a seekable reader for range requests
+type FileRepository interface {
+	Upload(ctx context.Context, file *models.StoredFile, content io.Reader) error
+	GetFile(ctx context.Context, fileID primitive.ObjectID) (*models.StoredFile, error)
+	ListFiles(ctx context.Context, filter models.FileFilter) ([]models.StoredFile, error)
+	Open(ctx context.Context, file *models.StoredFile) (io.ReadSeekCloser, error)
+	DeleteFile(ctx context.Context, fileID primitive.ObjectID) error
+	DeleteExamFiles(ctx context.Context, examIDs []primitive.ObjectID) (int64, error)
+	DeleteAttemptFiles(ctx context.Context, attemptIDs []primitive.ObjectID) (int64, error)
+}
+
+var (
+	_ StudentRepository      = (*MongoStudentRepository)(nil)
+	_ StudentRepository      = (*MemoryStudentRepository)(nil)
+	_ LoginHistoryRepository = (*MongoLoginHistoryRepository)(nil)
+	_ LoginHistoryRepository = (*MemoryLoginHistoryRepository)(nil)
+	_ ExamRepository         = (*MongoExamRepository)(nil)
+	_ ExamRepository         = (*MemoryExamRepository)(nil)
+	_ AuditRepository        = (*MongoAuditRepository)(nil)
+	_ AuditRepository        = (*MemoryAuditRepository)(nil)
+	_ FileRepository         = (*MongoFileRepository)(nil)
+	_ FileRepository         = (*MemoryFileRepository)(nil)
+)
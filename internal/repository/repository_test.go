@@ -0,0 +1,87 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"github.com/Glorified-Toaster/senior-project/internal/repository/repotest"
+)
+
+// The Mongo suites run against the database of MONGODB_TEST_URI, which must be a replica set
+// since the repositories use transactions, and are skipped when it is not set.
+
+func TestMemoryStudentRepository(t *testing.T) {
+	repotest.StudentRepositoryContract(t, func(t *testing.T) repository.StudentRepository {
+		return repository.NewMemoryStudentRepo()
+	})
+}
+
+func TestMongoStudentRepository(t *testing.T) {
+	repotest.StudentRepositoryContract(t, func(t *testing.T) repository.StudentRepository {
+		db := repotest.MongoDatabase(t)
+		files, err := repository.NewFileRepo(db)
+		if err != nil {
+			t.Fatalf("NewFileRepo: %v", err)
+		}
+		return repository.NewStudentRepo(context.Background(), db, nil, files)
+	})
+}
+
+func TestMemoryExamRepository(t *testing.T) {
+	repotest.ExamRepositoryContract(t, func(t *testing.T) (repository.ExamRepository, repository.StudentRepository) {
+		students := repository.NewMemoryStudentRepo()
+		return repository.NewMemoryExamRepo(students), students
+	})
+}
+
+func TestMongoExamRepository(t *testing.T) {
+	repotest.ExamRepositoryContract(t, func(t *testing.T) (repository.ExamRepository, repository.StudentRepository) {
+		db := repotest.MongoDatabase(t)
+		files, err := repository.NewFileRepo(db)
+		if err != nil {
+			t.Fatalf("NewFileRepo: %v", err)
+		}
+		return repository.NewExamRepo(db, nil, files), repository.NewStudentRepo(context.Background(), db, nil, files)
+	})
+}
+
+func TestMemoryAuditRepository(t *testing.T) {
+	repotest.AuditRepositoryContract(t, func(t *testing.T) repository.AuditRepository {
+		return repository.NewMemoryAuditRepo()
+	})
+}
+
+func TestMongoAuditRepository(t *testing.T) {
+	repotest.AuditRepositoryContract(t, func(t *testing.T) repository.AuditRepository {
+		return repository.NewAuditRepo(repotest.MongoDatabase(t))
+	})
+}
+
+func TestMemoryFileRepository(t *testing.T) {
+	repotest.FileRepositoryContract(t, func(t *testing.T) repository.FileRepository {
+		return repository.NewMemoryFileRepo()
+	})
+}
+
+func TestMongoFileRepository(t *testing.T) {
+	repotest.FileRepositoryContract(t, func(t *testing.T) repository.FileRepository {
+		files, err := repository.NewFileRepo(repotest.MongoDatabase(t))
+		if err != nil {
+			t.Fatalf("NewFileRepo: %v", err)
+		}
+		return files
+	})
+}
+
+func TestMemoryLoginHistoryRepository(t *testing.T) {
+	repotest.LoginHistoryRepositoryContract(t, func(t *testing.T) repository.LoginHistoryRepository {
+		return repository.NewMemoryLoginHistoryRepo()
+	})
+}
+
+func TestMongoLoginHistoryRepository(t *testing.T) {
+	repotest.LoginHistoryRepositoryContract(t, func(t *testing.T) repository.LoginHistoryRepository {
+		return repository.NewLoginHistoryRepo(repotest.MongoDatabase(t))
+	})
+}
@@ -0,0 +1,111 @@
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+)
+
+// AuditRepositoryContract : behaviour every AuditRepository must have, newRepo must return an empty repository
+func AuditRepositoryContract(t *testing.T, newRepo func(t *testing.T) repository.AuditRepository) {
+	ctx := context.Background()
+
+	seed := func(t *testing.T, repo repository.AuditRepository) []models.AuditEntry {
+		t.Helper()
+		entries := []models.AuditEntry{
+			{ActorID: "admin", Action: models.AuditStudentStatus, TargetType: models.AuditTargetStudent, TargetID: "S0001", RequestID: "r1",
+				Changes: []models.AuditChange{{Field: "status", Before: json.RawMessage(`"active"`), After: json.RawMessage(`"suspended"`)}}},
+			{ActorID: "teacher", Action: models.AuditExamCreate, TargetType: models.AuditTargetExam, TargetID: "E1", RequestID: "r2"},
+			{ActorID: "admin", Action: models.AuditStudentDelete, TargetType: models.AuditTargetStudent, TargetID: "S0002", RequestID: "r3",
+				Metadata: map[string]string{"reason": "duplicate"}},
+		}
+		for i := range entries {
+			if err := repo.Record(ctx, &entries[i]); err != nil {
+				t.Fatalf("Record: %v", err)
+			}
+		}
+		return entries
+	}
+
+	t.Run("entries are chained", func(t *testing.T) {
+		repo := newRepo(t)
+		entries := seed(t, repo)
+
+		for i, entry := range entries {
+			if entry.Seq != int64(i+1) || entry.Hash == "" || entry.ID.IsZero() {
+				t.Fatalf("entry %d = %+v", i, entry)
+			}
+			if i > 0 && entry.PrevHash != entries[i-1].Hash {
+				t.Fatalf("entry %d does not link to the previous one", i)
+			}
+		}
+
+		verification, err := repo.Verify(ctx)
+		if err != nil || !verification.Valid || verification.Checked != 3 {
+			t.Fatalf("Verify = %+v, %v", verification, err)
+		}
+	})
+
+	t.Run("search newest first", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		entries, err := repo.Search(ctx, models.AuditFilter{ActorID: "admin"})
+		if err != nil || len(entries) != 2 || entries[0].TargetID != "S0002" || entries[1].TargetID != "S0001" {
+			t.Fatalf("Search by actor = %+v, %v", entries, err)
+		}
+		if string(entries[1].Changes[0].After) != `"suspended"` {
+			t.Fatalf("stored changes = %+v", entries[1].Changes)
+		}
+
+		entries, err = repo.Search(ctx, models.AuditFilter{Limit: 1, BeforeSeq: 3})
+		if err != nil || len(entries) != 1 || entries[0].Seq != 2 {
+			t.Fatalf("Search before seq 3 = %+v, %v", entries, err)
+		}
+
+		entries, err = repo.Search(ctx, models.AuditFilter{RequestID: "r2"})
+		if err != nil || len(entries) != 1 || entries[0].Action != models.AuditExamCreate {
+			t.Fatalf("Search by request ID = %+v, %v", entries, err)
+		}
+	})
+
+	t.Run("export oldest first", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		var seqs []int64
+		err := repo.Export(ctx, models.AuditFilter{TargetType: models.AuditTargetStudent}, func(entry *models.AuditEntry) error {
+			seqs = append(seqs, entry.Seq)
+			return nil
+		})
+		if err != nil || len(seqs) != 2 || seqs[0] != 1 || seqs[1] != 3 {
+			t.Fatalf("Export = %v, %v", seqs, err)
+		}
+	})
+
+	t.Run("concurrent writers keep a single chain", func(t *testing.T) {
+		repo := newRepo(t)
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				entry := &models.AuditEntry{ActorID: "admin", Action: models.AuditExamUpdate, TargetType: models.AuditTargetExam}
+				if err := repo.Record(ctx, entry); err != nil {
+					t.Errorf("Record: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		verification, err := repo.Verify(ctx)
+		if err != nil || !verification.Valid || verification.Checked != 4 {
+			t.Fatalf("Verify = %+v, %v", verification, err)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newExam : a two question exam worth 3 marks that is not stored yet
+func newExam() *models.Exam {
+	return &models.Exam{
+		Title: "Algorithms",
+		Questions: []models.Question{
+			{Text: "q1", Options: []string{"a", "b"}, Answer: 0, Marks: 1},
+			{Text: "q2", Options: []string{"a", "b", "c"}, Answer: 2, Marks: 2},
+		},
+		PassMark:  2,
+		CreatedBy: "T0001",
+	}
+}
+
+// ExamRepositoryContract : behaviour every ExamRepository must have, newRepos must return an empty
+// exam repository and the (empty) student repository it works with
+func ExamRepositoryContract(t *testing.T, newRepos func(t *testing.T) (repository.ExamRepository, repository.StudentRepository)) {
+	ctx := context.Background()
+
+	// setup : stores an exam and student S0001 enrolled in it
+	setup := func(t *testing.T) (repository.ExamRepository, repository.StudentRepository, *models.Exam) {
+		t.Helper()
+		exams, students := newRepos(t)
+
+		mustCreate(t, students, newStudent(1))
+		exam := newExam()
+		if err := exams.CreateExam(ctx, exam); err != nil {
+			t.Fatalf("CreateExam: %v", err)
+		}
+		if err := exams.EnrollStudents(ctx, exam.ID, []string{"S0001"}); err != nil {
+			t.Fatalf("EnrollStudents: %v", err)
+		}
+		return exams, students, exam
+	}
+
+	t.Run("invalid exam is rejected", func(t *testing.T) {
+		exams, _ := newRepos(t)
+
+		exam := newExam()
+		exam.Questions[0].Answer = 5
+		if err := exams.CreateExam(ctx, exam); !errors.Is(err, repository.ErrInvalidExam) {
+			t.Fatalf("CreateExam error = %v, want ErrInvalidExam", err)
+		}
+	})
+
+	t.Run("enrollment", func(t *testing.T) {
+		exams, students, exam := setup(t)
+
+		student, err := students.GetStudentByIDFromBD(ctx, "S0001")
+		if err != nil || !slices.Contains(student.RequiredExams, exam.ID) {
+			t.Fatalf("required exams after enrollment = %+v, %v", student, err)
+		}
+		stored, err := exams.GetExam(ctx, exam.ID)
+		if err != nil || !slices.Equal(stored.EnrolledStudents, []string{"S0001"}) {
+			t.Fatalf("exam roster after enrollment = %+v, %v", stored, err)
+		}
+
+		if err := exams.EnrollStudents(ctx, exam.ID, []string{"S0001", "missing"}); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("EnrollStudents error = %v, want repository.ErrNotFound", err)
+		}
+
+		if err := exams.UnenrollStudents(ctx, exam.ID, []string{"S0001"}); err != nil {
+			t.Fatalf("UnenrollStudents: %v", err)
+		}
+		student, err = students.GetStudentByIDFromBD(ctx, "S0001")
+		if err != nil || slices.Contains(student.RequiredExams, exam.ID) {
+			t.Fatalf("required exams after unenrollment = %+v, %v", student, err)
+		}
+		if _, err := exams.StartAttempt(ctx, exam.ID, "S0001"); !errors.Is(err, repository.ErrNotEnrolled) {
+			t.Fatalf("StartAttempt error = %v, want ErrNotEnrolled", err)
+		}
+	})
+
+	t.Run("submit attempt", func(t *testing.T) {
+		exams, students, exam := setup(t)
+
+		attempt, err := exams.StartAttempt(ctx, exam.ID, "S0001")
+		if err != nil {
+			t.Fatalf("StartAttempt: %v", err)
+		}
+		again, err := exams.StartAttempt(ctx, exam.ID, "S0001")
+		if err != nil || again.ID != attempt.ID {
+			t.Fatalf("StartAttempt did not return the attempt in progress: %+v, %v", again, err)
+		}
+
+		if _, err := exams.SubmitAttempt(ctx, attempt.ID, "S0001", []int{0}); !errors.Is(err, repository.ErrInvalidAnswers) {
+			t.Fatalf("SubmitAttempt error = %v, want ErrInvalidAnswers", err)
+		}
+		if _, err := exams.SubmitAttempt(ctx, attempt.ID, "S0002", []int{0, 2}); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("SubmitAttempt of another student error = %v, want repository.ErrNotFound", err)
+		}
+
+		submitted, err := exams.SubmitAttempt(ctx, attempt.ID, "S0001", []int{0, 1})
+		if err != nil {
+			t.Fatalf("SubmitAttempt: %v", err)
+		}
+		if submitted.Status != models.AttemptSubmitted || submitted.Score != 1 || submitted.TotalMarks != 3 || submitted.Passed {
+			t.Fatalf("SubmitAttempt returned %+v", submitted)
+		}
+
+		student, err := students.GetStudentByIDFromBD(ctx, "S0001")
+		if err != nil {
+			t.Fatalf("GetStudentByIDFromBD: %v", err)
+		}
+		if slices.Contains(student.RequiredExams, exam.ID) || len(student.CompletedExams) != 1 || student.CompletedExams[0].Score != 1 {
+			t.Fatalf("student exams after submission = %+v / %+v", student.RequiredExams, student.CompletedExams)
+		}
+
+		if _, err := exams.SubmitAttempt(ctx, attempt.ID, "S0001", []int{0, 2}); !errors.Is(err, repository.ErrAttemptSubmitted) {
+			t.Fatalf("second SubmitAttempt error = %v, want ErrAttemptSubmitted", err)
+		}
+
+		stored, err := exams.GetAttempt(ctx, attempt.ID)
+		if err != nil || stored.Status != models.AttemptSubmitted || stored.StudentID != "S0001" {
+			t.Fatalf("GetAttempt = %+v, %v", stored, err)
+		}
+		if _, err := exams.GetAttempt(ctx, primitive.NewObjectID()); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetAttempt of a missing attempt error = %v, want repository.ErrNotFound", err)
+		}
+	})
+
+	t.Run("regrade", func(t *testing.T) {
+		exams, students, exam := setup(t)
+
+		attempt, err := exams.StartAttempt(ctx, exam.ID, "S0001")
+		if err != nil {
+			t.Fatalf("StartAttempt: %v", err)
+		}
+		if _, err := exams.SubmitAttempt(ctx, attempt.ID, "S0001", []int{0, 1}); err != nil {
+			t.Fatalf("SubmitAttempt: %v", err)
+		}
+
+		// the answer key of question 2 was wrong
+		questions := newExam().Questions
+		questions[1].Answer = 1
+		if _, err := exams.RegradeExam(ctx, exam.ID, models.ExamRegrade{Questions: questions[:1], PassMark: 2}); !errors.Is(err, repository.ErrInvalidExam) {
+			t.Fatalf("RegradeExam with fewer questions error = %v, want ErrInvalidExam", err)
+		}
+
+		regraded, err := exams.RegradeExam(ctx, exam.ID, models.ExamRegrade{Questions: questions, PassMark: 2})
+		if err != nil || regraded != 1 {
+			t.Fatalf("RegradeExam = %d, %v", regraded, err)
+		}
+
+		student, err := students.GetStudentByIDFromBD(ctx, "S0001")
+		if err != nil || len(student.CompletedExams) != 1 || student.CompletedExams[0].Score != 3 || !student.CompletedExams[0].Passed {
+			t.Fatalf("completed exams after regrading = %+v, %v", student, err)
+		}
+
+		if _, err := exams.RegradeExam(ctx, primitive.NewObjectID(), models.ExamRegrade{Questions: questions, PassMark: 2}); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("RegradeExam of a missing exam error = %v, want repository.ErrNotFound", err)
+		}
+	})
+
+	t.Run("versioned updates", func(t *testing.T) {
+		exams, _ := newRepos(t)
+		exam := newExam()
+		if err := exams.CreateExam(ctx, exam); err != nil {
+			t.Fatalf("CreateExam: %v", err)
+		}
+
+		title := "Data structures"
+		stale := exam.Version
+		updated, err := exams.UpdateExam(ctx, exam.ID, models.ExamUpdate{Title: &title, ExpectedVersion: &stale})
+		if err != nil || updated.Title != title || updated.Version != stale+1 {
+			t.Fatalf("UpdateExam = %+v, %v", updated, err)
+		}
+
+		if _, err := exams.UpdateExam(ctx, exam.ID, models.ExamUpdate{Title: &title, ExpectedVersion: &stale}); !errors.Is(err, repository.ErrVersionConflict) {
+			t.Fatalf("UpdateExam with a stale version error = %v, want ErrVersionConflict", err)
+		}
+		if _, err := exams.RegradeExam(ctx, exam.ID, models.ExamRegrade{Questions: exam.Questions, PassMark: 1, ExpectedVersion: &stale}); !errors.Is(err, repository.ErrVersionConflict) {
+			t.Fatalf("RegradeExam with a stale version error = %v, want ErrVersionConflict", err)
+		}
+
+		tooHigh := 10.0
+		if _, err := exams.UpdateExam(ctx, exam.ID, models.ExamUpdate{PassMark: &tooHigh}); !errors.Is(err, repository.ErrInvalidExam) {
+			t.Fatalf("UpdateExam with a pass mark above the total error = %v, want ErrInvalidExam", err)
+		}
+
+		// edits made without a version do not conflict with each other
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := range 8 {
+			wg.Go(func() {
+				description := fmt.Sprintf("edit %d", i)
+				_, err := exams.UpdateExam(ctx, exam.ID, models.ExamUpdate{Description: &description})
+				errs <- err
+			})
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("concurrent UpdateExam without a version: %v", err)
+			}
+		}
+		if stored, err := exams.GetExam(ctx, exam.ID); err != nil || stored.Version != stale+9 {
+			t.Fatalf("GetExam after the concurrent edits = %+v, %v", stored, err)
+		}
+	})
+
+	t.Run("trash", func(t *testing.T) {
+		exams, students, exam := setup(t)
+
+		if err := exams.SoftDeleteExam(ctx, exam.ID, "admin"); err != nil {
+			t.Fatalf("SoftDeleteExam: %v", err)
+		}
+		if _, err := exams.GetExam(ctx, exam.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetExam of a deleted exam error = %v, want repository.ErrNotFound", err)
+		}
+		if _, err := exams.StartAttempt(ctx, exam.ID, "S0001"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("StartAttempt of a deleted exam error = %v, want repository.ErrNotFound", err)
+		}
+
+		deleted, err := exams.ListDeletedExams(ctx, 0)
+		if err != nil || len(deleted) != 1 || deleted[0].ID != exam.ID || deleted[0].DeletedBy != "admin" {
+			t.Fatalf("ListDeletedExams = %+v, %v", deleted, err)
+		}
+
+		if _, err := exams.RestoreExam(ctx, exam.ID); err != nil {
+			t.Fatalf("RestoreExam: %v", err)
+		}
+		if _, err := exams.GetExam(ctx, exam.ID); err != nil {
+			t.Fatalf("GetExam after restore: %v", err)
+		}
+
+		if err := exams.SoftDeleteExam(ctx, exam.ID, "admin"); err != nil {
+			t.Fatalf("SoftDeleteExam: %v", err)
+		}
+		if purged, err := exams.PurgeDeletedExams(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+			t.Fatalf("PurgeDeletedExams = %d, %v", purged, err)
+		}
+		student, err := students.GetStudentByIDFromBD(ctx, "S0001")
+		if err != nil || slices.Contains(student.RequiredExams, exam.ID) {
+			t.Fatalf("required exams after purge = %+v, %v", student, err)
+		}
+		if _, err := exams.RestoreExam(ctx, exam.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("RestoreExam of a purged exam error = %v, want repository.ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package repotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FileRepositoryContract : behaviour every FileRepository must have, newRepo must return an empty repository
+func FileRepositoryContract(t *testing.T, newRepo func(t *testing.T) repository.FileRepository) {
+	ctx := context.Background()
+	examID := primitive.NewObjectID()
+	attemptID := primitive.NewObjectID()
+
+	upload := func(t *testing.T, repo repository.FileRepository, name, content string, attempt *primitive.ObjectID) *models.StoredFile {
+		t.Helper()
+		file := &models.StoredFile{
+			Filename: name,
+			FileMetadata: models.FileMetadata{
+				ContentType: "text/plain; charset=utf-8",
+				ExamID:      examID,
+				AttemptID:   attempt,
+				UploadedBy:  "T0001",
+			},
+		}
+		if err := repo.Upload(ctx, file, strings.NewReader(content)); err != nil {
+			t.Fatalf("Upload: %v", err)
+		}
+		if file.ID.IsZero() || file.Size != int64(len(content)) || file.UploadedAt.IsZero() {
+			t.Fatalf("Upload returned %+v", file)
+		}
+		return file
+	}
+
+	t.Run("upload and read back", func(t *testing.T) {
+		repo := newRepo(t)
+		content := strings.Repeat("0123456789", 1000)
+		file := upload(t, repo, "notes.txt", content, nil)
+
+		stored, err := repo.GetFile(ctx, file.ID)
+		if err != nil || stored.Filename != "notes.txt" || stored.ContentType != file.ContentType || stored.ExamID != examID || stored.UploadedBy != "T0001" {
+			t.Fatalf("GetFile = %+v, %v", stored, err)
+		}
+
+		reader, err := repo.Open(ctx, stored)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		defer reader.Close()
+
+		data, err := io.ReadAll(reader)
+		if err != nil || string(data) != content {
+			t.Fatalf("read %d bytes, %v", len(data), err)
+		}
+
+		// a range in the middle of the file
+		if _, err := reader.Seek(4995, io.SeekStart); err != nil {
+			t.Fatalf("Seek: %v", err)
+		}
+		part := make([]byte, 10)
+		if _, err := io.ReadFull(reader, part); err != nil || !bytes.Equal(part, []byte(content[4995:5005])) {
+			t.Fatalf("read after seek = %q, %v", part, err)
+		}
+		if size, err := reader.Seek(0, io.SeekEnd); err != nil || size != int64(len(content)) {
+			t.Fatalf("Seek to end = %d, %v", size, err)
+		}
+	})
+
+	t.Run("list exam and attempt files", func(t *testing.T) {
+		repo := newRepo(t)
+		diagram := upload(t, repo, "diagram.png", "a", nil)
+		answer := upload(t, repo, "answer.pdf", "b", &attemptID)
+
+		files, err := repo.ListFiles(ctx, models.FileFilter{ExamID: examID})
+		if err != nil || len(files) != 1 || files[0].ID != diagram.ID {
+			t.Fatalf("ListFiles of the exam = %+v, %v", files, err)
+		}
+		files, err = repo.ListFiles(ctx, models.FileFilter{ExamID: examID, AttemptID: &attemptID})
+		if err != nil || len(files) != 1 || files[0].ID != answer.ID {
+			t.Fatalf("ListFiles of the attempt = %+v, %v", files, err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		file := upload(t, repo, "old.txt", "x", nil)
+
+		if err := repo.DeleteFile(ctx, file.ID); err != nil {
+			t.Fatalf("DeleteFile: %v", err)
+		}
+		if _, err := repo.GetFile(ctx, file.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetFile of a deleted file error = %v, want repository.ErrNotFound", err)
+		}
+		if err := repo.DeleteFile(ctx, file.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("second DeleteFile error = %v, want repository.ErrNotFound", err)
+		}
+	})
+
+	t.Run("delete the files of exams and attempts", func(t *testing.T) {
+		repo := newRepo(t)
+		diagram := upload(t, repo, "diagram.png", "a", nil)
+		answer := upload(t, repo, "answer.pdf", "b", &attemptID)
+		other := &models.StoredFile{
+			Filename:     "other.txt",
+			FileMetadata: models.FileMetadata{ContentType: "text/plain; charset=utf-8", ExamID: primitive.NewObjectID()},
+		}
+		if err := repo.Upload(ctx, other, strings.NewReader("c")); err != nil {
+			t.Fatalf("Upload: %v", err)
+		}
+
+		if deleted, err := repo.DeleteAttemptFiles(ctx, []primitive.ObjectID{attemptID}); err != nil || deleted != 1 {
+			t.Fatalf("DeleteAttemptFiles = %d, %v", deleted, err)
+		}
+		if _, err := repo.GetFile(ctx, answer.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetFile of a deleted attempt file error = %v, want repository.ErrNotFound", err)
+		}
+		if _, err := repo.GetFile(ctx, diagram.ID); err != nil {
+			t.Fatalf("GetFile of the exam file: %v", err)
+		}
+
+		upload(t, repo, "answer.pdf", "b", &attemptID)
+		// the attempt uploads go with the exam
+		if deleted, err := repo.DeleteExamFiles(ctx, []primitive.ObjectID{examID}); err != nil || deleted != 2 {
+			t.Fatalf("DeleteExamFiles = %d, %v", deleted, err)
+		}
+		if _, err := repo.GetFile(ctx, diagram.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetFile of a deleted exam file error = %v, want repository.ErrNotFound", err)
+		}
+		if _, err := repo.GetFile(ctx, other.ID); err != nil {
+			t.Fatalf("GetFile of the file of another exam: %v", err)
+		}
+		if deleted, err := repo.DeleteExamFiles(ctx, []primitive.ObjectID{examID}); err != nil || deleted != 0 {
+			t.Fatalf("second DeleteExamFiles = %d, %v", deleted, err)
+		}
+	})
+}
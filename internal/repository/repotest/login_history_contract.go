@@ -0,0 +1,80 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+)
+
+// LoginHistoryRepositoryContract : behaviour every LoginHistoryRepository must have,
+// newRepo must return an empty repository
+func LoginHistoryRepositoryContract(t *testing.T, newRepo func(t *testing.T) repository.LoginHistoryRepository) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+
+	seed := func(t *testing.T, repo repository.LoginHistoryRepository) {
+		t.Helper()
+		events := []models.LoginEvent{
+			{StudentID: "S0001", Success: true, IP: "10.0.0.1", Timestamp: base},
+			{StudentID: "S0001", Success: false, Reason: models.LoginFailedInvalidPassword, IP: "10.0.0.2", Timestamp: base.Add(time.Minute)},
+			{StudentID: "S0002", Success: true, IP: "10.0.0.1", Timestamp: base.Add(2 * time.Minute)},
+			{StudentID: "S0001", Success: true, IP: "10.0.0.1", Timestamp: base.Add(3 * time.Minute)},
+		}
+		for i := range events {
+			if err := repo.Record(ctx, &events[i]); err != nil {
+				t.Fatalf("Record: %v", err)
+			}
+			if events[i].ID.IsZero() {
+				t.Fatalf("Record did not assign an ID")
+			}
+		}
+	}
+
+	t.Run("list for student newest first", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		events, err := repo.ListForStudent(ctx, "S0001", 0)
+		if err != nil {
+			t.Fatalf("ListForStudent: %v", err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("ListForStudent returned %d events, want 3", len(events))
+		}
+		for i := 1; i < len(events); i++ {
+			if events[i].Timestamp.After(events[i-1].Timestamp) {
+				t.Fatalf("ListForStudent is not sorted newest first")
+			}
+		}
+
+		limited, err := repo.ListForStudent(ctx, "S0001", 1)
+		if err != nil || len(limited) != 1 || !limited[0].Timestamp.Equal(base.Add(3*time.Minute)) {
+			t.Fatalf("ListForStudent with limit = %+v, %v", limited, err)
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+
+		failed := false
+		events, err := repo.Search(ctx, models.LoginHistoryFilter{Success: &failed})
+		if err != nil || len(events) != 1 || events[0].Reason != models.LoginFailedInvalidPassword {
+			t.Fatalf("Search failures = %+v, %v", events, err)
+		}
+
+		events, err = repo.Search(ctx, models.LoginHistoryFilter{IP: "10.0.0.1"})
+		if err != nil || len(events) != 3 {
+			t.Fatalf("Search by IP = %+v, %v", events, err)
+		}
+
+		from, to := base.Add(time.Minute), base.Add(2*time.Minute)
+		events, err = repo.Search(ctx, models.LoginHistoryFilter{From: &from, To: &to})
+		if err != nil || len(events) != 2 {
+			t.Fatalf("Search by time range = %+v, %v", events, err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+// Package repotest holds the contract suites every repository implementation must pass,
+// call them from a test with a constructor for the implementation under test, e.g.
+//
+//	repotest.StudentRepositoryContract(t, func(t *testing.T) repository.StudentRepository {
+//		return repository.NewMemoryStudentRepo()
+//	})
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/migrations"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoURIEnv : environment variable with the URI of the MongoDB used by the contract suites
+const MongoURIEnv = "MONGODB_TEST_URI"
+
+// MongoDatabase : returns a fresh, migrated database that is dropped when the test ends,
+// the test is skipped if MONGODB_TEST_URI is not set
+func MongoDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	uri := os.Getenv(MongoURIEnv)
+	if uri == "" {
+		t.Skipf("%s is not set, skipping the MongoDB backed suite", MongoURIEnv)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+
+	db := client.Database(fmt.Sprintf("contract_%d", time.Now().UnixNano()))
+	if _, err := migrations.NewRunner(db).Up(ctx, 0); err != nil {
+		t.Fatalf("failed to migrate the test database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = db.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+
+	return db
+}
@@ -0,0 +1,430 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/repository"
+)
+
+const testPassword = "Passw0rdTest"
+
+// newStudent : a valid student that is not stored yet
+func newStudent(n int) *models.Student {
+	return &models.Student{
+		FirstName:  fmt.Sprintf("First%02d", n),
+		LastName:   fmt.Sprintf("Last%02d", n),
+		Email:      fmt.Sprintf("student%02d@example.com", n),
+		StudentID:  fmt.Sprintf("S%04d", n),
+		Department: "CS",
+	}
+}
+
+// mustCreate : stores a student with testPassword
+func mustCreate(t *testing.T, repo repository.StudentRepository, student *models.Student) {
+	t.Helper()
+	if _, err := repo.CreateStudent(context.Background(), student, testPassword); err != nil {
+		t.Fatalf("CreateStudent(%s): %v", student.StudentID, err)
+	}
+}
+
+// StudentRepositoryContract : behaviour every StudentRepository must have,
+// newRepo must return an empty repository
+func StudentRepositoryContract(t *testing.T, newRepo func(t *testing.T) repository.StudentRepository) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		student := newStudent(1)
+
+		id, err := repo.CreateStudent(ctx, student, testPassword)
+		if err != nil {
+			t.Fatalf("CreateStudent: %v", err)
+		}
+		if id == "" || student.ID.IsZero() {
+			t.Fatalf("CreateStudent did not assign an ID")
+		}
+		if !student.IsActive || student.Status != models.StatusActive || student.Role != "student" {
+			t.Fatalf("CreateStudent did not set the defaults: %+v", student)
+		}
+
+		byID, err := repo.GetStudentByID(ctx, student.StudentID)
+		if err != nil || byID.Email != student.Email {
+			t.Fatalf("GetStudentByID = %+v, %v", byID, err)
+		}
+		byEmail, err := repo.GetStudentByEmail(ctx, student.Email)
+		if err != nil || byEmail.StudentID != student.StudentID {
+			t.Fatalf("GetStudentByEmail = %+v, %v", byEmail, err)
+		}
+	})
+
+	t.Run("missing student", func(t *testing.T) {
+		repo := newRepo(t)
+
+		if _, err := repo.GetStudentByIDFromBD(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetStudentByIDFromBD error = %v, want repository.ErrNotFound", err)
+		}
+		if _, err := repo.VerifyPassword(ctx, "missing", testPassword); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("VerifyPassword error = %v, want repository.ErrNotFound", err)
+		}
+	})
+
+	t.Run("duplicates are rejected", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		sameID := newStudent(2)
+		sameID.StudentID = "S0001"
+		if _, err := repo.CreateStudent(ctx, sameID, testPassword); err == nil {
+			t.Fatalf("CreateStudent accepted a duplicate student_id")
+		}
+
+		sameEmail := newStudent(3)
+		sameEmail.Email = "student01@example.com"
+		if _, err := repo.CreateStudent(ctx, sameEmail, testPassword); err == nil {
+			t.Fatalf("CreateStudent accepted a duplicate email")
+		}
+	})
+
+	t.Run("weak password is rejected", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.CreateStudent(ctx, newStudent(1), "weak"); err == nil {
+			t.Fatalf("CreateStudent accepted a weak password")
+		}
+	})
+
+	t.Run("verify password", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		if _, err := repo.VerifyPassword(ctx, "S0001", testPassword); err != nil {
+			t.Fatalf("VerifyPassword with the right password: %v", err)
+		}
+		if _, err := repo.VerifyPassword(ctx, "S0001", "Wr0ngPassword"); !errors.Is(err, repository.ErrInvalidCredentials) {
+			t.Fatalf("VerifyPassword error = %v, want ErrInvalidCredentials", err)
+		}
+	})
+
+	t.Run("account status", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		student, err := repo.SetAccountStatus(ctx, "S0001", models.AccountStatusChange{
+			Status: models.StatusDeactivated, Reason: "test", ChangedBy: "admin",
+		})
+		if err != nil {
+			t.Fatalf("SetAccountStatus: %v", err)
+		}
+		if student.IsActive || student.Status != models.StatusDeactivated || student.StatusReason != "test" {
+			t.Fatalf("SetAccountStatus returned %+v", student)
+		}
+		if _, err := repo.VerifyPassword(ctx, "S0001", testPassword); !errors.Is(err, repository.ErrAccountInactive) {
+			t.Fatalf("VerifyPassword error = %v, want ErrAccountInactive", err)
+		}
+
+		if _, err := repo.SetAccountStatus(ctx, "S0001", models.AccountStatusChange{Status: models.StatusActive, Reason: "test"}); err != nil {
+			t.Fatalf("SetAccountStatus: %v", err)
+		}
+		if _, err := repo.VerifyPassword(ctx, "S0001", testPassword); err != nil {
+			t.Fatalf("VerifyPassword after reactivation: %v", err)
+		}
+
+		if _, err := repo.SetAccountStatus(ctx, "missing", models.AccountStatusChange{Status: models.StatusActive}); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("SetAccountStatus error = %v, want repository.ErrNotFound", err)
+		}
+	})
+
+	t.Run("expired suspension is lifted", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		ended := time.Now().Add(-time.Minute)
+		if _, err := repo.SetAccountStatus(ctx, "S0001", models.AccountStatusChange{
+			Status: models.StatusSuspended, Reason: "test", SuspendedUntil: &ended,
+		}); err != nil {
+			t.Fatalf("SetAccountStatus: %v", err)
+		}
+
+		student, err := repo.VerifyPassword(ctx, "S0001", testPassword)
+		if err != nil {
+			t.Fatalf("VerifyPassword after the suspension ended: %v", err)
+		}
+		if !student.IsActive || student.SuspendedUntil != nil {
+			t.Fatalf("suspension was not lifted: %+v", student)
+		}
+	})
+
+	t.Run("update profile", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		firstName := "Changed"
+		student, err := repo.UpdateStudentProfile(ctx, "S0001", models.StudentProfileUpdate{FirstName: &firstName})
+		if err != nil {
+			t.Fatalf("UpdateStudentProfile: %v", err)
+		}
+		if student.FirstName != "Changed" || student.LastName != "Last01" {
+			t.Fatalf("UpdateStudentProfile returned %+v", student)
+		}
+
+		stored, err := repo.GetStudentByID(ctx, "S0001")
+		if err != nil || stored.FirstName != "Changed" {
+			t.Fatalf("GetStudentByID after update = %+v, %v", stored, err)
+		}
+	})
+
+	t.Run("change password", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		if err := repo.ChangePassword(ctx, "S0001", "Wr0ngPassword", "N3wPassword"); !errors.Is(err, repository.ErrInvalidCredentials) {
+			t.Fatalf("ChangePassword error = %v, want ErrInvalidCredentials", err)
+		}
+		if err := repo.ChangePassword(ctx, "S0001", testPassword, "weak"); !errors.Is(err, repository.ErrInvalidPassword) {
+			t.Fatalf("ChangePassword error = %v, want ErrInvalidPassword", err)
+		}
+		if err := repo.ChangePassword(ctx, "S0001", testPassword, "N3wPassword"); err != nil {
+			t.Fatalf("ChangePassword: %v", err)
+		}
+		if _, err := repo.VerifyPassword(ctx, "S0001", "N3wPassword"); err != nil {
+			t.Fatalf("VerifyPassword with the new password: %v", err)
+		}
+	})
+
+	t.Run("token revocation", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		// validAfter : the revocation time of the student, it must cover the tokens issued up to issuedAt
+		validAfter := func(t *testing.T, issuedAt time.Time) time.Time {
+			t.Helper()
+			student, err := repo.GetStudentByID(ctx, "S0001")
+			if err != nil {
+				t.Fatalf("GetStudentByID: %v", err)
+			}
+			if student.TokensValidAfter == nil || !issuedAt.Before(*student.TokensValidAfter) {
+				t.Fatalf("tokens_valid_after = %v, want after %v", student.TokensValidAfter, issuedAt)
+			}
+			return *student.TokensValidAfter
+		}
+
+		if student, err := repo.GetStudentByID(ctx, "S0001"); err != nil || student.TokensValidAfter != nil {
+			t.Fatalf("GetStudentByID of a new student = %+v, %v", student, err)
+		}
+
+		issuedAt := time.Now()
+		if err := repo.ChangePassword(ctx, "S0001", testPassword, "N3wPassword"); err != nil {
+			t.Fatalf("ChangePassword: %v", err)
+		}
+		validAfter(t, issuedAt)
+
+		issuedAt = time.Now()
+		if _, err := repo.SetAccountStatus(ctx, "S0001", models.AccountStatusChange{Status: models.StatusSuspended, Reason: "test"}); err != nil {
+			t.Fatalf("SetAccountStatus: %v", err)
+		}
+		suspended := validAfter(t, issuedAt)
+
+		// a reactivation keeps the revocation
+		if _, err := repo.SetAccountStatus(ctx, "S0001", models.AccountStatusChange{Status: models.StatusActive, Reason: "test"}); err != nil {
+			t.Fatalf("SetAccountStatus: %v", err)
+		}
+		if reactivated := validAfter(t, issuedAt); !reactivated.Equal(suspended) {
+			t.Fatalf("tokens_valid_after after reactivation = %v, want %v", reactivated, suspended)
+		}
+
+		issuedAt = time.Now()
+		if err := repo.SoftDeleteStudent(ctx, "S0001", "admin"); err != nil {
+			t.Fatalf("SoftDeleteStudent: %v", err)
+		}
+		if _, err := repo.RestoreStudent(ctx, "S0001"); err != nil {
+			t.Fatalf("RestoreStudent: %v", err)
+		}
+		validAfter(t, issuedAt)
+	})
+
+	t.Run("last login", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		loginAt := time.Now().Truncate(time.Millisecond)
+		if err := repo.UpdateLastLogin(ctx, "S0001", loginAt); err != nil {
+			t.Fatalf("UpdateLastLogin: %v", err)
+		}
+
+		student, err := repo.GetStudentByIDFromBD(ctx, "S0001")
+		if err != nil || student.LastLogin == nil || !student.LastLogin.Equal(loginAt) {
+			t.Fatalf("LastLogin = %v, %v, want %v", student.LastLogin, err, loginAt)
+		}
+	})
+
+	t.Run("bulk insert and existing lookup", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+
+		students := []*models.Student{newStudent(1), newStudent(2), newStudent(3)}
+		failed, err := repo.InsertStudents(ctx, students, []string{testPassword, testPassword, "weak"})
+		if err != nil {
+			t.Fatalf("InsertStudents: %v", err)
+		}
+		if len(failed) != 2 || failed[0] == nil || failed[2] == nil {
+			t.Fatalf("InsertStudents failed = %v, want rows 0 (duplicate) and 2 (weak password)", failed)
+		}
+
+		takenIDs, takenEmails, err := repo.FindExistingStudents(ctx, []string{"S0001", "S0002", "S0003"}, []string{"student02@example.com"})
+		if err != nil {
+			t.Fatalf("FindExistingStudents: %v", err)
+		}
+		if !takenIDs["S0001"] || !takenIDs["S0002"] || takenIDs["S0003"] || !takenEmails["student02@example.com"] {
+			t.Fatalf("FindExistingStudents = %v, %v", takenIDs, takenEmails)
+		}
+	})
+
+	t.Run("list with filters and pagination", func(t *testing.T) {
+		repo := newRepo(t)
+		for n := 1; n <= 5; n++ {
+			student := newStudent(n)
+			if n == 5 {
+				student.Department = "EE"
+			}
+			mustCreate(t, repo, student)
+		}
+
+		seen := map[string]bool{}
+		var previous string
+		cursor := ""
+		for page := 0; ; page++ {
+			students, next, err := repo.ListStudents(ctx, models.StudentListFilter{
+				Department: "CS",
+				SortBy:     "last_name",
+				Limit:      2,
+				Cursor:     cursor,
+			})
+			if err != nil {
+				t.Fatalf("ListStudents: %v", err)
+			}
+
+			for _, student := range students {
+				if student.Department != "CS" || seen[student.StudentID] || student.LastName <= previous {
+					t.Fatalf("ListStudents page %d returned an unexpected student %+v", page, student)
+				}
+				if student.PasswordHash != "" {
+					t.Fatalf("ListStudents leaked the password hash")
+				}
+				seen[student.StudentID] = true
+				previous = student.LastName
+			}
+
+			if next == "" {
+				break
+			}
+			if page > 3 {
+				t.Fatalf("ListStudents did not stop paginating")
+			}
+			cursor = next
+		}
+
+		if len(seen) != 4 {
+			t.Fatalf("ListStudents returned %d students, want 4", len(seen))
+		}
+
+		students, _, err := repo.ListStudents(ctx, models.StudentListFilter{Search: "student05"})
+		if err != nil || len(students) != 1 || students[0].StudentID != "S0005" {
+			t.Fatalf("ListStudents search = %+v, %v", students, err)
+		}
+		// the beginning of a student ID or an email matches too, not only whole words
+		for search, want := range map[string]int{"S000": 5, "student05@exa": 1, "S0005": 1, "tudent": 0} {
+			students, _, err := repo.ListStudents(ctx, models.StudentListFilter{Search: search})
+			if err != nil || len(students) != want {
+				t.Fatalf("ListStudents search %q = %d students, %v, want %d", search, len(students), err, want)
+			}
+		}
+
+		if _, _, err := repo.ListStudents(ctx, models.StudentListFilter{Cursor: "not-a-cursor"}); !errors.Is(err, repository.ErrInvalidCursor) {
+			t.Fatalf("ListStudents error = %v, want ErrInvalidCursor", err)
+		}
+	})
+
+	t.Run("versioned updates", func(t *testing.T) {
+		repo := newRepo(t)
+		student := newStudent(1)
+		mustCreate(t, repo, student)
+
+		firstName := "Changed"
+		stale := student.Version
+		updated, err := repo.UpdateStudentProfile(ctx, "S0001", models.StudentProfileUpdate{FirstName: &firstName, ExpectedVersion: &stale})
+		if err != nil || updated.Version != stale+1 {
+			t.Fatalf("UpdateStudentProfile = %+v, %v", updated, err)
+		}
+
+		if _, err := repo.UpdateStudentProfile(ctx, "S0001", models.StudentProfileUpdate{FirstName: &firstName, ExpectedVersion: &stale}); !errors.Is(err, repository.ErrVersionConflict) {
+			t.Fatalf("UpdateStudentProfile with a stale version error = %v, want ErrVersionConflict", err)
+		}
+		if _, err := repo.SetAccountStatus(ctx, "S0001", models.AccountStatusChange{Status: models.StatusDeactivated, ExpectedVersion: &stale}); !errors.Is(err, repository.ErrVersionConflict) {
+			t.Fatalf("SetAccountStatus with a stale version error = %v, want ErrVersionConflict", err)
+		}
+		if _, err := repo.UpdateStudentProfile(ctx, "missing", models.StudentProfileUpdate{FirstName: &firstName, ExpectedVersion: &stale}); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("UpdateStudentProfile of a missing student error = %v, want repository.ErrNotFound", err)
+		}
+
+		current := updated.Version
+		if _, err := repo.SetAccountStatus(ctx, "S0001", models.AccountStatusChange{Status: models.StatusDeactivated, ExpectedVersion: &current}); err != nil {
+			t.Fatalf("SetAccountStatus with the current version: %v", err)
+		}
+	})
+
+	t.Run("trash", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newStudent(1))
+		mustCreate(t, repo, newStudent(2))
+
+		if err := repo.SoftDeleteStudent(ctx, "S0001", "admin"); err != nil {
+			t.Fatalf("SoftDeleteStudent: %v", err)
+		}
+		if _, err := repo.GetStudentByID(ctx, "S0001"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetStudentByID of a deleted student error = %v, want repository.ErrNotFound", err)
+		}
+		if _, err := repo.VerifyPassword(ctx, "S0001", testPassword); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("VerifyPassword of a deleted student error = %v, want repository.ErrNotFound", err)
+		}
+		if err := repo.SoftDeleteStudent(ctx, "S0001", "admin"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("second SoftDeleteStudent error = %v, want repository.ErrNotFound", err)
+		}
+
+		listed, _, err := repo.ListStudents(ctx, models.StudentListFilter{})
+		if err != nil || len(listed) != 1 || listed[0].StudentID != "S0002" {
+			t.Fatalf("ListStudents with a deleted student = %+v, %v", listed, err)
+		}
+		deleted, err := repo.ListDeletedStudents(ctx, 0)
+		if err != nil || len(deleted) != 1 || deleted[0].StudentID != "S0001" || deleted[0].DeletedBy != "admin" || deleted[0].DeletedAt == nil {
+			t.Fatalf("ListDeletedStudents = %+v, %v", deleted, err)
+		}
+
+		restored, err := repo.RestoreStudent(ctx, "S0001")
+		if err != nil || restored.DeletedAt != nil {
+			t.Fatalf("RestoreStudent = %+v, %v", restored, err)
+		}
+		if _, err := repo.GetStudentByID(ctx, "S0001"); err != nil {
+			t.Fatalf("GetStudentByID after restore: %v", err)
+		}
+		if _, err := repo.RestoreStudent(ctx, "S0002"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("RestoreStudent of a live student error = %v, want repository.ErrNotFound", err)
+		}
+
+		if err := repo.SoftDeleteStudent(ctx, "S0001", "admin"); err != nil {
+			t.Fatalf("SoftDeleteStudent: %v", err)
+		}
+		if purged, err := repo.PurgeDeletedStudents(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+			t.Fatalf("PurgeDeletedStudents within the retention = %d, %v", purged, err)
+		}
+		if purged, err := repo.PurgeDeletedStudents(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+			t.Fatalf("PurgeDeletedStudents = %d, %v", purged, err)
+		}
+		if _, err := repo.RestoreStudent(ctx, "S0001"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("RestoreStudent of a purged student error = %v, want repository.ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache"
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/mongodb"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"github.com/Glorified-Toaster/senior-project/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// StudentCacheInvalidator : change handler evicting the cached entries of every changed student and
+// announcing the eviction to the other instances, so that writes made elsewhere (another instance,
+// a CLI command, the mongo shell) are not served stale from the cache
+func StudentCacheInvalidator(c cache.Store) mongodb.ChangeHandler {
+	return func(ctx context.Context, event *mongodb.ChangeEvent) error {
+		var students []*models.Student
+		for _, raw := range []bson.Raw{event.FullDocumentBeforeChange, event.FullDocument} {
+			if len(raw) == 0 {
+				continue
+			}
+			var student models.Student
+			if err := bson.Unmarshal(raw, &student); err != nil {
+				return fmt.Errorf("failed to decode changed student: %w", err)
+			}
+			if student.StudentID != "" {
+				students = append(students, &student)
+			}
+		}
+
+		// the student ID and usually the email are the same before and after
+		keys := slices.Compact(slices.Sorted(slices.Values(studentCacheKeys(students...))))
+		if len(keys) == 0 {
+			return nil
+		}
+
+		if err := c.Invalidate(keys...); err != nil {
+			return fmt.Errorf("failed to evict changed students: %w", err)
+		}
+		if err := c.InvalidateTags(ctx, slices.Compact(slices.Sorted(slices.Values(studentCacheTags(students...))))...); err != nil {
+			return fmt.Errorf("failed to evict changed students: %w", err)
+		}
+		if err := c.PublishInvalidation(ctx, keys...); err != nil {
+			utils.LogErrorWithLevel("warn",
+				utils.DragonflyFailedToDeleteCache.Type,
+				utils.DragonflyFailedToDeleteCache.Code,
+				"failed to publish cache invalidation",
+				err,
+			)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/helpers"
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// liftSuspension : status change applied when a suspension has run out
+var liftSuspension = models.AccountStatusChange{
+	Status:    models.StatusActive,
+	Reason:    "suspension expired",
+	ChangedBy: "system",
+}
+
+// prepareStudent : validates and hashes the password and fills the server-side fields of a new student
+func prepareStudent(student *models.Student, password string) error {
+	// validate password
+	if err := helpers.ValidatePassword(password); err != nil {
+		return fmt.Errorf("password validate error : %w", err)
+	}
+
+	// hash password
+	hashedPassword, err := helpers.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password : %w", err)
+	}
+
+	timeNow := time.Now()
+
+	// set student
+	student.ID = primitive.NewObjectID()
+	student.Role = "student"
+	student.CreatedAt = timeNow
+	student.UpdatedAt = timeNow
+	student.IsActive = true
+	student.Status = models.StatusActive
+	student.PasswordHash = hashedPassword
+	// init an empty slice
+	student.RequiredExams = []primitive.ObjectID{}
+	student.CompletedExams = []models.CompletedExam{}
+
+	return nil
+}
+
+// accountStatus : returns the status of the account, falling back to is_active for older documents
+func accountStatus(student *models.Student) string {
+	if student.Status != "" {
+		return student.Status
+	}
+	if student.IsActive {
+		return models.StatusActive
+	}
+	return models.StatusDeactivated
+}
+
+// suspensionExpired : reports whether the student is suspended and the suspension has ended
+func suspensionExpired(student *models.Student) bool {
+	return student.Status == models.StatusSuspended &&
+		student.SuspendedUntil != nil &&
+		time.Now().After(*student.SuspendedUntil)
+}
+
+// tokensValidAfter : the revocation time of the tokens issued up to now. Mongo keeps
+// milliseconds, rounding up also revokes a token issued earlier in the same millisecond
+func tokensValidAfter(now time.Time) time.Time {
+	return now.Truncate(time.Millisecond).Add(time.Millisecond)
+}
+
+// checkStudentPassword : compares the plain password with the stored hash
+func checkStudentPassword(student *models.Student, plainPassword string) error {
+	if student.PasswordHash == "" {
+		return fmt.Errorf("password not set for this account")
+	}
+
+	// Check the password
+	if err := helpers.CheckWithHashedPassword(plainPassword, student.PasswordHash); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return nil
+}
+
+// hashNewPassword : validates a replacement password and returns its hash
+func hashNewPassword(currentPassword, newPassword string) (string, error) {
+	if currentPassword == newPassword {
+		return "", fmt.Errorf("%w: must be different from the current password", ErrInvalidPassword)
+	}
+
+	// validate password
+	if err := helpers.ValidatePassword(newPassword); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPassword, err)
+	}
+
+	// hash password
+	hashedPassword, err := helpers.HashPassword(newPassword)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password : %w", err)
+	}
+	return hashedPassword, nil
+}
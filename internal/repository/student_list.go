@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexNotFound : server error code of a $text query on a collection without a text index
+const indexNotFound = 27
+
+var (
+	defaultListLimit int64 = 25
+	maxListLimit     int64 = 200
+)
+
+// StudentSortFields : fields a student listing can be sorted by
+var StudentSortFields = []string{"created_at", "last_name", "first_name", "student_id", "email"}
+
+// ErrInvalidCursor : the page cursor could not be decoded or does not match the sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// listCursor : position of the last document of a page, Value is the sort field value
+type listCursor struct {
+	SortBy string             `bson:"s"`
+	Value  any                `bson:"v"`
+	ID     primitive.ObjectID `bson:"id"`
+}
+
+// ListStudents : returns one page of students matching the filter and the cursor of the next page,
+// the cursor is empty on the last page
+func (r *MongoStudentRepository) ListStudents(ctx context.Context, filter models.StudentListFilter) ([]models.Student, string, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	if !slices.Contains(StudentSortFields, sortBy) {
+		return nil, "", fmt.Errorf("unsupported sort field %q", sortBy)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	direction := 1
+	if filter.SortDesc {
+		direction = -1
+	}
+
+	conditions := studentListConditions(filter)
+
+	if filter.Cursor != "" {
+		cursor, err := decodeListCursor(filter.Cursor)
+		if err != nil || cursor.SortBy != sortBy {
+			return nil, "", ErrInvalidCursor
+		}
+
+		operator := "$gt"
+		if filter.SortDesc {
+			operator = "$lt"
+		}
+		// keyset pagination : after the last (value, _id) pair of the previous page
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{sortBy: bson.M{operator: cursor.Value}},
+			bson.M{sortBy: cursor.Value, "_id": bson.M{operator: cursor.ID}},
+		}})
+	}
+
+	query := bson.M{}
+	if len(conditions) > 0 {
+		query["$and"] = conditions
+	}
+
+	// fetch one extra document to know if there is a next page
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: sortBy, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(limit + 1).
+		SetProjection(bson.M{"password_hash": 0})
+
+	results, err := r.collection.Find(ctx, query, findOpts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list students: %w", searchIndexError(err))
+	}
+
+	students := []models.Student{}
+	if err := results.All(ctx, &students); err != nil {
+		return nil, "", fmt.Errorf("failed to decode students: %w", searchIndexError(err))
+	}
+
+	if int64(len(students)) <= limit {
+		return students, "", nil
+	}
+
+	students = students[:limit]
+	next, err := encodeListCursor(sortBy, &students[len(students)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	return students, next, nil
+}
+
+// studentListConditions : translates the filter to mongo conditions
+func studentListConditions(filter models.StudentListFilter) []bson.M {
+	// students in the trash are only listed by ListDeletedStudents
+	conditions := []bson.M{{"deleted_at": nil}}
+
+	if filter.Department != "" {
+		conditions = append(conditions, bson.M{"department": filter.Department})
+	}
+	if filter.IsActive != nil {
+		conditions = append(conditions, bson.M{"is_active": *filter.IsActive})
+	}
+	if filter.CreatedFrom != nil || filter.CreatedTo != nil {
+		createdAt := bson.M{}
+		if filter.CreatedFrom != nil {
+			createdAt["$gte"] = *filter.CreatedFrom
+		}
+		if filter.CreatedTo != nil {
+			createdAt["$lte"] = *filter.CreatedTo
+		}
+		conditions = append(conditions, bson.M{"created_at": createdAt})
+	}
+	if filter.RequiredExam != nil {
+		conditions = append(conditions, bson.M{"required_exams": *filter.RequiredExam})
+	}
+	// the text index of the names, email and student ID matches any of the whole words, the
+	// beginning of a student ID or an email as typed matches too. $text needs an index behind
+	// every other branch of the $or, which the unique indexes of both fields are
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		prefix := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(search)}
+		branches := bson.A{bson.M{"student_id": prefix}, bson.M{"email": prefix}}
+		if terms := searchTerms(search); len(terms) > 0 {
+			branches = append(branches, bson.M{"$text": bson.M{"$search": strings.Join(terms, " ")}})
+		}
+		conditions = append(conditions, bson.M{"$or": branches})
+	}
+
+	return conditions
+}
+
+// searchIndexError : ErrSearchUnavailable when the search failed for lack of the text index,
+// any other error is returned as is
+func searchIndexError(err error) error {
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(indexNotFound) {
+		return fmt.Errorf("%w: %v", ErrSearchUnavailable, err)
+	}
+	return err
+}
+
+// searchTerms : the words of a search, lower cased. Punctuation separates words like in the text
+// index, so quotes and a leading "-" cannot turn into phrase or negation operators
+func searchTerms(search string) []string {
+	return strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// encodeListCursor : encodes the position of the student in the sort order
+func encodeListCursor(sortBy string, student *models.Student) (string, error) {
+	raw, err := bson.Marshal(student)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	value, err := bson.Raw(raw).LookupErr(sortBy)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	data, err := bson.Marshal(listCursor{SortBy: sortBy, Value: value, ID: student.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeListCursor : decodes a cursor produced by encodeListCursor
+func decodeListCursor(cursor string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded listCursor
+	if err := bson.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestListCursorRoundTrip(t *testing.T) {
+	student := &models.Student{
+		ID:        primitive.NewObjectID(),
+		LastName:  "Doe",
+		Email:     "jane@example.com",
+		StudentID: "S0001",
+		CreatedAt: time.Date(2026, 3, 1, 10, 30, 0, 123456789, time.UTC),
+	}
+	raw, err := bson.Marshal(student)
+	if err != nil {
+		t.Fatalf("bson.Marshal: %v", err)
+	}
+
+	for _, sortBy := range StudentSortFields {
+		t.Run(sortBy, func(t *testing.T) {
+			encoded, err := encodeListCursor(sortBy, student)
+			if err != nil {
+				t.Fatalf("encodeListCursor: %v", err)
+			}
+
+			cursor, err := decodeListCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeListCursor: %v", err)
+			}
+			if cursor.SortBy != sortBy || cursor.ID != student.ID {
+				t.Fatalf("decoded cursor = %+v", cursor)
+			}
+
+			// the position is the value stored in the document, with its bson type
+			reencoded, err := bson.Marshal(cursor)
+			if err != nil {
+				t.Fatalf("bson.Marshal: %v", err)
+			}
+			if got, want := bson.Raw(reencoded).Lookup("v"), bson.Raw(raw).Lookup(sortBy); !got.Equal(want) {
+				t.Fatalf("cursor value = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestListCursorInvalid(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGJzb24"} {
+		if _, err := decodeListCursor(cursor); err == nil {
+			t.Fatalf("decodeListCursor(%q) succeeded", cursor)
+		}
+	}
+}
+
+func TestListCursorOfAnotherSort(t *testing.T) {
+	// a cursor only continues the listing it was taken from
+	students := NewMemoryStudentRepo()
+	encoded, err := encodeListCursor("email", &models.Student{ID: primitive.NewObjectID(), Email: "a@example.com"})
+	if err != nil {
+		t.Fatalf("encodeListCursor: %v", err)
+	}
+	if _, _, err := students.ListStudents(t.Context(), models.StudentListFilter{SortBy: "last_name", Cursor: encoded}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("ListStudents with the cursor of another sort error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := map[string][]string{
+		"":                     nil,
+		"Jane":                 {"jane"},
+		"  jane   DOE ":        {"jane", "doe"},
+		"jane.doe@example.com": {"jane", "doe", "example", "com"},
+		`-jane "doe"`:          {"jane", "doe"},
+		"S0001":                {"s0001"},
+	}
+	for search, want := range tests {
+		if got := searchTerms(search); !slices.Equal(got, want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", search, got, want)
+		}
+	}
+}
+
+func TestSearchIndexError(t *testing.T) {
+	missing := mongo.CommandError{Code: indexNotFound, Message: "text index required for $text query"}
+	if err := searchIndexError(missing); !errors.Is(err, ErrSearchUnavailable) {
+		t.Fatalf("searchIndexError of a missing text index = %v, want ErrSearchUnavailable", err)
+	}
+	other := mongo.CommandError{Code: 2, Message: "bad value"}
+	if err := searchIndexError(other); errors.Is(err, ErrSearchUnavailable) {
+		t.Fatalf("searchIndexError of another error = %v", err)
+	}
+}
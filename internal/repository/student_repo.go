@@ -2,61 +2,52 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Glorified-Toaster/senior-project/internal/config/db/cache"
-	"github.com/Glorified-Toaster/senior-project/internal/helpers"
+	"github.com/Glorified-Toaster/senior-project/internal/config/db/mongodb"
 	"github.com/Glorified-Toaster/senior-project/internal/models"
 	"github.com/Glorified-Toaster/senior-project/internal/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var cacheTTL int = 5 // mins
-
-type StudentRepository struct {
+type MongoStudentRepository struct {
 	collection *mongo.Collection
-	cache      *cache.Cache
+	uow        *mongodb.UnitOfWork
+	files      FileRepository
+	cache      cache.Store
+	// students : the cached students, nil without a cache
+	students *cache.TypedCache[*models.Student]
 }
 
-func NewStudentRepo(ctx context.Context, database *mongo.Database, c *cache.Cache) *StudentRepository {
-	return &StudentRepository{
+func NewStudentRepo(ctx context.Context, database *mongo.Database, c cache.Store, files FileRepository) *MongoStudentRepository {
+	r := &MongoStudentRepository{
 		collection: database.Collection("students"),
+		uow:        mongodb.NewUnitOfWork(database.Client()),
+		files:      files,
 		cache:      c,
 	}
+	if c != nil {
+		// msgpack skips the json:"-" fields, the password hash is never cached
+		r.students = cache.NewTypedCache[*models.Student](c, cache.TypedCacheOptions{Codec: cache.MsgpackCodec})
+	}
+	return r
 }
 
-func (r *StudentRepository) CreateStudent(ctx context.Context, student *models.Student, password string) (string, error) {
+func (r *MongoStudentRepository) CreateStudent(ctx context.Context, student *models.Student, password string) (string, error) {
 	if student == nil {
 		return "", fmt.Errorf("nil student is provided")
 	}
 
-	// validate password
-	if err := helpers.ValidatePassword(password); err != nil {
-		return "", fmt.Errorf("password validate error : %w", err)
-	}
-
-	// hash password
-	hashedPassword, err := helpers.HashPassword(password)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash password : %w", err)
+	if err := prepareStudent(student, password); err != nil {
+		return "", err
 	}
 
-	timeNow := time.Now()
-
-	// set student
-	student.ID = primitive.NewObjectID()
-	student.Role = "student"
-	student.CreatedAt = timeNow
-	student.UpdatedAt = timeNow
-	student.IsActive = true
-	student.PasswordHash = hashedPassword
-	// init an empty slice
-	student.RequiredExams = []primitive.ObjectID{}
-	student.CompletedExams = []models.CompletedExam{}
-
 	// add to mongo
 	result, err := r.collection.InsertOne(ctx, student)
 	if err != nil {
@@ -68,80 +59,109 @@ func (r *StudentRepository) CreateStudent(ctx context.Context, student *models.S
 
 	studentID := result.InsertedID.(primitive.ObjectID).Hex()
 
-	// set to cache
+	// set to cache, dropping a cached "not found" of the email
 	if r.cache != nil {
+		r.invalidateStudentCache(student)
 		cacheKey := fmt.Sprintf("user:%s", student.StudentID)
-		if err := r.cache.Set(cacheKey, student, time.Duration(cacheTTL)*time.Minute); err != nil {
+		if err := r.students.Set(ctx, cacheKey, student, cache.DefaultTTL()); err != nil {
 			utils.LogErrorWithLevel("warn",
 				utils.DragonflyFailedToWriteCache.Type,
 				utils.DragonflyFailedToWriteCache.Code,
 				utils.DragonflyFailedToWriteCache.Msg,
 				err,
 			)
+		} else {
+			r.tagStudentEntry(ctx, cacheKey, student)
 		}
 	}
 
 	return studentID, nil
 }
 
-func (r *StudentRepository) fetchStudentFromDB(ctx context.Context, searchType, searchValue string) (*models.Student, error) {
+func (r *MongoStudentRepository) fetchStudentFromDB(ctx context.Context, searchType, searchValue string) (*models.Student, error) {
 	var student models.Student
-	err := r.collection.FindOne(ctx, bson.M{searchType: searchValue}).Decode(&student)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{searchType: searchValue})).Decode(&student)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "student", searchValue)
 	}
 	return &student, nil
 }
 
-func (r *StudentRepository) GetStudentByEmail(ctx context.Context, email string) (*models.Student, error) {
-	var student models.Student
+// fetchStudentForCache : the fetch of the cached lookups, a missing student is cached as not found
+// and a found one has cacheKey tagged with its student tag
+func (r *MongoStudentRepository) fetchStudentForCache(cacheKey, searchType, searchValue string) func(ctx context.Context) (*models.Student, error) {
+	return func(ctx context.Context) (*models.Student, error) {
+		student, err := r.fetchStudentFromDB(ctx, searchType, searchValue)
+		if errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("%w: %w", cache.ErrNotFound, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.tagStudentEntry(ctx, cacheKey, student)
+		return student, nil
+	}
+}
+
+// tagStudentEntry : tags cacheKey with the student so its entry is evicted with the student's
+// other entries, even the ones under an email the student no longer has
+func (r *MongoStudentRepository) tagStudentEntry(ctx context.Context, cacheKey string, student *models.Student) {
+	err := r.cache.Tag(ctx, cacheKey, cache.DefaultTTL(), studentCacheTag(student.StudentID))
+	if err != nil && !errors.Is(err, cache.ErrUnavailable) {
+		utils.LogErrorWithLevel("warn",
+			utils.DragonflyFailedToWriteCache.Type,
+			utils.DragonflyFailedToWriteCache.Code,
+			utils.DragonflyFailedToWriteCache.Msg,
+			err,
+		)
+	}
+}
 
+func (r *MongoStudentRepository) GetStudentByEmail(ctx context.Context, email string) (*models.Student, error) {
 	cacheKey := fmt.Sprintf("user:email:%s", email)
 
-	if r.cache == nil {
+	if r.students == nil {
 		return r.fetchStudentFromDB(ctx, "email", email)
 	}
 
-	err := r.cache.GetFromCacheOrFetchDB(
+	student, err := r.students.GetOrFetch(
 		ctx,
 		cacheKey,
-		&student,
-		func() (any, error) {
-			return r.fetchStudentFromDB(ctx, "email", email)
-		},
-		time.Duration(cacheTTL)*time.Minute,
+		r.fetchStudentForCache(cacheKey, "email", email),
+		cache.DefaultTTL(),
 	)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, &NotFoundError{Resource: "student", Key: email}
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &student, nil
+	return student, nil
 }
 
-func (r *StudentRepository) GetStudentByID(ctx context.Context, studentID string) (*models.Student, error) {
-	var student models.Student
-
+func (r *MongoStudentRepository) GetStudentByID(ctx context.Context, studentID string) (*models.Student, error) {
 	cacheKey := fmt.Sprintf("user:%s", studentID)
 
-	if r.cache == nil {
+	if r.students == nil {
 		return r.fetchStudentFromDB(ctx, "student_id", studentID)
 	}
 
-	err := r.cache.GetFromCacheOrFetchDB(
+	student, err := r.students.GetOrFetch(
 		ctx,
 		cacheKey,
-		&student,
-		func() (any, error) {
-			return r.fetchStudentFromDB(ctx, "student_id", studentID)
-		},
-		time.Duration(cacheTTL)*time.Minute,
+		r.fetchStudentForCache(cacheKey, "student_id", studentID),
+		cache.DefaultTTL(),
 	)
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil, &NotFoundError{Resource: "student", Key: studentID}
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &student, nil
+	return student, nil
 }
 
-func (r *StudentRepository) GetStudentByIDFromBD(ctx context.Context, studentID string) (*models.Student, error) {
+func (r *MongoStudentRepository) GetStudentByIDFromBD(ctx context.Context, studentID string) (*models.Student, error) {
 	var student *models.Student
 	var err error
 
@@ -153,27 +173,414 @@ func (r *StudentRepository) GetStudentByIDFromBD(ctx context.Context, studentID
 	return student, nil
 }
 
-func (r *StudentRepository) VerifyPassword(ctx context.Context, studentID, plainPassword string) (*models.Student, error) {
+func (r *MongoStudentRepository) VerifyPassword(ctx context.Context, studentID, plainPassword string) (*models.Student, error) {
 	student, err := r.GetStudentByIDFromBD(ctx, studentID)
 	if err != nil {
 		return nil, fmt.Errorf("student not found: %w", err)
 	}
 
 	if !student.IsActive {
-		return nil, fmt.Errorf("account is deactivated")
+		// lift an expired suspension on the first login attempt after it ends
+		if !suspensionExpired(student) {
+			return nil, fmt.Errorf("%w: account is %s", ErrAccountInactive, accountStatus(student))
+		}
+
+		student, err = r.SetAccountStatus(ctx, studentID, liftSuspension)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lift expired suspension: %w", err)
+		}
 	}
 
-	hashedPassword := student.PasswordHash
+	if err := checkStudentPassword(student, plainPassword); err != nil {
+		return nil, err
+	}
 
-	if student.PasswordHash == "" {
-		return nil, fmt.Errorf("password not set for this account")
+	return student, nil
+}
+
+// SetAccountStatus : applies an admin status change, drops the cached copies of
+// the student and revokes the student's tokens unless the account is reactivated
+func (r *MongoStudentRepository) SetAccountStatus(ctx context.Context, studentID string, change models.AccountStatusChange) (*models.Student, error) {
+	timeNow := time.Now()
+
+	set := bson.M{
+		"status":            change.Status,
+		"is_active":         change.Status == models.StatusActive,
+		"status_reason":     change.Reason,
+		"status_changed_by": change.ChangedBy,
+		"status_changed_at": timeNow,
+		"updated_at":        timeNow,
+	}
+	update := bson.M{"$set": set}
+
+	if change.Status != models.StatusActive {
+		set["tokens_valid_after"] = tokensValidAfter(timeNow)
+	}
+	if change.SuspendedUntil != nil {
+		set["suspended_until"] = *change.SuspendedUntil
+	} else {
+		update["$unset"] = bson.M{"suspended_until": ""}
 	}
 
-	// Check the password
-	err = helpers.CheckWithHashedPassword(plainPassword, hashedPassword)
+	filter := notDeleted(bson.M{"student_id": studentID})
+
+	var student models.Student
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		versionFilter(filter, change.ExpectedVersion),
+		versioned(update),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&student)
 	if err != nil {
-		return nil, fmt.Errorf("invalid password: %w", err)
+		err = notFound(versionConflict(ctx, r.collection, filter, change.ExpectedVersion, err), "student", studentID)
+		return nil, fmt.Errorf("failed to update account status: %w", err)
 	}
 
-	return student, nil
+	r.invalidateStudentCache(&student)
+
+	return &student, nil
+}
+
+// invalidateStudentCache : removes every cached entry of the student
+func (r *MongoStudentRepository) invalidateStudentCache(student *models.Student) {
+	invalidateCachedStudents(r.cache, student)
+}
+
+// invalidateCachedStudents : removes every cached entry of the students, the students need
+// at least their student_id and email
+func invalidateCachedStudents(c cache.Store, students ...*models.Student) {
+	if c == nil || len(students) == 0 {
+		return
+	}
+
+	// the keys cover the cached "not found" entries, which carry no tag
+	if err := c.Invalidate(studentCacheKeys(students...)...); err != nil {
+		utils.LogErrorWithLevel("warn",
+			utils.DragonflyFailedToDeleteCache.Type,
+			utils.DragonflyFailedToDeleteCache.Code,
+			utils.DragonflyFailedToDeleteCache.Msg,
+			err,
+		)
+	}
+	if err := c.InvalidateTags(context.Background(), studentCacheTags(students...)...); err != nil {
+		utils.LogErrorWithLevel("warn",
+			utils.DragonflyFailedToDeleteCache.Type,
+			utils.DragonflyFailedToDeleteCache.Code,
+			utils.DragonflyFailedToDeleteCache.Msg,
+			err,
+		)
+	}
+}
+
+// studentCacheKeys : the cache keys a student can be stored under
+func studentCacheKeys(students ...*models.Student) []string {
+	keys := make([]string, 0, 2*len(students))
+	for _, student := range students {
+		keys = append(keys, fmt.Sprintf("user:%s", student.StudentID))
+		if student.Email != "" {
+			keys = append(keys, fmt.Sprintf("user:email:%s", student.Email))
+		}
+	}
+	return keys
+}
+
+// studentCacheTag : the tag of every cached entry of a student
+func studentCacheTag(studentID string) string {
+	return fmt.Sprintf("student:%s", studentID)
+}
+
+func studentCacheTags(students ...*models.Student) []string {
+	tags := make([]string, 0, len(students))
+	for _, student := range students {
+		tags = append(tags, studentCacheTag(student.StudentID))
+	}
+	return tags
+}
+
+// UpdateStudentProfile : updates the editable profile fields of a student
+func (r *MongoStudentRepository) UpdateStudentProfile(ctx context.Context, studentID string, profile models.StudentProfileUpdate) (*models.Student, error) {
+	set := bson.M{"updated_at": time.Now()}
+
+	if profile.FirstName != nil {
+		set["first_name"] = *profile.FirstName
+	}
+	if profile.LastName != nil {
+		set["last_name"] = *profile.LastName
+	}
+	if profile.Department != nil {
+		set["department"] = *profile.Department
+	}
+
+	filter := notDeleted(bson.M{"student_id": studentID})
+
+	var student models.Student
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		versionFilter(filter, profile.ExpectedVersion),
+		versioned(bson.M{"$set": set}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&student)
+	if err != nil {
+		err = notFound(versionConflict(ctx, r.collection, filter, profile.ExpectedVersion, err), "student", studentID)
+		return nil, fmt.Errorf("failed to update student profile: %w", err)
+	}
+
+	r.invalidateStudentCache(&student)
+
+	return &student, nil
+}
+
+// ChangePassword : replaces the password of a student after checking the current one and
+// revokes the tokens issued before the change
+func (r *MongoStudentRepository) ChangePassword(ctx context.Context, studentID, currentPassword, newPassword string) error {
+	student, err := r.VerifyPassword(ctx, studentID, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashNewPassword(currentPassword, newPassword)
+	if err != nil {
+		return err
+	}
+
+	timeNow := time.Now()
+
+	// the student can be deleted between the check of the password and the update,
+	// the sessions opened with the old password end with it
+	result, err := r.collection.UpdateOne(ctx,
+		notDeleted(bson.M{"_id": student.ID}),
+		versioned(bson.M{"$set": bson.M{
+			"password_hash":      hashedPassword,
+			"tokens_valid_after": tokensValidAfter(timeNow),
+			"updated_at":         timeNow,
+		}}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{Resource: "student", Key: studentID}
+	}
+
+	r.invalidateStudentCache(student)
+
+	return nil
+}
+
+// UpdateLastLogin : records the time of the latest successful login
+func (r *MongoStudentRepository) UpdateLastLogin(ctx context.Context, studentID string, loginAt time.Time) error {
+	var student models.Student
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		notDeleted(bson.M{"student_id": studentID}),
+		versioned(bson.M{"$set": bson.M{"last_login": loginAt}}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&student)
+	if err != nil {
+		return fmt.Errorf("failed to update last login: %w", notFound(err, "student", studentID))
+	}
+
+	r.invalidateStudentCache(&student)
+
+	return nil
+}
+
+// InsertStudents : creates several students at once, passwords[i] is the initial password of students[i].
+// Insertion is unordered so one bad document does not stop the batch, the returned map holds the
+// error of every student (by index) that was not inserted
+func (r *MongoStudentRepository) InsertStudents(ctx context.Context, students []*models.Student, passwords []string) (map[int]error, error) {
+	if len(students) != len(passwords) {
+		return nil, fmt.Errorf("got %d students but %d passwords", len(students), len(passwords))
+	}
+
+	failed := map[int]error{}
+	docs := make([]any, 0, len(students))
+	prepared := make([]*models.Student, 0, len(students))
+	// position in docs -> position in students
+	docIndex := make([]int, 0, len(students))
+
+	for i, student := range students {
+		if err := prepareStudent(student, passwords[i]); err != nil {
+			failed[i] = err
+			continue
+		}
+		docs = append(docs, student)
+		prepared = append(prepared, student)
+		docIndex = append(docIndex, i)
+	}
+
+	if len(docs) == 0 {
+		return failed, nil
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	// new students may have been looked up and cached as not found
+	defer invalidateCachedStudents(r.cache, prepared...)
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+			return nil, fmt.Errorf("failed to insert students: %w", err)
+		}
+
+		for _, writeErr := range bulkErr.WriteErrors {
+			i := docIndex[writeErr.Index]
+			if mongo.IsDuplicateKeyError(writeErr) {
+				failed[i] = fmt.Errorf("student with this ID or email already exists")
+			} else {
+				failed[i] = writeErr
+			}
+		}
+	}
+
+	return failed, nil
+}
+
+// FindExistingStudents : returns which of the given student IDs and emails are already taken,
+// students in the trash still hold their unique student_id and email
+func (r *MongoStudentRepository) FindExistingStudents(ctx context.Context, studentIDs, emails []string) (map[string]bool, map[string]bool, error) {
+	takenIDs := map[string]bool{}
+	takenEmails := map[string]bool{}
+
+	if len(studentIDs) == 0 && len(emails) == 0 {
+		return takenIDs, takenEmails, nil
+	}
+
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"$or": bson.A{
+			bson.M{"student_id": bson.M{"$in": studentIDs}},
+			bson.M{"email": bson.M{"$in": emails}},
+		}},
+		options.Find().SetProjection(bson.M{"student_id": 1, "email": 1}),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up existing students: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var student models.Student
+		if err := cursor.Decode(&student); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode student: %w", err)
+		}
+		takenIDs[student.StudentID] = true
+		takenEmails[student.Email] = true
+	}
+
+	return takenIDs, takenEmails, cursor.Err()
+}
+
+// SoftDeleteStudent : moves the student to the trash, the student can no longer log in
+// and the tokens already issued are revoked
+func (r *MongoStudentRepository) SoftDeleteStudent(ctx context.Context, studentID, deletedBy string) error {
+	timeNow := time.Now()
+
+	var student models.Student
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		notDeleted(bson.M{"student_id": studentID}),
+		versioned(bson.M{
+			"$set": bson.M{
+				"deleted_at":         timeNow,
+				"deleted_by":         deletedBy,
+				"tokens_valid_after": tokensValidAfter(timeNow),
+				"updated_at":         timeNow,
+			},
+		}),
+		options.FindOneAndUpdate().SetProjection(bson.M{"student_id": 1, "email": 1}),
+	).Decode(&student)
+	if err != nil {
+		return fmt.Errorf("failed to delete student: %w", notFound(err, "student", studentID))
+	}
+
+	r.invalidateStudentCache(&student)
+
+	return nil
+}
+
+// RestoreStudent : takes the student out of the trash
+func (r *MongoStudentRepository) RestoreStudent(ctx context.Context, studentID string) (*models.Student, error) {
+	var student models.Student
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		inTrash(bson.M{"student_id": studentID}),
+		versioned(bson.M{
+			"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}),
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&student)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore student: %w", notFound(err, "student", studentID))
+	}
+
+	r.invalidateStudentCache(&student)
+
+	return &student, nil
+}
+
+// ListDeletedStudents : students in the trash, most recently deleted first
+func (r *MongoStudentRepository) ListDeletedStudents(ctx context.Context, limit int64) ([]models.Student, error) {
+	results, err := r.collection.Find(ctx,
+		inTrash(bson.M{}),
+		options.Find().
+			SetSort(bson.D{{Key: "deleted_at", Value: -1}}).
+			SetLimit(trashLimit(limit)).
+			SetProjection(bson.M{"password_hash": 0}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted students: %w", err)
+	}
+
+	students := []models.Student{}
+	if err := results.All(ctx, &students); err != nil {
+		return nil, fmt.Errorf("failed to decode students: %w", err)
+	}
+	return students, nil
+}
+
+// PurgeDeletedStudents : hard deletes the students that were moved to the trash before the
+// given time together with their exam attempts and the files uploaded in them, in one
+// transaction. Returns the number of purged students
+func (r *MongoStudentRepository) PurgeDeletedStudents(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	filter := bson.M{"deleted_at": bson.M{"$lt": deletedBefore}}
+	attempts := r.collection.Database().Collection("exam_attempts")
+
+	var purged int64
+	err := r.uow.Do(ctx, func(sessCtx mongo.SessionContext) error {
+		studentIDs, err := r.collection.Distinct(sessCtx, "student_id", filter)
+		if err != nil {
+			return fmt.Errorf("failed to find students to purge: %w", err)
+		}
+		if len(studentIDs) == 0 {
+			purged = 0
+			return nil
+		}
+
+		attemptIDs, err := attempts.Distinct(sessCtx, "_id", bson.M{"student_id": bson.M{"$in": studentIDs}})
+		if err != nil {
+			return fmt.Errorf("failed to find exam attempts to purge: %w", err)
+		}
+		if len(attemptIDs) > 0 {
+			if _, err := r.files.DeleteAttemptFiles(sessCtx, objectIDs(attemptIDs)); err != nil {
+				return fmt.Errorf("failed to purge attempt files: %w", err)
+			}
+			if _, err := attempts.DeleteMany(sessCtx, bson.M{"_id": bson.M{"$in": attemptIDs}}); err != nil {
+				return fmt.Errorf("failed to purge exam attempts: %w", err)
+			}
+		}
+
+		result, err := r.collection.DeleteMany(sessCtx, bson.M{
+			"student_id": bson.M{"$in": studentIDs},
+			"deleted_at": bson.M{"$lt": deletedBefore},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to purge students: %w", err)
+		}
+		purged = result.DeletedCount
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
 }
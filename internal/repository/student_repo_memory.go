@@ -0,0 +1,471 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Glorified-Toaster/senior-project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStudentRepository : in-memory StudentRepository, student_id and email are unique
+// like with the indexes of the mongo collection
+type MemoryStudentRepository struct {
+	mu       sync.RWMutex
+	students map[string]*models.Student // by student_id
+}
+
+func NewMemoryStudentRepo() *MemoryStudentRepository {
+	return &MemoryStudentRepository{
+		students: map[string]*models.Student{},
+	}
+}
+
+// copyStudent : returns a copy that does not share slices with the stored student
+func copyStudent(student *models.Student) *models.Student {
+	clone := *student
+	clone.RequiredExams = slices.Clone(student.RequiredExams)
+	clone.CompletedExams = slices.Clone(student.CompletedExams)
+	return &clone
+}
+
+// studentNotFound : error returned for a missing student, matches the mongo implementation
+func studentNotFound(studentID string) error {
+	return &NotFoundError{Resource: "student", Key: studentID}
+}
+
+// insertLocked : stores a prepared student, the caller holds the write lock
+func (r *MemoryStudentRepository) insertLocked(student *models.Student) error {
+	if _, ok := r.students[student.StudentID]; ok {
+		return fmt.Errorf("student with this ID is already exsists")
+	}
+	for _, existing := range r.students {
+		if existing.Email == student.Email {
+			return fmt.Errorf("student with this ID is already exsists")
+		}
+	}
+
+	r.students[student.StudentID] = copyStudent(student)
+	return nil
+}
+
+func (r *MemoryStudentRepository) CreateStudent(ctx context.Context, student *models.Student, password string) (string, error) {
+	if student == nil {
+		return "", fmt.Errorf("nil student is provided")
+	}
+
+	if err := prepareStudent(student, password); err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.insertLocked(student); err != nil {
+		return "", err
+	}
+	return student.ID.Hex(), nil
+}
+
+func (r *MemoryStudentRepository) InsertStudents(ctx context.Context, students []*models.Student, passwords []string) (map[int]error, error) {
+	if len(students) != len(passwords) {
+		return nil, fmt.Errorf("got %d students but %d passwords", len(students), len(passwords))
+	}
+
+	failed := map[int]error{}
+	for i, student := range students {
+		if err := prepareStudent(student, passwords[i]); err != nil {
+			failed[i] = err
+			continue
+		}
+
+		r.mu.Lock()
+		if err := r.insertLocked(student); err != nil {
+			failed[i] = fmt.Errorf("student with this ID or email already exists")
+		}
+		r.mu.Unlock()
+	}
+	return failed, nil
+}
+
+func (r *MemoryStudentRepository) GetStudentByEmail(ctx context.Context, email string) (*models.Student, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, student := range r.students {
+		if student.Email == email && student.DeletedAt == nil {
+			return copyStudent(student), nil
+		}
+	}
+	return nil, studentNotFound(email)
+}
+
+func (r *MemoryStudentRepository) GetStudentByID(ctx context.Context, studentID string) (*models.Student, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	student, ok := r.students[studentID]
+	if !ok || student.DeletedAt != nil {
+		return nil, studentNotFound(studentID)
+	}
+	return copyStudent(student), nil
+}
+
+func (r *MemoryStudentRepository) GetStudentByIDFromBD(ctx context.Context, studentID string) (*models.Student, error) {
+	student, err := r.GetStudentByID(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get student by id from the database : %w", err)
+	}
+	return student, nil
+}
+
+func (r *MemoryStudentRepository) FindExistingStudents(ctx context.Context, studentIDs, emails []string) (map[string]bool, map[string]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	takenIDs := map[string]bool{}
+	takenEmails := map[string]bool{}
+	for _, student := range r.students {
+		if slices.Contains(studentIDs, student.StudentID) || slices.Contains(emails, student.Email) {
+			takenIDs[student.StudentID] = true
+			takenEmails[student.Email] = true
+		}
+	}
+	return takenIDs, takenEmails, nil
+}
+
+func (r *MemoryStudentRepository) ListStudents(ctx context.Context, filter models.StudentListFilter) ([]models.Student, string, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	if !slices.Contains(StudentSortFields, sortBy) {
+		return nil, "", fmt.Errorf("unsupported sort field %q", sortBy)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	var after *listCursor
+	if filter.Cursor != "" {
+		cursor, err := decodeListCursor(filter.Cursor)
+		if err != nil || cursor.SortBy != sortBy {
+			return nil, "", ErrInvalidCursor
+		}
+		after = cursor
+	}
+
+	// compare by (sort value, _id) like the mongo sort
+	compare := func(a, b *models.Student) int {
+		if c := compareSortValues(studentSortValue(a, sortBy), studentSortValue(b, sortBy)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.Hex(), b.ID.Hex())
+	}
+	if filter.SortDesc {
+		ascending := compare
+		compare = func(a, b *models.Student) int { return -ascending(a, b) }
+	}
+
+	r.mu.RLock()
+	matches := []*models.Student{}
+	for _, student := range r.students {
+		if !matchesListFilter(student, filter) {
+			continue
+		}
+		if after != nil && !afterCursor(student, after, sortBy, filter.SortDesc) {
+			continue
+		}
+		matches = append(matches, copyStudent(student))
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(matches, compare)
+
+	students := make([]models.Student, 0, min(int64(len(matches)), limit))
+	for _, student := range matches {
+		if int64(len(students)) == limit {
+			break
+		}
+		student.PasswordHash = ""
+		students = append(students, *student)
+	}
+
+	if int64(len(matches)) <= limit {
+		return students, "", nil
+	}
+
+	next, err := encodeListCursor(sortBy, &students[len(students)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	return students, next, nil
+}
+
+func (r *MemoryStudentRepository) VerifyPassword(ctx context.Context, studentID, plainPassword string) (*models.Student, error) {
+	student, err := r.GetStudentByIDFromBD(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("student not found: %w", err)
+	}
+
+	if !student.IsActive {
+		if !suspensionExpired(student) {
+			return nil, fmt.Errorf("%w: account is %s", ErrAccountInactive, accountStatus(student))
+		}
+
+		student, err = r.SetAccountStatus(ctx, studentID, liftSuspension)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lift expired suspension: %w", err)
+		}
+	}
+
+	if err := checkStudentPassword(student, plainPassword); err != nil {
+		return nil, err
+	}
+	return student, nil
+}
+
+func (r *MemoryStudentRepository) ChangePassword(ctx context.Context, studentID, currentPassword, newPassword string) error {
+	if _, err := r.VerifyPassword(ctx, studentID, currentPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashNewPassword(currentPassword, newPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.update(studentID, nil, func(student *models.Student) {
+		timeNow := time.Now()
+		validAfter := tokensValidAfter(timeNow)
+
+		student.PasswordHash = hashedPassword
+		student.TokensValidAfter = &validAfter
+		student.UpdatedAt = timeNow
+	})
+	return err
+}
+
+func (r *MemoryStudentRepository) UpdateStudentProfile(ctx context.Context, studentID string, profile models.StudentProfileUpdate) (*models.Student, error) {
+	return r.update(studentID, profile.ExpectedVersion, func(student *models.Student) {
+		if profile.FirstName != nil {
+			student.FirstName = *profile.FirstName
+		}
+		if profile.LastName != nil {
+			student.LastName = *profile.LastName
+		}
+		if profile.Department != nil {
+			student.Department = *profile.Department
+		}
+		student.UpdatedAt = time.Now()
+	})
+}
+
+func (r *MemoryStudentRepository) UpdateLastLogin(ctx context.Context, studentID string, loginAt time.Time) error {
+	_, err := r.update(studentID, nil, func(student *models.Student) {
+		student.LastLogin = &loginAt
+	})
+	return err
+}
+
+func (r *MemoryStudentRepository) SetAccountStatus(ctx context.Context, studentID string, change models.AccountStatusChange) (*models.Student, error) {
+	return r.update(studentID, change.ExpectedVersion, func(student *models.Student) {
+		timeNow := time.Now()
+
+		student.Status = change.Status
+		student.IsActive = change.Status == models.StatusActive
+		student.StatusReason = change.Reason
+		student.StatusChangedBy = change.ChangedBy
+		student.StatusChangedAt = &timeNow
+		student.SuspendedUntil = change.SuspendedUntil
+		student.UpdatedAt = timeNow
+		if change.Status != models.StatusActive {
+			validAfter := tokensValidAfter(timeNow)
+			student.TokensValidAfter = &validAfter
+		}
+	})
+}
+
+func (r *MemoryStudentRepository) SoftDeleteStudent(ctx context.Context, studentID, deletedBy string) error {
+	_, err := r.update(studentID, nil, func(student *models.Student) {
+		timeNow := time.Now()
+		validAfter := tokensValidAfter(timeNow)
+
+		student.DeletedAt = &timeNow
+		student.DeletedBy = deletedBy
+		student.TokensValidAfter = &validAfter
+		student.UpdatedAt = timeNow
+	})
+	return err
+}
+
+func (r *MemoryStudentRepository) RestoreStudent(ctx context.Context, studentID string) (*models.Student, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	student, ok := r.students[studentID]
+	if !ok || student.DeletedAt == nil {
+		return nil, studentNotFound(studentID)
+	}
+
+	student.DeletedAt = nil
+	student.DeletedBy = ""
+	student.UpdatedAt = time.Now()
+	student.Version++
+	return copyStudent(student), nil
+}
+
+func (r *MemoryStudentRepository) ListDeletedStudents(ctx context.Context, limit int64) ([]models.Student, error) {
+	r.mu.RLock()
+	deleted := []*models.Student{}
+	for _, student := range r.students {
+		if student.DeletedAt != nil {
+			deleted = append(deleted, copyStudent(student))
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(deleted, func(a, b *models.Student) int { return b.DeletedAt.Compare(*a.DeletedAt) })
+
+	students := []models.Student{}
+	for _, student := range deleted[:min(int64(len(deleted)), trashLimit(limit))] {
+		student.PasswordHash = ""
+		students = append(students, *student)
+	}
+	return students, nil
+}
+
+// PurgeDeletedStudents : the exam attempts of the students are kept by the MemoryExamRepository
+func (r *MemoryStudentRepository) PurgeDeletedStudents(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for studentID, student := range r.students {
+		if student.DeletedAt != nil && student.DeletedAt.Before(deletedBefore) {
+			delete(r.students, studentID)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// update : applies fn to the stored student, bumps its version and returns a copy of the
+// result, nothing is changed when expectedVersion is set and does not match
+func (r *MemoryStudentRepository) update(studentID string, expectedVersion *int64, fn func(student *models.Student)) (*models.Student, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	student, ok := r.students[studentID]
+	if !ok || student.DeletedAt != nil {
+		return nil, studentNotFound(studentID)
+	}
+	if err := checkVersion(student.Version, expectedVersion); err != nil {
+		return nil, err
+	}
+
+	fn(student)
+	student.Version++
+	return copyStudent(student), nil
+}
+
+// afterCursor : reports whether the student comes after the cursor position in the sort order
+func afterCursor(student *models.Student, cursor *listCursor, sortBy string, desc bool) bool {
+	c := compareSortValues(studentSortValue(student, sortBy), cursorValue(cursor.Value))
+	if c == 0 {
+		c = strings.Compare(student.ID.Hex(), cursor.ID.Hex())
+	}
+	if desc {
+		c = -c
+	}
+	return c > 0
+}
+
+// matchesListFilter : in-memory version of studentListConditions
+func matchesListFilter(student *models.Student, filter models.StudentListFilter) bool {
+	if student.DeletedAt != nil {
+		return false
+	}
+	if filter.Department != "" && student.Department != filter.Department {
+		return false
+	}
+	if filter.IsActive != nil && student.IsActive != *filter.IsActive {
+		return false
+	}
+	if filter.CreatedFrom != nil && student.CreatedAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && student.CreatedAt.After(*filter.CreatedTo) {
+		return false
+	}
+	if filter.RequiredExam != nil && !slices.Contains(student.RequiredExams, *filter.RequiredExam) {
+		return false
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		return matchesSearch(student, search)
+	}
+	return true
+}
+
+// matchesSearch : whole words like the text index, or the beginning of the student ID or email
+func matchesSearch(student *models.Student, search string) bool {
+	if strings.HasPrefix(student.StudentID, search) || strings.HasPrefix(student.Email, search) {
+		return true
+	}
+
+	var words []string
+	for _, field := range []string{student.FirstName, student.LastName, student.Email, student.StudentID} {
+		words = append(words, searchTerms(field)...)
+	}
+	return slices.ContainsFunc(searchTerms(search), func(term string) bool { return slices.Contains(words, term) })
+}
+
+// studentSortValue : value of the sort field of a student
+func studentSortValue(student *models.Student, field string) any {
+	switch field {
+	case "last_name":
+		return student.LastName
+	case "first_name":
+		return student.FirstName
+	case "student_id":
+		return student.StudentID
+	case "email":
+		return student.Email
+	default:
+		return student.CreatedAt
+	}
+}
+
+// cursorValue : converts a decoded cursor value to the type used by studentSortValue
+func cursorValue(value any) any {
+	if dateTime, ok := value.(primitive.DateTime); ok {
+		return dateTime.Time()
+	}
+	return value
+}
+
+// compareSortValues : compares two values returned by studentSortValue
+func compareSortValues(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			// mongo stores dates with millisecond precision
+			return cmp.Compare(a.UnixMilli(), b.UnixMilli())
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	}
+	return 0
+}
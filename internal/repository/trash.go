@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Deleting a student or an exam only moves it to the trash by setting deleted_at and deleted_by.
+// Documents in the trash are hidden from every default query, they can be listed and restored
+// until they are purged once the retention period has passed.
+
+// notDeleted : returns the filter restricted to documents that are not in the trash
+func notDeleted(filter bson.M) bson.M {
+	live := bson.M{"deleted_at": nil}
+	for key, value := range filter {
+		live[key] = value
+	}
+	return live
+}
+
+// inTrash : returns the filter restricted to documents that are in the trash
+func inTrash(filter bson.M) bson.M {
+	deleted := bson.M{"deleted_at": bson.M{"$ne": nil}}
+	for key, value := range filter {
+		deleted[key] = value
+	}
+	return deleted
+}
+
+// objectIDs : the object IDs among the values returned by a Distinct
+func objectIDs(values []any) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// trashLimit : page size of a trash listing
+func trashLimit(limit int64) int64 {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	return min(limit, maxListLimit)
+}
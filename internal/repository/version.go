@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Documents carry a version that is incremented by every edit. An update made with an expected
+// version only matches while the stored version is still the same, so two concurrent edits of
+// the same document cannot silently overwrite each other.
+
+// versioned : the update with the version of the document incremented, every update of a
+// student or an exam goes through it so an edit made with a version read before it conflicts
+func versioned(update bson.M) bson.M {
+	inc := bson.M{"version": 1}
+	if extra, ok := update["$inc"].(bson.M); ok {
+		for key, value := range extra {
+			if key != "version" {
+				inc[key] = value
+			}
+		}
+	}
+
+	result := make(bson.M, len(update)+1)
+	for operator, fields := range update {
+		result[operator] = fields
+	}
+	result["$inc"] = inc
+	return result
+}
+
+// versionFilter : returns the filter restricted to the expected version, if any
+func versionFilter(filter bson.M, expectedVersion *int64) bson.M {
+	if expectedVersion == nil {
+		return filter
+	}
+
+	versioned := bson.M{"version": *expectedVersion}
+	for key, value := range filter {
+		versioned[key] = value
+	}
+	return versioned
+}
+
+// versionConflict : turns the ErrNoDocuments of a versioned update into ErrVersionConflict
+// when the document matched by filter (without the version) still exists
+func versionConflict(ctx context.Context, collection *mongo.Collection, filter bson.M, expectedVersion *int64, err error) error {
+	if expectedVersion == nil || !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	count, countErr := collection.CountDocuments(ctx, filter)
+	if countErr != nil {
+		return fmt.Errorf("failed to check document version: %w", countErr)
+	}
+	if count > 0 {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// checkVersion : in-memory version of the versioned update filter
+func checkVersion(current int64, expectedVersion *int64) error {
+	if expectedVersion != nil && current != *expectedVersion {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -25,6 +25,9 @@ func NewRouter(ctrl *controllers.Controllers, authMiddleware *middleware.AuthMid
 	// use gin.Default() to create a router with default middleware: logger and recovery (crash-free) middleware
 	router := gin.Default()
 
+	// tag every request, the ID ends up in the audit log
+	router.Use(middleware.RequestID())
+
 	router.Static("/web/static", "./web/static")
 	router.Static("/images", "./web/static/images")
 
@@ -32,6 +35,10 @@ func NewRouter(ctrl *controllers.Controllers, authMiddleware *middleware.AuthMid
 	prometheus := ginprometheus.NewPrometheus("gin")
 	prometheus.Use(router)
 
+	// limit every route registered from here on (the static files are not), after the
+	// prometheus middleware so the rejected requests are counted
+	router.Use(middleware.NewRateLimiter().Middleware())
+
 	return &Router{
 		router:         router,
 		controllers:    ctrl,
@@ -55,6 +62,7 @@ func (r *Router) SetupRoutes() {
 	publicAPI := r.router.Group("/api/v1")
 	{
 		publicAPI.GET("/ping", controllers.Ping())
+		publicAPI.GET("/ready", r.controllers.Readiness())
 		publicAPI.POST("/login", r.controllers.StudentLogin())
 		publicAPI.POST("/signup", r.controllers.Signup())
 		publicAPI.GET("/simple-content", func(c *gin.Context) {
@@ -67,5 +75,51 @@ func (r *Router) SetupRoutes() {
 	protected.Use(r.authMiddleware.AuthenticationMiddleware())
 	{
 		protected.GET("/student/:id", r.controllers.GetStudentByID())
+		protected.GET("/me", r.controllers.GetMe())
+		protected.PATCH("/me", r.controllers.UpdateMe())
+		protected.POST("/me/password", r.controllers.ChangePassword())
+		protected.GET("/me/logins", r.controllers.GetMyLoginHistory())
+		protected.POST("/exams/:id/attempts", r.controllers.StartAttempt())
+		protected.POST("/attempts/:id/submit", r.controllers.SubmitAttempt())
+		protected.GET("/exams/:id/files", r.controllers.ListExamFiles())
+		protected.POST("/attempts/:id/files", r.controllers.UploadAttemptFile())
+		protected.GET("/attempts/:id/files", r.controllers.ListAttemptFiles())
+		protected.GET("/files/:id", r.controllers.DownloadFile())
+		protected.DELETE("/files/:id", r.controllers.DeleteFile())
+	}
+
+	exams := r.router.Group("/api/v1/exams")
+	exams.Use(r.authMiddleware.AuthenticationMiddleware(), r.authMiddleware.RequireRoles("admin", "teacher"))
+	{
+		exams.POST("", r.controllers.CreateExam())
+		exams.GET("/:id", r.controllers.GetExam())
+		exams.PATCH("/:id", r.controllers.UpdateExam())
+		exams.DELETE("/:id", r.controllers.DeleteExam())
+		exams.POST("/:id/regrade", r.controllers.RegradeExam())
+		exams.POST("/:id/enroll", r.controllers.EnrollStudents())
+		exams.POST("/:id/unenroll", r.controllers.UnenrollStudents())
+		exams.POST("/:id/files", r.controllers.UploadExamFile())
+	}
+
+	admin := r.router.Group("/api/v1/admin")
+	admin.Use(r.authMiddleware.AuthenticationMiddleware(), r.authMiddleware.RequireRoles("admin"))
+	{
+		admin.GET("/students", r.controllers.ListStudents())
+		admin.PATCH("/students/:id", r.controllers.UpdateStudent())
+		admin.DELETE("/students/:id", r.controllers.DeleteStudent())
+		admin.POST("/students/:id/deactivate", r.controllers.DeactivateStudent())
+		admin.POST("/students/:id/reactivate", r.controllers.ReactivateStudent())
+		admin.POST("/students/:id/suspend", r.controllers.SuspendStudent())
+		admin.POST("/students/:id/archive", r.controllers.ArchiveStudent())
+		admin.POST("/students/import", r.controllers.ImportStudents())
+		admin.GET("/logins", r.controllers.SearchLoginHistory())
+		admin.GET("/trash/students", r.controllers.ListDeletedStudents())
+		admin.POST("/trash/students/:id/restore", r.controllers.RestoreStudent())
+		admin.GET("/trash/exams", r.controllers.ListDeletedExams())
+		admin.POST("/trash/exams/:id/restore", r.controllers.RestoreExam())
+		admin.GET("/audit", r.controllers.SearchAuditLog())
+		admin.GET("/audit/export", r.controllers.ExportAuditLog())
+		admin.GET("/audit/verify", r.controllers.VerifyAuditLog())
+		admin.GET("/cache/stats", r.controllers.GetCacheStats())
 	}
 }
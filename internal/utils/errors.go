@@ -33,6 +33,12 @@ var (
 		"failed to disconnect from mongodb",
 	}
 
+	MongoNotReplicaSet = Error{
+		DatabaseError,
+		"MONGODB_NOT_REPLICA_SET_ERROR",
+		"mongodb must run as a replica set for transactions and change streams",
+	}
+
 	MongoNotInitialized = Error{
 		DatabaseError,
 		"MONGODB_NOT_INITIALIZED_ERROR",
@@ -45,6 +51,42 @@ var (
 		"failed to get collection",
 	}
 
+	MigrationFailed = Error{
+		DatabaseError,
+		"MONGODB_MIGRATION_ERROR",
+		"failed to run database migrations",
+	}
+
+	MigrationLockFailed = Error{
+		DatabaseError,
+		"MONGODB_MIGRATION_LOCK_ERROR",
+		"migration lock error",
+	}
+
+	TrashPurgeFailed = Error{
+		DatabaseError,
+		"MONGODB_TRASH_PURGE_ERROR",
+		"failed to purge the trash",
+	}
+
+	AuditRecordFailed = Error{
+		DatabaseError,
+		"MONGODB_AUDIT_RECORD_ERROR",
+		"failed to record audit entry",
+	}
+
+	ChangeStreamFailed = Error{
+		DatabaseError,
+		"MONGODB_CHANGE_STREAM_ERROR",
+		"change stream watcher failed",
+	}
+
+	FileStoreFailedToInit = Error{
+		DatabaseError,
+		"MONGODB_GRIDFS_INIT_ERROR",
+		"failed to init the gridfs file store",
+	}
+
 	// dragonfly errors
 	DragonflyFailedToInit = Error{
 		CacheError,
@@ -70,6 +112,54 @@ var (
 		"failed to delete cache",
 	}
 
+	CacheValueUndecodable = Error{
+		CacheError,
+		"CACHE_DECODE_ERROR",
+		"cached value cannot be decoded, fetching it again",
+	}
+
+	LocalCacheFailedToInit = Error{
+		CacheError,
+		"LOCAL_CACHE_INIT_ERROR",
+		"failed to enable the local cache tier, continuing without it",
+	}
+
+	CacheInvalidationFailed = Error{
+		CacheError,
+		"CACHE_INVALIDATION_PUBLISH_ERROR",
+		"failed to announce cache invalidation",
+	}
+
+	CacheLockFailed = Error{
+		CacheError,
+		"CACHE_FILL_LOCK_ERROR",
+		"failed to take cache fill lock",
+	}
+
+	CacheRefreshFailed = Error{
+		CacheError,
+		"CACHE_REFRESH_ERROR",
+		"failed to refresh cached value in the background",
+	}
+
+	CacheUnavailable = Error{
+		CacheError,
+		"CACHE_UNAVAILABLE_ERROR",
+		"dragonfly is unavailable, bypassing the cache until it is back",
+	}
+
+	CacheMetricsFailedToRegister = Error{
+		CacheError,
+		"CACHE_METRICS_REGISTER_ERROR",
+		"failed to register the cache metrics",
+	}
+
+	CacheInvalidationsLost = Error{
+		CacheError,
+		"CACHE_INVALIDATIONS_LOST_ERROR",
+		"invalidations were dropped while the cache was unavailable, stale entries may be served until they expire",
+	}
+
 	// internal errors
 	ConfigFailedToLoad = Error{
 		InternalServerError,
@@ -77,6 +167,24 @@ var (
 		"failed to get config",
 	}
 
+	ConfigFailedToReload = Error{
+		InternalServerError,
+		"CONFIG_RELOAD_ERROR",
+		"failed to reload config, keeping the current one",
+	}
+
+	ConfigKeyNotReloadable = Error{
+		InternalServerError,
+		"CONFIG_KEY_NOT_RELOADABLE",
+		"config key changed but only applies on restart, keeping the current value",
+	}
+
+	ConfigFailedToWatch = Error{
+		InternalServerError,
+		"CONFIG_WATCH_ERROR",
+		"failed to watch config, it will not be reloaded",
+	}
+
 	LoggerFailedToInit = Error{
 		InternalServerError,
 		"LOGGER_INIT_ERROR",
@@ -89,12 +197,6 @@ var (
 		"failed to sync zap logger",
 	}
 
-	FailedToGetProgramPath = Error{
-		InternalServerError,
-		"PROGRAM_PATH_LOAD_ERROR",
-		"failed to get executable path",
-	}
-
 	FailedToGenerateTLSCert = Error{
 		InternalServerError,
 		"FAILED_TO_GENERATE_TLS_ERROR",
@@ -28,12 +28,32 @@ var (
 		"Disconnected from mangodb successfully...",
 	}
 
+	MigrationApplied = Info{
+		DatabaseInfo,
+		"Database migration applied",
+	}
+
+	MigrationReverted = Info{
+		DatabaseInfo,
+		"Database migration reverted",
+	}
+
+	TrashPurged = Info{
+		DatabaseInfo,
+		"Purged expired documents from the trash",
+	}
+
 	// dragonfly info
 	DragonflyIsConnected = Info{
 		CacheInfo,
 		"Connected to dragonflydb successfully...",
 	}
 
+	CacheRecovered = Info{
+		CacheInfo,
+		"Dragonflydb is reachable again, the cache is used again",
+	}
+
 	// internal info
 	ServerStartOK = Info{
 		InternalServerInfo,
@@ -55,6 +75,16 @@ var (
 		"Shutdown signal recived",
 	}
 
+	ConfigReloaded = Info{
+		InternalServerInfo,
+		"Configuration reloaded...",
+	}
+
+	ConfigApplied = Info{
+		InternalServerInfo,
+		"Reloaded configuration applied...",
+	}
+
 	ServerShutdown = Info{
 		InternalServerInfo,
 		"Shutting down the server...",